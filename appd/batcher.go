@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+//serialized records sent to the Events API in one request
+type EventBatch struct {
+	Records [][]byte
+	Size    int //bytes of the JSON array
+}
+
+func (b *EventBatch) add(record []byte) {
+	if len(b.Records) == 0 {
+		b.Size = 2 //brackets
+	} else {
+		b.Size++ //comma
+	}
+	b.Records = append(b.Records, record)
+	b.Size += len(record)
+}
+
+//the records as a JSON array
+func (b *EventBatch) JSON() []byte {
+	var buf bytes.Buffer
+	buf.Grow(b.Size)
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(b.Records, []byte(",")))
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+//halves of the batch, for the batches rejected as too large
+func (b *EventBatch) Split() (EventBatch, EventBatch) {
+	first := EventBatch{}
+	second := EventBatch{}
+	half := len(b.Records) / 2
+	for i, record := range b.Records {
+		if i < half {
+			first.add(record)
+		} else {
+			second.add(record)
+		}
+	}
+	return first, second
+}
+
+/*
+ Serializes the records, a slice of any schema, and cuts them into batches of up to maxRecords records
+ and maxBytes bytes. 0 - no limit. A record larger than maxBytes is sent in a batch of its own
+*/
+func SplitRecords(records interface{}, maxRecords int, maxBytes int) ([]EventBatch, error) {
+	v := reflect.ValueOf(records)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Records must be a list, not %s", v.Kind())
+	}
+
+	batches := []EventBatch{}
+	current := EventBatch{}
+	for i := 0; i < v.Len(); i++ {
+		record, err := json.Marshal(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		full := maxRecords > 0 && len(current.Records) >= maxRecords
+		tooLarge := maxBytes > 0 && len(current.Records) > 0 && current.Size+1+len(record) > maxBytes
+		if full || tooLarge {
+			batches = append(batches, current)
+			current = EventBatch{}
+		}
+		current.add(record)
+	}
+	if len(current.Records) > 0 {
+		batches = append(batches, current)
+	}
+	return batches, nil
+}
@@ -8,60 +8,24 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
-
-	appd "appdynamics"
 )
 
-var lockMap = sync.RWMutex{}
-
+//REST client of the controller. The metrics are reported by the appd metric sink
 type ControllerClient struct {
 	logger       *log.Logger
 	ConfManager  *config.MutexConfigManager
-	regMetrics   map[string]bool
 	MetricsCache map[string]float64
 }
 
 func NewControllerClient(cm *config.MutexConfigManager, logger *log.Logger) (*ControllerClient, error) {
-	cfg := appd.Config{}
-
 	bag := (*cm).Get()
 
-	cfg.AppName = bag.AppName
-	cfg.TierName = bag.TierName
-	cfg.NodeName = bag.NodeName
-	cfg.Controller.Host = bag.ControllerUrl
-	cfg.Controller.Port = bag.ControllerPort
-	cfg.Controller.UseSSL = bag.SSLEnabled
-	if bag.SSLEnabled {
-		if bag.AgentSSLCert != "" {
-			logger.Infof("Setting custom agent cert:  %s", bag.AgentSSLCert)
-			cfg.Controller.CertificateFile = bag.AgentSSLCert
-		} else {
-			logger.Infof("Using default system cert: %s", bag.SystemSSLCert)
-			cfg.Controller.CertificateFile = bag.SystemSSLCert
-		}
-	}
-	cfg.Controller.Account = bag.Account
-	cfg.Controller.AccessKey = bag.AccessKey
-	cfg.UseConfigFromEnv = false
-	cfg.InitTimeoutMs = 1000
-	//	cfg.Logging.BaseDir = "__console__"
-	cfg.Logging.MinimumLevel = appd.APPD_LOG_LEVEL_DEBUG
-	if err := appd.InitSDK(&cfg); err != nil {
-		logger.WithField("error", err).Error("Error initializing the AppDynamics SDK.")
-		return nil, err
-	} else {
-		logger.Info("Initialized AppDynamics SDK successfully")
-	}
-	logger.Debugf("AppD Controller info: %v", &cfg.Controller)
-
-	controller := ControllerClient{ConfManager: cm, logger: logger, regMetrics: make(map[string]bool), MetricsCache: make(map[string]float64)}
+	controller := ControllerClient{ConfManager: cm, logger: logger, MetricsCache: make(map[string]float64)}
 
 	compatErr := controller.GetControllerStatus(bag)
 	if compatErr != nil {
@@ -81,70 +45,6 @@ func NewControllerClient(cm *config.MutexConfigManager, logger *log.Logger) (*Co
 	return &controller, nil
 }
 
-func (c *ControllerClient) RegisterMetrics(metrics m.AppDMetricList) error {
-	c.logger.Println("Registering Metrics with the agent:")
-	bt := appd.StartBT("RegMetrics", "")
-	for _, metric := range metrics.Items {
-
-		metric.MetricPath = fmt.Sprintf(metric.MetricPath, (*c.ConfManager).Get().TierName)
-		exists := c.checkMetricCache(metric)
-		if !exists {
-			//		c.logger.Println(metric)
-			appd.AddCustomMetric("", metric.MetricPath,
-				metric.MetricTimeRollUpType,
-				metric.MetricClusterRollUpType,
-				appd.APPD_HOLEHANDLING_TYPE_REGULAR_COUNTER)
-			appd.ReportCustomMetric("", metric.MetricPath, 0)
-			c.saveMetricInCache(metric)
-		}
-	}
-	appd.EndBT(bt)
-	c.logger.Println("Done registering Metrics with the agent")
-
-	return nil
-}
-
-func (c *ControllerClient) checkMetricCache(metric m.AppDMetric) bool {
-	lockMap.RLock()
-	defer lockMap.RUnlock()
-	_, exists := c.regMetrics[metric.MetricPath]
-	return exists
-}
-
-func (c *ControllerClient) saveMetricInCache(metric m.AppDMetric) {
-	lockMap.Lock()
-	defer lockMap.Unlock()
-	c.regMetrics[metric.MetricPath] = true
-}
-
-func (c *ControllerClient) registerMetric(metric m.AppDMetric) error {
-	exists := c.checkMetricCache(metric)
-	if !exists {
-		bt := appd.StartBT("RegSingleMetric", "")
-		appd.AddCustomMetric("", metric.MetricPath,
-			metric.MetricTimeRollUpType,
-			metric.MetricClusterRollUpType,
-			appd.APPD_HOLEHANDLING_TYPE_REGULAR_COUNTER)
-		appd.ReportCustomMetric("", metric.MetricPath, 0)
-		c.saveMetricInCache(metric)
-		appd.EndBT(bt)
-	}
-
-	return nil
-}
-
-func (c *ControllerClient) PostMetrics(metrics m.AppDMetricList) error {
-	bt := appd.StartBT("PostMetrics", "")
-	for _, metric := range metrics.Items {
-		metric.MetricPath = fmt.Sprintf(metric.MetricPath, (*c.ConfManager).Get().TierName)
-		c.registerMetric(metric)
-		appd.ReportCustomMetric("", metric.MetricPath, metric.MetricValue)
-	}
-	appd.EndBT(bt)
-
-	return nil
-}
-
 func writeSSLFromEnv(bag *m.AppDBag, logger *log.Logger) error {
 	from, err := os.Open(bag.SystemSSLCert)
 	if err != nil {
@@ -181,14 +81,6 @@ func writeSSLFromEnv(bag *m.AppDBag, logger *log.Logger) error {
 	return nil
 }
 
-func (c *ControllerClient) StartBT(name string) appd.BtHandle {
-	return appd.StartBT(name, "")
-}
-
-func (c *ControllerClient) StopBT(bth appd.BtHandle) {
-	appd.EndBT(bth)
-}
-
 func (c *ControllerClient) DetermineNodeID(appName string, tierName string, nodeName string) (int, int, int, error) {
 	appID, err := c.FindAppID(appName)
 	if err != nil {
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockHttpTransports = sync.Mutex{}
+var lockCircuitBreakers = sync.Mutex{}
+
+const (
+	httpDefaultTimeout     int           = 30 //seconds
+	httpRetryBaseBackoff   time.Duration = 500 * time.Millisecond
+	httpDefaultMaxBackoff  int           = 30 //seconds
+	httpMaxIdleConnections int           = 10
+
+	CIRCUIT_CLOSED    string = "closed"
+	CIRCUIT_OPEN      string = "open"
+	CIRCUIT_HALF_OPEN string = "halfOpen"
+)
+
+//transports are shared by all requests with the same proxy and TLS settings to reuse the connections
+var httpTransports = make(map[string]*http.Transport)
+
+//endpoint (scheme://host) -> breaker
+var circuitBreakers = make(map[string]*CircuitBreaker)
+
+//returned without a request while the circuit breaker of the endpoint is open
+type CircuitOpenError struct {
+	Endpoint string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("Endpoint %s is unavailable. Requests are paused by the circuit breaker", e.Endpoint)
+}
+
+/*
+ Stops the requests to an endpoint after a number of consecutive failures.
+ When the cooldown period is over, a single request probes the endpoint.
+ The circuit closes if the probe succeeds and opens again otherwise
+*/
+type CircuitBreaker struct {
+	Endpoint string
+	State    string
+	Failures int
+	OpenedAt time.Time
+}
+
+func GetCircuitBreaker(endpoint string) *CircuitBreaker {
+	key := getEndpointKey(endpoint)
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	cb, ok := circuitBreakers[key]
+	if !ok {
+		cb = &CircuitBreaker{Endpoint: key, State: CIRCUIT_CLOSED}
+		circuitBreakers[key] = cb
+	}
+	return cb
+}
+
+//state of the circuit breakers of all endpoints called by the agent
+func GetCircuitStates() map[string]string {
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	states := make(map[string]string)
+	for key, cb := range circuitBreakers {
+		states[key] = cb.State
+	}
+	return states
+}
+
+func getEndpointKey(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+}
+
+func getCircuitCooldown(bag *m.AppDBag) time.Duration {
+	return time.Duration(bag.CircuitBreakerCooldown) * time.Second
+}
+
+//true while the circuit is open and the cooldown period is not over
+func (cb *CircuitBreaker) IsOpen(bag *m.AppDBag) bool {
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	return cb.State == CIRCUIT_OPEN && time.Since(cb.OpenedAt) < getCircuitCooldown(bag)
+}
+
+func (cb *CircuitBreaker) Allow(bag *m.AppDBag) bool {
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	switch cb.State {
+	case CIRCUIT_OPEN:
+		if time.Since(cb.OpenedAt) < getCircuitCooldown(bag) {
+			return false
+		}
+		cb.State = CIRCUIT_HALF_OPEN
+		return true
+	case CIRCUIT_HALF_OPEN:
+		//the probe is in flight
+		return false
+	}
+	return true
+}
+
+func (cb *CircuitBreaker) Success(logger *log.Logger) {
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	if cb.State != CIRCUIT_CLOSED {
+		logger.Infof("Endpoint %s is available. Resuming requests\n", cb.Endpoint)
+	}
+	cb.State = CIRCUIT_CLOSED
+	cb.Failures = 0
+}
+
+func (cb *CircuitBreaker) Failure(bag *m.AppDBag, logger *log.Logger) {
+	lockCircuitBreakers.Lock()
+	defer lockCircuitBreakers.Unlock()
+	cb.Failures++
+	//the probe failed, even if the circuit breaker has been disabled in the meantime
+	if cb.State == CIRCUIT_HALF_OPEN {
+		cb.State = CIRCUIT_OPEN
+		cb.OpenedAt = time.Now()
+		return
+	}
+	if bag.CircuitBreakerThreshold <= 0 {
+		return
+	}
+	if cb.State == CIRCUIT_CLOSED && cb.Failures >= bag.CircuitBreakerThreshold {
+		logger.Warnf("Endpoint %s failed %d times in a row. Pausing requests for %d seconds\n", cb.Endpoint, cb.Failures, bag.CircuitBreakerCooldown)
+		cb.State = CIRCUIT_OPEN
+		cb.OpenedAt = time.Now()
+	}
+}
+
+func getHttpTransport(bag *m.AppDBag, logger *log.Logger) *http.Transport {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s|%s", bag.ProxyUrl, bag.ProxyUser, bag.ProxyPass, bag.AgentSSLCert, bag.HttpClientCert, bag.HttpClientKey)
+	lockHttpTransports.Lock()
+	defer lockHttpTransports.Unlock()
+	if transport, ok := httpTransports[key]; ok {
+		return transport
+	}
+
+	transport := &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: httpMaxIdleConnections,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     getHttpTLSConfig(bag, logger),
+		Proxy:               http.ProxyFromEnvironment,
+	}
+	//the configured proxy takes precedence over HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	if bag.ProxyUrl != "" {
+		proxyUrl, err := url.Parse(bag.ProxyUrl)
+		if err != nil {
+			logger.Error("Proxy url is invalid")
+		} else {
+			transport.Proxy = http.ProxyURL(proxyUrl)
+			//proxy authentication of the https tunnels
+			if bag.ProxyUser != "" && bag.ProxyPass != "" {
+				auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", bag.ProxyUser, bag.ProxyPass)))
+				transport.ProxyConnectHeader = http.Header{"Proxy-Authorization": []string{"Basic " + auth}}
+			}
+		}
+	}
+	httpTransports[key] = transport
+	return transport
+}
+
+//trusts the system CAs and the certificates in AgentSSLCert. Presents the client certificate, if configured
+func getHttpTLSConfig(bag *m.AppDBag, logger *log.Logger) *tls.Config {
+	tlsConfig := &tls.Config{}
+	if bag.AgentSSLCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(bag.AgentSSLCert)
+		if err != nil {
+			logger.Warnf("Unable to read %s. Using the system certificates. %v\n", bag.AgentSSLCert, err)
+		} else if !pool.AppendCertsFromPEM(pem) {
+			logger.Warnf("No certificates found in %s. Using the system certificates\n", bag.AgentSSLCert)
+		} else {
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if bag.HttpClientCert != "" || bag.HttpClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(bag.HttpClientCert, bag.HttpClientKey)
+		if err != nil {
+			logger.Errorf("Unable to load the client certificate. Requests are sent without it. %v\n", err)
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return tlsConfig
+}
+
+func isRetriableStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+//delay before the next attempt: Retry-After, if the server sent one, or exponential backoff with full jitter
+func getRetryDelay(resp *http.Response, attempt int, maxBackoff time.Duration) time.Duration {
+	if resp != nil {
+		if val := resp.Header.Get("Retry-After"); val != "" {
+			if seconds, err := strconv.Atoi(val); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+			if t, err := http.ParseTime(val); err == nil {
+				return time.Until(t)
+			}
+		}
+	}
+	backoff := httpRetryBaseBackoff << uint(attempt)
+	if backoff <= 0 || backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+/*
+ Sends the request with the shared transport. Connection errors and responses with status 408, 429, 502, 503 and 504
+ are retried up to HttpMaxRetries times, unless the server asks to wait longer than HttpMaxBackoff.
+ The response of the last attempt is returned
+*/
+func (rc *RestClient) do(req *http.Request) (*http.Response, error) {
+	//the https requests are authenticated with the proxy when the tunnel is established
+	if req.URL.Scheme == "http" {
+		rc.addProxyAuth(req)
+	}
+	return doWithRetries(rc.getClient(), req, rc.Bag, rc.logger)
+}
+
+//sends the request with the client, with the retries and the circuit breaker of the endpoint. See RestClient.do
+func doWithRetries(client *http.Client, req *http.Request, bag *m.AppDBag, logger *log.Logger) (*http.Response, error) {
+	cb := GetCircuitBreaker(req.URL.String())
+	maxBackoff := time.Duration(httpDefaultMaxBackoff) * time.Second
+	if bag.HttpMaxBackoff > 0 {
+		maxBackoff = time.Duration(bag.HttpMaxBackoff) * time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !cb.Allow(bag) {
+			return nil, &CircuitOpenError{Endpoint: cb.Endpoint}
+		}
+		resp, err := client.Do(req)
+		if err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			cb.Failure(bag, logger)
+		} else {
+			cb.Success(logger)
+		}
+		if err == nil && !isRetriableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := getRetryDelay(resp, attempt, maxBackoff)
+		if attempt >= bag.HttpMaxRetries || delay > maxBackoff || (req.Body != nil && req.GetBody == nil) || cb.IsOpen(bag) {
+			return resp, err
+		}
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			logger.Debugf("%s %s returned %s. Retrying in %v\n", req.Method, req.URL.Path, resp.Status, delay)
+		} else {
+			logger.Debugf("%s %s failed. Retrying in %v. %v\n", req.Method, req.URL.Path, delay, err)
+		}
+		time.Sleep(delay)
+		if req.GetBody != nil {
+			body, errBody := req.GetBody()
+			if errBody != nil {
+				return nil, errBody
+			}
+			req.Body = body
+		}
+	}
+}
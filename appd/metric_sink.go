@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockMap = sync.RWMutex{}
+
+//reports the metrics to the controller through the configured metric transport
+type AppDMetricSink struct {
+	ConfManager *config.MutexConfigManager
+	transport   MetricTransport
+	regMetrics  map[string]bool
+	logger      *log.Logger
+}
+
+func NewAppDMetricSink(cm *config.MutexConfigManager, logger *log.Logger) (*AppDMetricSink, error) {
+	transport, err := NewMetricTransport(cm, logger)
+	if err != nil {
+		logger.WithField("error", err).Error("Error initializing the metric transport.")
+		return nil, err
+	}
+	return NewAppDMetricSinkWithTransport(cm, transport, logger), nil
+}
+
+func NewAppDMetricSinkWithTransport(cm *config.MutexConfigManager, transport MetricTransport, logger *log.Logger) *AppDMetricSink {
+	return &AppDMetricSink{ConfManager: cm, transport: transport, regMetrics: make(map[string]bool), logger: logger}
+}
+
+func (s *AppDMetricSink) RegisterMetrics(metrics m.AppDMetricList) error {
+	s.logger.Println("Registering Metrics with the agent:")
+	bt := s.transport.StartBT("RegMetrics")
+	for _, metric := range metrics.Items {
+
+		metric.MetricPath = fmt.Sprintf(metric.MetricPath, (*s.ConfManager).Get().TierName)
+		exists := s.checkMetricCache(metric)
+		if !exists {
+			s.transport.RegisterMetric(metric)
+			s.saveMetricInCache(metric)
+		}
+	}
+	s.transport.EndBT(bt)
+	s.logger.Println("Done registering Metrics with the agent")
+
+	return nil
+}
+
+func (s *AppDMetricSink) checkMetricCache(metric m.AppDMetric) bool {
+	lockMap.RLock()
+	defer lockMap.RUnlock()
+	_, exists := s.regMetrics[metric.MetricPath]
+	return exists
+}
+
+func (s *AppDMetricSink) saveMetricInCache(metric m.AppDMetric) {
+	lockMap.Lock()
+	defer lockMap.Unlock()
+	s.regMetrics[metric.MetricPath] = true
+}
+
+func (s *AppDMetricSink) registerMetric(metric m.AppDMetric) error {
+	exists := s.checkMetricCache(metric)
+	if !exists {
+		bt := s.transport.StartBT("RegSingleMetric")
+		err := s.transport.RegisterMetric(metric)
+		s.saveMetricInCache(metric)
+		s.transport.EndBT(bt)
+		return err
+	}
+
+	return nil
+}
+
+func (s *AppDMetricSink) PostMetrics(metrics m.AppDMetricList) error {
+	bt := s.transport.StartBT("PostMetrics")
+	defer s.transport.EndBT(bt)
+	items := []m.AppDMetric{}
+	for _, metric := range metrics.Items {
+		metric.MetricPath = fmt.Sprintf(metric.MetricPath, (*s.ConfManager).Get().TierName)
+		s.registerMetric(metric)
+		items = append(items, metric)
+	}
+
+	return s.transport.ReportMetrics(items)
+}
+
+func (s *AppDMetricSink) StartBT(name string) BtHandle {
+	return s.transport.StartBT(name)
+}
+
+func (s *AppDMetricSink) StopBT(bth BtHandle) {
+	s.transport.EndBT(bth)
+}
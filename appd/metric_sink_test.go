@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+//HTTP listener of a Machine Agent that fails the requests with the given numbers, starting with 1
+type fakeMachineAgent struct {
+	lock     sync.Mutex
+	requests int
+	fail     map[int]bool
+	metrics  []machineAgentMetric
+}
+
+func (ma *fakeMachineAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	ma.requests++
+	if r.Method != "POST" || r.URL.Path != MACHINE_AGENT_METRICS_PATH || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if ma.fail[ma.requests] {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var batch []machineAgentMetric
+	body, _ := ioutil.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ma.metrics = append(ma.metrics, batch...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//number of requests and metrics received
+func (ma *fakeMachineAgent) received() (int, []machineAgentMetric) {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	return ma.requests, ma.metrics
+}
+
+func newMachineAgentSink(t *testing.T, ma *fakeMachineAgent) (*AppDMetricSink, func()) {
+	server := httptest.NewServer(ma)
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	bag := m.GetDefaultProperties()
+	bag.TierName = "ClusterAgent"
+	bag.MetricTransport = m.METRIC_TRANSPORT_HTTP
+	bag.MachineAgentUrl = server.URL + "/"
+	cm := &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: logger}
+	sink, err := NewAppDMetricSink(cm, logger)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Unable to create the appd metric sink. %v", err)
+	}
+	return sink, server.Close
+}
+
+func TestAppDMetricSinkReportsToMachineAgent(t *testing.T) {
+	ma := &fakeMachineAgent{fail: map[int]bool{}}
+	sink, stop := newMachineAgentSink(t, ma)
+	defer stop()
+
+	podCount := m.NewAppDMetric("PodCount", 5, m.RootPath)
+	cpu := m.NewAppDMetric("CpuUsage", 250, m.RootPath+"Nodes|node1|")
+	cpu.MetricTimeRollUpType = m.TIMEROLLUP_TYPE_AVERAGE
+	metrics := m.AppDMetricList{Items: []m.AppDMetric{podCount, cpu}}
+	if err := sink.RegisterMetrics(metrics); err != nil {
+		t.Fatalf("Unable to register metrics. %v", err)
+	}
+	if requests, _ := ma.received(); requests != 0 {
+		t.Errorf("Expected the Machine Agent to register the metrics with the first report, got %d requests", requests)
+	}
+	if err := sink.PostMetrics(metrics); err != nil {
+		t.Fatalf("Unable to post metrics. %v", err)
+	}
+
+	expected := []machineAgentMetric{
+		{MetricName: "Server|Component:ClusterAgent|Custom Metrics|Cluster Stats|PodCount", AggregatorType: "OBSERVATION", Value: 5},
+		{MetricName: "Server|Component:ClusterAgent|Custom Metrics|Cluster Stats|Nodes|node1|CpuUsage", AggregatorType: "AVERAGE", Value: 250},
+	}
+	requests, received := ma.received()
+	if requests != 1 || len(received) != len(expected) {
+		t.Fatalf("Expected %d metrics in 1 request, got %d in %d", len(expected), len(received), requests)
+	}
+	for i, metric := range expected {
+		if received[i] != metric {
+			t.Errorf("Expected %+v, got %+v", metric, received[i])
+		}
+	}
+	if bth := sink.StartBT("Test"); bth != 0 {
+		t.Errorf("Expected no transactions with the http transport, got %d", bth)
+	}
+}
+
+func TestAppDMetricSinkReportsAllBatches(t *testing.T) {
+	ma := &fakeMachineAgent{fail: map[int]bool{2: true}}
+	sink, stop := newMachineAgentSink(t, ma)
+	defer stop()
+
+	metrics := m.NewAppDMetricList()
+	count := 2*machineAgentBatchSize + 10
+	for i := 0; i < count; i++ {
+		metrics.Items = append(metrics.Items, m.NewAppDMetric(fmt.Sprintf("Metric%d", i), int64(i), m.RootPath))
+	}
+	err := sink.PostMetrics(metrics)
+	if err == nil {
+		t.Fatalf("Expected the failed batch to be reported")
+	}
+	if !strings.Contains(err.Error(), "1 of 3 batches") || !strings.Contains(err.Error(), fmt.Sprintf("metrics %d-%d", machineAgentBatchSize, 2*machineAgentBatchSize-1)) {
+		t.Errorf("Expected the error to name the failed batch, got %v", err)
+	}
+	requests, received := ma.received()
+	if requests != 3 {
+		t.Errorf("Expected all 3 batches to be attempted, got %d requests", requests)
+	}
+	if len(received) != count-machineAgentBatchSize {
+		t.Errorf("Expected %d metrics from the other batches, got %d", count-machineAgentBatchSize, len(received))
+	}
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockMetricTransports = sync.RWMutex{}
+
+const (
+	MACHINE_AGENT_METRICS_PATH string = "/api/v1/metrics"
+
+	//metrics posted to the Machine Agent in one request
+	machineAgentBatchSize int = 1000
+)
+
+//handle of a business transaction of the agent. 0 if the transport does not track transactions
+type BtHandle uint64
+
+//reports the custom metrics of the appd sink to the controller
+type MetricTransport interface {
+	RegisterMetric(metric m.AppDMetric) error
+	ReportMetrics(metrics []m.AppDMetric) error
+	StartBT(name string) BtHandle
+	EndBT(bth BtHandle)
+}
+
+type MetricTransportFactory func(cm *config.MutexConfigManager, logger *log.Logger) (MetricTransport, error)
+
+//the sdk transport registers itself when the agent is built with cgo
+var metricTransportFactories = map[string]MetricTransportFactory{
+	m.METRIC_TRANSPORT_HTTP: func(cm *config.MutexConfigManager, logger *log.Logger) (MetricTransport, error) {
+		transport, err := NewMachineAgentTransport(cm, logger)
+		if err != nil {
+			return nil, err
+		}
+		return transport, nil
+	},
+}
+
+//makes a metric transport available to the MetricTransport setting under the name
+func RegisterMetricTransport(name string, factory MetricTransportFactory) {
+	lockMetricTransports.Lock()
+	defer lockMetricTransports.Unlock()
+	metricTransportFactories[name] = factory
+}
+
+func NewMetricTransport(cm *config.MutexConfigManager, logger *log.Logger) (MetricTransport, error) {
+	name := (*cm).Get().MetricTransport
+	lockMetricTransports.RLock()
+	factory, ok := metricTransportFactories[name]
+	lockMetricTransports.RUnlock()
+	if !ok {
+		if name == m.METRIC_TRANSPORT_SDK {
+			return nil, fmt.Errorf("Metric transport %s is not available. The agent was built without cgo. Use the %s transport", name, m.METRIC_TRANSPORT_HTTP)
+		}
+		return nil, fmt.Errorf("Unknown metric transport %s", name)
+	}
+	transport, err := factory(cm, logger)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to initialize metric transport %s. %v", name, err)
+	}
+	logger.Infof("Metric transport: %s\n", name)
+	return transport, nil
+}
+
+//metric in the format of the HTTP listener of the Machine Agent
+type machineAgentMetric struct {
+	MetricName     string `json:"metricName"`
+	AggregatorType string `json:"aggregatorType"`
+	Value          int64  `json:"value"`
+}
+
+/*
+ Posts the metrics to the HTTP listener of a Machine Agent, which reports them to the controller.
+ Does not depend on the AppDynamics SDK, so that the agent can be built without cgo.
+ The requests share the retries and the circuit breakers of the REST clients, but not the proxy settings
+*/
+type MachineAgentTransport struct {
+	ConfManager *config.MutexConfigManager
+	Url         string
+	client      *http.Client
+	logger      *log.Logger
+}
+
+func NewMachineAgentTransport(cm *config.MutexConfigManager, logger *log.Logger) (*MachineAgentTransport, error) {
+	bag := (*cm).Get()
+	u, err := url.Parse(bag.MachineAgentUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Machine Agent url %s is invalid", bag.MachineAgentUrl)
+	}
+	timeout := httpDefaultTimeout
+	if bag.HttpTimeout > 0 {
+		timeout = bag.HttpTimeout
+	}
+	//the listener runs next to the agent, the proxy settings do not apply
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: httpMaxIdleConnections,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     getHttpTLSConfig(bag, logger),
+	}
+	client := &http.Client{Transport: transport, Timeout: time.Duration(timeout) * time.Second}
+	return &MachineAgentTransport{ConfManager: cm, Url: strings.TrimRight(bag.MachineAgentUrl, "/"), client: client, logger: logger}, nil
+}
+
+//the Machine Agent registers the metrics when they are reported for the first time
+func (t *MachineAgentTransport) RegisterMetric(metric m.AppDMetric) error {
+	return nil
+}
+
+//a failed batch does not prevent the delivery of the others
+func (t *MachineAgentTransport) ReportMetrics(metrics []m.AppDMetric) error {
+	errs := []string{}
+	for start := 0; start < len(metrics); start += machineAgentBatchSize {
+		end := start + machineAgentBatchSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		batch := []machineAgentMetric{}
+		for _, metric := range metrics[start:end] {
+			batch = append(batch, machineAgentMetric{MetricName: metric.MetricPath, AggregatorType: getAggregatorType(metric), Value: metric.MetricValue})
+		}
+		if err := t.post(batch); err != nil {
+			errs = append(errs, fmt.Sprintf("metrics %d-%d: %v", start, end-1, err))
+		}
+	}
+	if len(errs) > 0 {
+		batches := (len(metrics) + machineAgentBatchSize - 1) / machineAgentBatchSize
+		return fmt.Errorf("Unable to report %d of %d batches of metrics. %s", len(errs), batches, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (t *MachineAgentTransport) post(batch []machineAgentMetric) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("Unable to serialize metrics. %v", err)
+	}
+	req, err := http.NewRequest("POST", t.Url+MACHINE_AGENT_METRICS_PATH, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("Unable to initiate request. %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := doWithRetries(t.client, req, (*t.ConfManager).Get(), t.logger)
+	if err != nil {
+		return fmt.Errorf("Unable to post metrics to the Machine Agent. %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
+		return fmt.Errorf("Machine Agent rejected the metrics with status %s. %s", resp.Status, string(body))
+	}
+	t.logger.Debugf("Posted %d metrics to the Machine Agent\n", len(batch))
+	return nil
+}
+
+func (t *MachineAgentTransport) StartBT(name string) BtHandle {
+	return 0
+}
+
+func (t *MachineAgentTransport) EndBT(bth BtHandle) {
+}
+
+//the time rollup of the metric as the aggregator of the Machine Agent
+func getAggregatorType(metric m.AppDMetric) string {
+	switch metric.MetricTimeRollUpType {
+	case m.TIMEROLLUP_TYPE_AVERAGE:
+		return "AVERAGE"
+	case m.TIMEROLLUP_TYPE_SUM:
+		return "SUM"
+	}
+	return "OBSERVATION"
+}
@@ -0,0 +1,379 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/version"
+)
+
+const (
+	OTLP_METRICS_PATH string = "/v1/metrics"
+	OTLP_LOGS_PATH    string = "/v1/logs"
+
+	otlpScopeName        string = "appdynamics-cluster-agent"
+	otlpDefaultTimeout   int    = 10 //seconds
+	otlpTemporalityDelta int    = 1
+)
+
+/*
+ Entities of the metric paths and fields of the records -> OTel semantic conventions.
+ A tier can be any kind of workload, or a label value shared by several, so it maps to the service
+*/
+var otlpAttributeNames = map[string]string{
+	"namespace":     "k8s.namespace.name",
+	"tier":          "service.name",
+	"node":          "k8s.node.name",
+	"nodeName":      "k8s.node.name",
+	"container":     "k8s.container.name",
+	"containerName": "k8s.container.name",
+	"pod":           "k8s.pod.name",
+	"podName":       "k8s.pod.name",
+	"clusterName":   "k8s.cluster.name",
+}
+
+//levels of the log records and severities of the events -> OTel severity numbers
+var otlpSeverities = map[string]int{
+	"TRACE":   1,
+	"DEBUG":   5,
+	"INFO":    9,
+	"NORMAL":  9,
+	"WARN":    13,
+	"WARNING": 13,
+	"ERROR":   17,
+	"FATAL":   21,
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpDataPoint struct {
+	StartTimeUnixNano string `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string `json:"timeUnixNano"`
+	AsInt             string `json:"asInt"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string                 `json:"timeUnixNano"`
+	ObservedTimeUnixNano string                 `json:"observedTimeUnixNano"`
+	SeverityNumber       int                    `json:"severityNumber,omitempty"`
+	SeverityText         string                 `json:"severityText,omitempty"`
+	Body                 map[string]interface{} `json:"body"`
+	Attributes           []otlpKeyValue         `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+func otlpString(s string) map[string]interface{} {
+	return map[string]interface{}{"stringValue": s}
+}
+
+func otlpValue(v interface{}) (map[string]interface{}, bool) {
+	switch val := v.(type) {
+	case string:
+		return otlpString(val), true
+	case bool:
+		return map[string]interface{}{"boolValue": val}, true
+	case float64:
+		if val == float64(int64(val)) {
+			return map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}, true
+		}
+		return map[string]interface{}{"doubleValue": val}, true
+	}
+	return nil, false
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+//sorted by key, so that the same entities always map to the same resource
+func otlpAttributes(attributes map[string]string) []otlpKeyValue {
+	keys := []string{}
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := []otlpKeyValue{}
+	for _, k := range keys {
+		list = append(list, otlpKeyValue{Key: k, Value: otlpString(attributes[k])})
+	}
+	return list
+}
+
+/*
+ Exports the metrics as OTel gauges and sums and the analytics records as OTel log records
+ to an OTLP/HTTP receiver, using the JSON encoding.
+ The entities of the metric paths become the resource attributes of the metrics
+*/
+type OtlpSink struct {
+	ConfManager *config.MutexConfigManager
+	client      *http.Client
+	logger      *log.Logger
+}
+
+func NewOtlpSink(cm *config.MutexConfigManager, logger *log.Logger) (*OtlpSink, error) {
+	bag := (*cm).Get()
+	if bag.OtlpEndpoint == "" {
+		return nil, fmt.Errorf("OtlpEndpoint is not set")
+	}
+	tlsConfig, err := getOtlpTLSConfig(bag)
+	if err != nil {
+		return nil, err
+	}
+	timeout := bag.OtlpTimeout
+	if timeout <= 0 {
+		timeout = otlpDefaultTimeout
+	}
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return &OtlpSink{ConfManager: cm, client: client, logger: logger}, nil
+}
+
+func getOtlpTLSConfig(bag *m.AppDBag) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: bag.OtlpInsecureSkipVerify}
+	if bag.OtlpCACert != "" {
+		pem, err := ioutil.ReadFile(bag.OtlpCACert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read OTLP CA certificate %s. %v", bag.OtlpCACert, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in %s", bag.OtlpCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if bag.OtlpClientCert != "" || bag.OtlpClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(bag.OtlpClientCert, bag.OtlpClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load OTLP client certificate. %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (s *OtlpSink) getResourceAttributes(bag *m.AppDBag) map[string]string {
+	return map[string]string{"k8s.cluster.name": bag.AppName, "service.name": otlpScopeName, "service.version": version.Version}
+}
+
+func (s *OtlpSink) PostMetrics(metrics m.AppDMetricList) error {
+	if len(metrics.Items) == 0 {
+		return nil
+	}
+	bag := (*s.ConfManager).Get()
+	now := time.Now()
+	start := now.Add(-time.Duration(bag.MetricsSyncInterval) * time.Second)
+	scope := otlpScope{Name: otlpScopeName, Version: version.Version}
+
+	//one resource per entity
+	resources := make(map[string]*otlpResourceMetrics)
+	keys := []string{}
+	for _, metric := range metrics.Items {
+		scopeName, name, labels := parseMetricPath(metric.MetricPath)
+		attributes := s.getResourceAttributes(bag)
+		if _, ok := labels["tier"]; ok {
+			//the agent version does not apply to the tier, the scope still identifies the agent
+			delete(attributes, "service.version")
+		}
+		for label, value := range labels {
+			key, ok := otlpAttributeNames[label]
+			if !ok {
+				key = "appd." + label
+			}
+			attributes[key] = value
+		}
+		list := otlpAttributes(attributes)
+		data, _ := json.Marshal(list)
+		rm, ok := resources[string(data)]
+		if !ok {
+			rm = &otlpResourceMetrics{Resource: otlpResource{Attributes: list}, ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: []otlpMetric{}}}}
+			resources[string(data)] = rm
+			keys = append(keys, string(data))
+		}
+
+		om := otlpMetric{Name: fmt.Sprintf("appd.%s.%s", scopeName, name)}
+		point := otlpDataPoint{TimeUnixNano: otlpTime(now), AsInt: strconv.FormatInt(metric.MetricValue, 10)}
+		if metric.MetricDelta {
+			point.StartTimeUnixNano = otlpTime(start)
+			om.Sum = &otlpSum{DataPoints: []otlpDataPoint{point}, AggregationTemporality: otlpTemporalityDelta, IsMonotonic: true}
+		} else {
+			om.Gauge = &otlpGauge{DataPoints: []otlpDataPoint{point}}
+		}
+		rm.ScopeMetrics[0].Metrics = append(rm.ScopeMetrics[0].Metrics, om)
+	}
+
+	request := otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{}}
+	for _, k := range keys {
+		request.ResourceMetrics = append(request.ResourceMetrics, *resources[k])
+	}
+	return s.post(bag, OTLP_METRICS_PATH, request)
+}
+
+//every record becomes a log record with the schema name and the scalar fields of the record as attributes
+func (s *OtlpSink) PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("Problems when serializing array of %s records. %v", schemaName, err)
+	}
+	var list []map[string]interface{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("Records of %s are not a list of objects. %v", schemaName, err)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	bag := (*s.ConfManager).Get()
+	now := time.Now()
+
+	logRecords := []otlpLogRecord{}
+	for _, record := range list {
+		lr := otlpLogRecord{TimeUnixNano: otlpTime(getOtlpRecordTime(record, now)), ObservedTimeUnixNano: otlpTime(now),
+			Attributes: []otlpKeyValue{{Key: "appd.schema", Value: otlpString(schemaName)}}}
+		lr.SeverityText, lr.SeverityNumber = getOtlpSeverity(record)
+
+		fields := []string{}
+		for k := range record {
+			fields = append(fields, k)
+		}
+		sort.Strings(fields)
+		for _, k := range fields {
+			value, ok := otlpValue(record[k])
+			if !ok {
+				continue
+			}
+			if key, known := otlpAttributeNames[k]; known {
+				lr.Attributes = append(lr.Attributes, otlpKeyValue{Key: key, Value: value})
+			}
+			lr.Attributes = append(lr.Attributes, otlpKeyValue{Key: "appd." + k, Value: value})
+		}
+
+		if msg, ok := record["message"].(string); ok && msg != "" {
+			lr.Body = otlpString(msg)
+		} else {
+			body, _ := json.Marshal(record)
+			lr.Body = otlpString(string(body))
+		}
+		logRecords = append(logRecords, lr)
+	}
+
+	request := otlpLogsRequest{ResourceLogs: []otlpResourceLogs{{Resource: otlpResource{Attributes: otlpAttributes(s.getResourceAttributes(bag))},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: otlpScopeName, Version: version.Version}, LogRecords: logRecords}}}}}
+	return s.post(bag, OTLP_LOGS_PATH, request)
+}
+
+//the time of the record, if the schema has one
+func getOtlpRecordTime(record map[string]interface{}, now time.Time) time.Time {
+	for _, field := range []string{"timestamp", "lastTimestamp", "lastSeen", "creationTimestamp"} {
+		if val, ok := record[field].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, val); err == nil && !t.IsZero() && t.Year() > 1 {
+				return t
+			}
+		}
+	}
+	return now
+}
+
+func getOtlpSeverity(record map[string]interface{}) (string, int) {
+	for _, field := range []string{"level", "severity", "type"} {
+		if val, ok := record[field].(string); ok && val != "" {
+			if number, known := otlpSeverities[strings.ToUpper(val)]; known {
+				return strings.ToUpper(val), number
+			}
+		}
+	}
+	return "", 0
+}
+
+func (s *OtlpSink) post(bag *m.AppDBag, path string, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("Problems when serializing OTLP request. %v", err)
+	}
+	url := strings.TrimRight(bag.OtlpEndpoint, "/") + path
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("Unable to create OTLP request. %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range bag.OtlpHeaders {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to post to %s. %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("OTLP receiver %s returned %s. %s", url, resp.Status, string(body))
+	}
+	s.logger.Debugf("Posted %d bytes to %s\n", len(data), url)
+	return nil
+}
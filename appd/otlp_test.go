@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+type otlpRequest struct {
+	Path    string
+	Headers http.Header
+	Body    []byte
+}
+
+func newOtlpTestSink(t *testing.T) (*OtlpSink, *[]otlpRequest, func()) {
+	requests := []otlpRequest{}
+	lock := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		requests = append(requests, otlpRequest{Path: r.URL.Path, Headers: r.Header, Body: body})
+		lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	bag := m.GetDefaultProperties()
+	bag.AppName = "test-cluster"
+	bag.OtlpEndpoint = server.URL + "/"
+	bag.OtlpHeaders = map[string]string{"Authorization": "Bearer secret"}
+	cm := &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: logger}
+	sink, err := NewOtlpSink(cm, logger)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Unable to create the otlp sink. %v", err)
+	}
+	return sink, &requests, server.Close
+}
+
+func otlpAttributeMap(list []otlpKeyValue) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	for _, kv := range list {
+		for _, v := range kv.Value {
+			attributes[kv.Key] = v
+		}
+	}
+	return attributes
+}
+
+func checkOtlpHeaders(t *testing.T, req otlpRequest) {
+	if ct := req.Headers.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json on %s, got %q", req.Path, ct)
+	}
+	if auth := req.Headers.Get("Authorization"); auth != "Bearer secret" {
+		t.Errorf("Expected the configured Authorization header on %s, got %q", req.Path, auth)
+	}
+}
+
+func TestOtlpSinkPostMetrics(t *testing.T) {
+	sink, requests, stop := newOtlpTestSink(t)
+	defer stop()
+
+	crashLoops := m.NewAppDMetric("CrashLoops", 3, m.RootPath+"Namespaces|shop|Deployments|web|Events|")
+	crashLoops.MetricDelta = true
+	podCount := m.NewAppDMetric("PodCount", 5, m.RootPath)
+	if err := sink.PostMetrics(m.AppDMetricList{Items: []m.AppDMetric{crashLoops, podCount}}); err != nil {
+		t.Fatalf("Unable to post metrics. %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Path != OTLP_METRICS_PATH {
+		t.Errorf("Expected the metrics at %s, got %s", OTLP_METRICS_PATH, req.Path)
+	}
+	checkOtlpHeaders(t, req)
+
+	var body otlpMetricsRequest
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		t.Fatalf("Invalid OTLP metrics body. %v\n%s", err, string(req.Body))
+	}
+	if len(body.ResourceMetrics) != 2 {
+		t.Fatalf("Expected one resource per entity, got %d", len(body.ResourceMetrics))
+	}
+
+	tier := body.ResourceMetrics[0]
+	attributes := otlpAttributeMap(tier.Resource.Attributes)
+	expected := map[string]string{"k8s.cluster.name": "test-cluster", "k8s.namespace.name": "shop", "service.name": "web"}
+	for k, v := range expected {
+		if attributes[k] != v {
+			t.Errorf("Expected resource attribute %s=%s, got %v", k, v, attributes[k])
+		}
+	}
+	if _, ok := attributes["service.version"]; ok {
+		t.Errorf("Expected no agent version on the tier resource")
+	}
+	if _, ok := attributes["k8s.deployment.name"]; ok {
+		t.Errorf("Expected the tier not to be reported as a deployment")
+	}
+	metric := tier.ScopeMetrics[0].Metrics[0]
+	if metric.Name != "appd.tier.events_crash_loops" {
+		t.Errorf("Unexpected metric name %s", metric.Name)
+	}
+	if metric.Sum == nil || metric.Gauge != nil {
+		t.Fatalf("Expected the delta metric as a sum, got %+v", metric)
+	}
+	if metric.Sum.AggregationTemporality != otlpTemporalityDelta || !metric.Sum.IsMonotonic {
+		t.Errorf("Expected a monotonic delta sum, got %+v", metric.Sum)
+	}
+	point := metric.Sum.DataPoints[0]
+	if point.AsInt != "3" || point.StartTimeUnixNano == "" {
+		t.Errorf("Unexpected data point %+v", point)
+	}
+
+	cluster := body.ResourceMetrics[1]
+	attributes = otlpAttributeMap(cluster.Resource.Attributes)
+	if attributes["service.name"] != otlpScopeName || attributes["service.version"] == nil {
+		t.Errorf("Expected the agent as the service of the cluster resource, got %v", attributes)
+	}
+	metric = cluster.ScopeMetrics[0].Metrics[0]
+	if metric.Name != "appd.cluster.pod_count" || metric.Gauge == nil || metric.Sum != nil {
+		t.Fatalf("Expected pod_count as a gauge, got %+v", metric)
+	}
+	if metric.Gauge.DataPoints[0].AsInt != "5" {
+		t.Errorf("Expected pod_count 5, got %s", metric.Gauge.DataPoints[0].AsInt)
+	}
+}
+
+func TestOtlpSinkPostEvents(t *testing.T) {
+	sink, requests, stop := newOtlpTestSink(t)
+	defer stop()
+
+	records := []map[string]interface{}{{"namespace": "shop", "podName": "web-1", "type": "Warning", "message": "Back-off restarting failed container",
+		"lastTimestamp": "2020-01-02T03:04:05Z", "count": 2, "involvedObject": map[string]string{"kind": "Pod"}}}
+	if err := sink.PostEvents("k8s_events", nil, records); err != nil {
+		t.Fatalf("Unable to post events. %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Path != OTLP_LOGS_PATH {
+		t.Errorf("Expected the records at %s, got %s", OTLP_LOGS_PATH, req.Path)
+	}
+	checkOtlpHeaders(t, req)
+
+	var body otlpLogsRequest
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		t.Fatalf("Invalid OTLP logs body. %v\n%s", err, string(req.Body))
+	}
+	if len(body.ResourceLogs) != 1 || len(body.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("Expected one resource and scope, got %s", string(req.Body))
+	}
+	logRecords := body.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(logRecords) != 1 {
+		t.Fatalf("Expected 1 log record, got %d", len(logRecords))
+	}
+	lr := logRecords[0]
+	if lr.SeverityText != "WARNING" || lr.SeverityNumber != 13 {
+		t.Errorf("Expected severity WARNING/13, got %s/%d", lr.SeverityText, lr.SeverityNumber)
+	}
+	if lr.TimeUnixNano != "1577934245000000000" {
+		t.Errorf("Expected the time of the event, got %s", lr.TimeUnixNano)
+	}
+	if lr.Body["stringValue"] != "Back-off restarting failed container" {
+		t.Errorf("Expected the message as the body, got %v", lr.Body)
+	}
+	attributes := otlpAttributeMap(lr.Attributes)
+	expected := map[string]interface{}{"appd.schema": "k8s_events", "k8s.namespace.name": "shop", "k8s.pod.name": "web-1", "appd.podName": "web-1", "appd.count": "2"}
+	for k, v := range expected {
+		if attributes[k] != v {
+			t.Errorf("Expected log attribute %s=%v, got %v", k, v, attributes[k])
+		}
+	}
+	if _, ok := attributes["appd.involvedObject"]; ok {
+		t.Errorf("Expected nested fields to be left out of the attributes")
+	}
+}
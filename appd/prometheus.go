@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockPrometheus = sync.Mutex{}
+
+//series that were not refreshed in this many sync intervals are no longer exposed, e.g. metrics of deleted pods
+const prometheusStaleIntervals = 3
+
+//path sections of the AppD metrics that are followed by the name of an entity
+var prometheusPathLabels = map[string]struct {
+	Label string
+	Scope string
+}{
+	m.METRIC_PATH_NAMESPACES:       {"namespace", "namespace"},
+	m.METRIC_PATH_APPS:             {"tier", "tier"},
+	m.METRIC_PATH_NODES:            {"node", "node"},
+	m.METRIC_PATH_CONT:             {"container", "container"},
+	m.METRIC_PATH_INSTANCES:        {"pod", "pod"},
+	m.METRIC_PATH_PORTS:            {"port", "port"},
+	m.METRIC_PATH_SERVICES:         {"service", "service"},
+	m.METRIC_PATH_SERVICES_EP:      {"endpoint", "endpoint"},
+	m.METRIC_PATH_REGISTRIES:       {"registry", "registry"},
+	m.METRIC_PATH_CUSTOM_RESOURCES: {"kind", "custom_resource"},
+}
+
+type prometheusSample struct {
+	Name    string
+	Labels  map[string]string
+	Value   int64
+	Updated time.Time
+}
+
+/*
+ Keeps the last value of every metric and exposes them in the Prometheus text format.
+ The metric paths are mapped to names prefixed with the scope of the metric and labels, e.g.
+ Cluster Stats|Namespaces|web|Deployments|frontend|PodRestarts -> appd_tier_pod_restarts{namespace="web",tier="frontend"}
+*/
+type PrometheusSink struct {
+	ConfManager *config.MutexConfigManager
+	Samples     map[string]prometheusSample //AppD metric path -> sample
+	logger      *log.Logger
+}
+
+func NewPrometheusSink(cm *config.MutexConfigManager, logger *log.Logger) *PrometheusSink {
+	return &PrometheusSink{ConfManager: cm, Samples: make(map[string]prometheusSample), logger: logger}
+}
+
+func (ps *PrometheusSink) PostMetrics(metrics m.AppDMetricList) error {
+	now := time.Now()
+	lockPrometheus.Lock()
+	defer lockPrometheus.Unlock()
+	for _, metric := range metrics.Items {
+		name, labels := GetPrometheusName(metric.MetricPath)
+		ps.Samples[metric.MetricPath] = prometheusSample{Name: name, Labels: labels, Value: metric.MetricValue, Updated: now}
+	}
+	return nil
+}
+
+//maps the pipe-delimited AppD metric path to a Prometheus metric name and labels
+func GetPrometheusName(metricPath string) (string, map[string]string) {
+	scope, name, labels := parseMetricPath(metricPath)
+	return fmt.Sprintf("appd_%s_%s", scope, name), labels
+}
+
+//splits the metric path into the scope of the metric, the snake case name and the entities in the path
+func parseMetricPath(metricPath string) (string, string, map[string]string) {
+	labels := make(map[string]string)
+	segments := strings.Split(strings.TrimPrefix(metricPath, m.RootPath), m.METRIC_SEPARATOR)
+	scope := "cluster"
+	parts := []string{}
+	last := len(segments) - 1
+	for i := 0; i < last; i++ {
+		if section, ok := prometheusPathLabels[segments[i]]; ok && i+1 < last {
+			labels[section.Label] = segments[i+1]
+			scope = section.Scope
+			parts = parts[:0]
+			i++
+			continue
+		}
+		parts = append(parts, toSnakeCase(segments[i]))
+	}
+	parts = append(parts, toSnakeCase(segments[last]))
+	return scope, strings.Join(parts, "_"), labels
+}
+
+//PodRestarts -> pod_restarts, CPUUsage -> cpu_usage. Characters invalid in metric names are replaced by "_"
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := []string{}
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := []string{}
+	for _, k := range keys {
+		list = append(list, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+	return "{" + strings.Join(list, ",") + "}"
+}
+
+//serves the metrics in the Prometheus text exposition format
+func (ps *PrometheusSink) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	bag := (*ps.ConfManager).Get()
+	staleAfter := time.Duration(prometheusStaleIntervals*bag.MetricsSyncInterval) * time.Second
+	now := time.Now()
+
+	series := make(map[string][]string) //metric name -> samples
+	lockPrometheus.Lock()
+	for path, sample := range ps.Samples {
+		if now.Sub(sample.Updated) > staleAfter {
+			delete(ps.Samples, path)
+			continue
+		}
+		series[sample.Name] = append(series[sample.Name], fmt.Sprintf("%s%s %d", sample.Name, formatLabels(sample.Labels), sample.Value))
+	}
+	lockPrometheus.Unlock()
+
+	names := []string{}
+	for name := range series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range names {
+		samples := series[name]
+		sort.Strings(samples)
+		io.WriteString(w, fmt.Sprintf("# TYPE %s gauge\n%s\n", name, strings.Join(samples, "\n")))
+	}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,8 +12,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"reflect"
-	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,6 +21,23 @@ import (
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
+var lockEventsGzip = sync.RWMutex{}
+
+//set when the Events API rejects compressed requests
+var eventsGzipUnsupported = false
+
+func isEventsGzipUnsupported() bool {
+	lockEventsGzip.RLock()
+	defer lockEventsGzip.RUnlock()
+	return eventsGzipUnsupported
+}
+
+func setEventsGzipUnsupported() {
+	lockEventsGzip.Lock()
+	defer lockEventsGzip.Unlock()
+	eventsGzipUnsupported = true
+}
+
 type RestClient struct {
 	logger *log.Logger
 	Bag    *m.AppDBag
@@ -34,15 +52,11 @@ func NewRestClient(bag *m.AppDBag, logger *log.Logger) *RestClient {
 }
 
 func (rc *RestClient) getClient() *http.Client {
-	if rc.Bag.ProxyUrl != "" {
-		proxyUrl, err := url.Parse(rc.Bag.ProxyUrl)
-		if err != nil {
-			rc.logger.Error("Proxy url is invalid")
-			return &http.Client{}
-		}
-		return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+	timeout := rc.Bag.HttpTimeout
+	if timeout <= 0 {
+		timeout = httpDefaultTimeout
 	}
-	return &http.Client{}
+	return &http.Client{Transport: getHttpTransport(rc.Bag, rc.logger), Timeout: time.Duration(timeout) * time.Second}
 }
 
 func (rc *RestClient) addProxyAuth(req *http.Request) {
@@ -66,8 +80,7 @@ func (rc *RestClient) LoadSchema(schemaName string) (*map[string]interface{}, er
 		req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
 		req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
 		//		fmt.Printf("Sending request. Account: %s   Event Key %s", rc.Bag.GlobalAccount, rc.Bag.EventKey)
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := rc.do(req)
 		if err != nil {
 			rc.logger.Errorf("Unable to load event schema %s. %v", schemaName, err)
 			return nil, err
@@ -106,8 +119,7 @@ func (rc *RestClient) SchemaExists(schemaName string) bool {
 		req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
 		req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := rc.do(req)
 		if err != nil {
 			rc.logger.Errorf("Unable to check event schema %s. %v", schemaName, err)
 			return false
@@ -125,119 +137,13 @@ func (rc *RestClient) SchemaExists(schemaName string) bool {
 	}
 }
 
+//creates the schema or adds the new fields. See ResolveSchema
 func (rc *RestClient) EnsureSchema(schemaName string, current m.AppDSchemaInterface) error {
-	if rc.Bag.SchemaUpdateCache == nil {
-		rc.Bag.SchemaUpdateCache = []string{}
-	}
-
-	schemaTypeName := reflect.TypeOf(current).Name()
-
-	//if the schema is marked 'SKIP'
-	if strings.Contains(strings.ToLower(schemaName), "skip") {
-		ar := strings.Split(schemaName, "^")
-		if len(ar) == 2 {
-			oldName := ar[0]
-			if rc.Bag.SchemaSkipCache == nil {
-				rc.Bag.SchemaSkipCache = []string{}
-			}
-
-			if !utils.StringInSlice(schemaTypeName, rc.Bag.SchemaSkipCache) {
-				rc.Bag.SchemaSkipCache = append(rc.Bag.SchemaSkipCache, schemaTypeName)
-				err := rc.DeleteSchema(oldName)
-				if err != nil {
-					return fmt.Errorf("The schema %s was marked for deletion, but Delete call failed. %v", oldName, err)
-				}
-				rc.logger.Infof("Schema %s deleted per user request", oldName)
-				index := utils.GetIndex(oldName, rc.Bag.SchemaUpdateCache)
-				if index >= 0 {
-					rc.Bag.SchemaUpdateCache[index] = rc.Bag.SchemaUpdateCache[len(rc.Bag.SchemaUpdateCache)-1]
-					rc.Bag.SchemaUpdateCache = rc.Bag.SchemaUpdateCache[:len(rc.Bag.SchemaUpdateCache)-1]
-				}
-			}
-			return nil
-		} else {
-			return fmt.Errorf("Schema name %s is invalid", schemaName)
-		}
-	}
-
-	//perform validity check
-
-	if rc.Bag.SchemaUpdateCache != nil && utils.StringInSlice(schemaName, rc.Bag.SchemaUpdateCache) {
-		return nil
-	} else {
-		rc.Bag.SchemaUpdateCache = append(rc.Bag.SchemaUpdateCache, schemaName)
-		skipIndex := utils.GetIndex(schemaTypeName, rc.Bag.SchemaSkipCache)
-		if skipIndex >= 0 {
-			rc.Bag.SchemaSkipCache[skipIndex] = rc.Bag.SchemaSkipCache[len(rc.Bag.SchemaSkipCache)-1]
-			rc.Bag.SchemaSkipCache = rc.Bag.SchemaSkipCache[:len(rc.Bag.SchemaSkipCache)-1]
-		}
-
-	}
-	wrapper, err := rc.LoadSchema(schemaName)
-	if err != nil {
-		rc.logger.Warnf("Unable load existing schema %s. Attempting to create. %v\n", schemaName, err)
-		//try to create
-		schemaDef, e := json.Marshal(current)
-		if e != nil {
-			return fmt.Errorf("Unable to serialize the current schema %s. %v", schemaName, e)
-		}
-		_, err = rc.CreateSchema(schemaName, schemaDef)
-		if err != nil {
-			return fmt.Errorf("Unable to recreate the current schema %s. %v", schemaName, err)
-		}
-		rc.logger.Infof("Schema %s created. \n", schemaName)
+	_, err := rc.ResolveSchema(schemaName, current)
+	if err == ErrSchemaSkipped {
 		return nil
 	}
-	equal := true
-	currentMap := current.Unwrap()
-	currentSchema := (*currentMap)["Schema"]
-	currentObj := currentSchema.(map[string]interface{})
-
-	if schema, ok := (*wrapper)["schema"]; ok {
-		schemaObj := schema.(map[string]interface{})
-		//delete built in fields
-		delete(schemaObj, "pickupTimestamp")
-		delete(schemaObj, "eventTimestamp")
-
-		if len(currentObj) != len(schemaObj) {
-			rc.logger.Infof("Schema %s has changed, %d vs %d fields...\n", schemaName, len(currentObj), len(schemaObj))
-			equal = false
-		}
-		if equal {
-			for k, v := range currentObj {
-				currentVal, okCur := utils.MapContainsNocase(schemaObj, k)
-				if !okCur || currentVal != v {
-					rc.logger.Infof("Schema %s has descrepancy in field %s: %v != %v ...\n", schemaName, k, currentVal, v)
-					equal = false
-					break
-				}
-			}
-		}
-	} else {
-		equal = false //invalid object
-	}
-
-	if !equal {
-		rc.logger.Infof("Schema %s has changed, recreating...\n", schemaName)
-		err := rc.DeleteSchema(schemaName)
-		if err != nil {
-			return fmt.Errorf("Unable to delete changed schema. %v", err)
-		}
-		rc.logger.Infof("Deleted the old schema %s. \n", schemaName)
-		schemaDef, e := json.Marshal(current)
-		if e != nil {
-			return fmt.Errorf("Unable to serialize the current schema %s. %v", schemaName, e)
-		}
-		_, err = rc.CreateSchema(schemaName, schemaDef)
-		if err != nil {
-			return fmt.Errorf("Unable to recreate the current schema %s. %v", schemaName, err)
-		}
-		rc.logger.Infof("Schema %s created. \n", schemaName)
-
-	} else {
-		rc.logger.Infof("Schema %s has not changed.\n", schemaName)
-	}
-	return nil
+	return err
 }
 
 func (rc *RestClient) DeleteSchema(schemaName string) error {
@@ -250,8 +156,7 @@ func (rc *RestClient) DeleteSchema(schemaName string) error {
 		req.Header.Set("Content-Type", "application/vnd.appd.events+json;v=2")
 		req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
 		req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := rc.do(req)
 		if err != nil {
 			rc.logger.Errorf("Unable to delete event schema %s. %v", schemaName, err)
 			return err
@@ -283,8 +188,7 @@ func (rc *RestClient) CreateSchema(schemaName string, data []byte) ([]byte, erro
 		req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
 		req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := rc.do(req)
 		if err != nil {
 			rc.logger.Errorf("Unable to create event schema %s. %v", schemaName, err)
 			return nil, err
@@ -303,29 +207,83 @@ func (rc *RestClient) CreateSchema(schemaName string, data []byte) ([]byte, erro
 	}
 }
 
-func (rc *RestClient) PostAppDEvents(schemaName string, data []byte) []byte {
+//response of the Events API other than 2xx
+type PublishError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("Events API request failed with status %s. Message: %s", e.Status, e.Message)
+}
+
+//true if the request may succeed later: network errors, throttling and server errors
+func IsRetriable(err error) bool {
+	if pe, ok := err.(*PublishError); ok {
+		return pe.StatusCode == http.StatusRequestTimeout || pe.StatusCode == http.StatusTooManyRequests || pe.StatusCode >= 500
+	}
+	return err != nil
+}
+
+func IsPayloadTooLarge(err error) bool {
+	pe, ok := err.(*PublishError)
+	return ok && pe.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+func (rc *RestClient) PostAppDEvents(schemaName string, data []byte) ([]byte, error) {
 	rc.logger.Debugf("PostAppDEvents Payload: %s", string(data))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/events/publish/%s", rc.Bag.EventServiceUrl, schemaName), bytes.NewBuffer(data))
+	compress := rc.Bag.EventAPICompression && !isEventsGzipUnsupported()
+	body, status, err := rc.postAppDEvents(schemaName, data, compress)
+	//the Events API may not accept compressed requests. Compression is turned off if the uncompressed request goes through
+	if compress && (status == http.StatusUnsupportedMediaType || status == http.StatusBadRequest) {
+		body, _, err = rc.postAppDEvents(schemaName, data, false)
+		if status == http.StatusUnsupportedMediaType || err == nil {
+			rc.logger.Warnf("Events API rejected the compressed request with status %d. Sending uncompressed requests\n", status)
+			setEventsGzipUnsupported()
+		}
+	}
+	return body, err
+}
+
+func (rc *RestClient) postAppDEvents(schemaName string, data []byte, compress bool) ([]byte, int, error) {
+	payload := data
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, 0, fmt.Errorf("Unable to compress events. %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, 0, fmt.Errorf("Unable to compress events. %v", err)
+		}
+		payload = buf.Bytes()
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/events/publish/%s", rc.Bag.EventServiceUrl, schemaName), bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Unable to initiate request. %v", err)
+	}
 	req.Header.Set("Accept", "application/vnd.appd.events+json;v=2")
 	req.Header.Set("Content-Type", "application/vnd.appd.events+json;v=2")
 	req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
 	req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.do(req)
 	if err != nil {
 		rc.logger.Errorf("Unable to post events. %v", err)
+		return nil, 0, fmt.Errorf("Unable to post events. %v", err)
 	}
-	if resp != nil && resp.Body != nil {
-		defer resp.Body.Close()
-		rc.logger.Debugf("PostAppDEvents Status: %s", resp.Status)
-		body, _ := ioutil.ReadAll(resp.Body)
-		if resp.StatusCode < 200 || resp.StatusCode > 204 {
-			rc.logger.Debugf("PostAppDEvents Body: %s", string(body))
-		}
-		return body
+	defer resp.Body.Close()
+	rc.logger.Debugf("PostAppDEvents Status: %s. %d bytes sent", resp.Status, len(payload))
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
+		rc.logger.Debugf("PostAppDEvents Body: %s", string(body))
+		return body, resp.StatusCode, &PublishError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(body)}
 	}
-	return nil
+	return body, resp.StatusCode, nil
 }
 
 func (rc *RestClient) GetRestAuth() (AppDRestAuth, error) {
@@ -341,14 +299,17 @@ func (rc *RestClient) GetRestAuth() (AppDRestAuth, error) {
 	}
 	authHeader := "Basic " + creds
 	req.Header.Set("Authorization", authHeader)
-	rc.logger.Debugf("Header: %s", authHeader)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.do(req)
 	if err != nil {
 		rc.logger.Errorf("Issues obtaining session and cookie. %v", err)
 		return restAuth, err
 	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
+		return restAuth, fmt.Errorf("Controller login failed with status %s", resp.Status)
+	}
 	for _, cookie := range resp.Cookies() {
 		if cookie.Name == "X-CSRF-TOKEN" {
 			restAuth.Token = cookie.Value
@@ -366,26 +327,21 @@ func (rc *RestClient) getControllerUrl() string {
 }
 
 func (rc *RestClient) CallAppDController(path, method string, data []byte) ([]byte, error) {
-	auth, err := rc.GetRestAuth()
-	if err != nil {
-		return nil, fmt.Errorf("Auth failed. Cannot call AppD controller")
-	}
-
 	url := rc.getControllerUrl() + path
 	var body io.Reader = nil
 	if data != nil {
 		body = bytes.NewBuffer(data)
 	}
 	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create request. %v", err)
+	}
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	if method == "POST" {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	req.Header.Set("X-CSRF-TOKEN", auth.Token)
-	req.Header.Set("Cookie", auth.getAuthCookie())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.doAuthorized(req, true)
 	if err != nil {
 		rc.logger.Errorf("Failed to call AppD controller. %v", err)
 		return nil, err
@@ -407,11 +363,6 @@ func (rc *RestClient) CreateDashboard(templatePath string) ([]byte, error) {
 
 	rc.logger.Debugf("\nCreating dashboard: %s\n", url)
 
-	bu := []byte(rc.Bag.RestAPICred)
-	creds := base64.StdEncoding.EncodeToString(bu)
-
-	rc.logger.Debugf("Credentials: %s\n", creds)
-
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -444,13 +395,11 @@ func (rc *RestClient) CreateDashboard(templatePath string) ([]byte, error) {
 	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		rc.logger.Errorf("Unable to create request for dashboard post. %v\n", err)
+		return nil, err
 	}
-	authHeader := "Basic " + creds
-	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.doAuthorized(req, false)
 	if err != nil {
 		rc.logger.Errorf("Unable to create dashboard. %v\n", err)
 		return nil, err
@@ -471,17 +420,39 @@ func (rc *RestClient) MarkNodeHistorical(nodeId int) error {
 	if err != nil {
 		return fmt.Errorf("Unable to create request for mark node historical. %v\n", err)
 	}
-	ar := strings.Split(rc.Bag.RestAPICred, ":")
-	if len(ar) != 2 {
-		return fmt.Errorf("Rest API credentials are formatted incorrectly. Must be <username>@<account>:<password>")
-	}
-
-	req.SetBasicAuth(ar[0], ar[1])
-	client := &http.Client{}
-	_, errReq := client.Do(req)
+	resp, errReq := rc.doAuthorized(req, false)
 	if errReq != nil {
 		return fmt.Errorf("Unable to mark node as historical. %v\n", errReq)
 	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (rc *RestClient) PostApplicationEvent(appName string, eventType string, severity string, summary string, comment string) error {
+	params := url.Values{}
+	params.Set("eventtype", eventType)
+	params.Set("severity", severity)
+	params.Set("summary", utils.TruncateString(summary, MAX_FIELD_LENGTH))
+	if comment != "" {
+		params.Set("comment", utils.TruncateString(comment, MAX_FIELD_LENGTH))
+	}
+	path := fmt.Sprintf("%srest/applications/%s/events?%s", rc.getControllerUrl(), url.PathEscape(appName), params.Encode())
+
+	req, err := http.NewRequest("POST", path, nil)
+	if err != nil {
+		return fmt.Errorf("Unable to create request for application event. %v\n", err)
+	}
+	resp, err := rc.doAuthorized(req, false)
+	if err != nil {
+		return fmt.Errorf("Unable to post application event. %v\n", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 202 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Application event request failed with status %s. %s", resp.Status, string(b))
+	}
 
 	return nil
 }
@@ -493,14 +464,7 @@ func (rc *RestClient) GetControllerVersion() ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create request to obtain controller version. %v\n", err)
 	}
-	ar := strings.Split(rc.Bag.RestAPICred, ":")
-	if len(ar) != 2 {
-		return nil, fmt.Errorf("Rest API credentials are formatted incorrectly. Must be <username>@<account>:<password>")
-	}
-
-	req.SetBasicAuth(ar[0], ar[1])
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.doAuthorized(req, false)
 	if err != nil {
 		rc.logger.Errorf("Failed to get controller version. %v", err)
 		return nil, err
@@ -524,14 +488,7 @@ func (rc *RestClient) validateLicense(accountID int) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("Unable to get license information for account %d. %v\n", accountID, err)
 	}
-	ar := strings.Split(rc.Bag.RestAPICred, ":")
-	if len(ar) != 2 {
-		return false, fmt.Errorf("Rest API credentials are formatted incorrectly. Must be <username>@<account>:<password>")
-	}
-
-	req.SetBasicAuth(ar[0], ar[1])
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := rc.doAuthorized(req, false)
 	if err != nil {
 		rc.logger.Errorf("Failed to get license information for account %d. %v", accountID, err)
 		return false, err
@@ -0,0 +1,488 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockSchemas = sync.Mutex{}
+
+//returned instead of the state of a schema whose name is set to "skip". The records are not published
+var ErrSchemaSkipped = errors.New("Publishing of the schema is turned off")
+
+const (
+	SCHEMA_VERSION_SUFFIX string = "_v"
+	SCHEMA_SKIP_SEPARATOR string = "^"
+
+	//incompatible versions skipped before giving up
+	maxSchemaVersions int = 10
+)
+
+//fields managed by the Events API
+var builtInSchemaFields = []string{"pickupTimestamp", "eventTimestamp"}
+
+//configured schema name -> state of the schema
+var schemaStates = make(map[string]*m.SchemaStatus)
+
+//previous version -> start of the transition period. Kept in SchemaStateFile
+var schemaTransitions = make(map[string]time.Time)
+var schemaTransitionsLoaded = false
+
+//configured schema names deleted per user request
+var skippedSchemas = make(map[string]bool)
+
+//name of the schema of the version. Version 1 has no suffix
+func GetSchemaVersionName(schemaName string, version int) string {
+	if version <= 1 {
+		return schemaName
+	}
+	return fmt.Sprintf("%s%s%d", schemaName, SCHEMA_VERSION_SUFFIX, version)
+}
+
+func getSchemaVersion(current m.AppDSchemaInterface) int {
+	if vs, ok := current.(m.AppDVersionedSchema); ok && vs.Version() > 1 {
+		return vs.Version()
+	}
+	return 1
+}
+
+//field -> type of the schema definition, as it is sent to the Events API
+func GetSchemaFields(current m.AppDSchemaInterface) (map[string]string, error) {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		Schema map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	for field, fieldType := range wrapper.Schema {
+		fields[field] = fmt.Sprintf("%v", fieldType)
+	}
+	return fields, nil
+}
+
+func getFieldType(fields map[string]string, field string) (string, bool) {
+	for k, v := range fields {
+		if strings.EqualFold(k, field) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+//fields of the expected schema with a different type in the live schema
+func getSchemaConflicts(live map[string]string, expected map[string]string) map[string]string {
+	conflicts := make(map[string]string)
+	for field, expectedType := range expected {
+		if liveType, ok := getFieldType(live, field); ok && !strings.EqualFold(liveType, expectedType) {
+			conflicts[field] = fmt.Sprintf("%s != %s", liveType, expectedType)
+		}
+	}
+	return conflicts
+}
+
+//fields of a that b does not have
+func getMissingFields(a map[string]string, b map[string]string) []string {
+	missing := []string{}
+	for field := range a {
+		if _, ok := getFieldType(b, field); !ok {
+			missing = append(missing, field)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+//the live schema without the built-in fields. Nil if the schema does not exist
+func (rc *RestClient) loadSchemaFields(schemaName string) (map[string]string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/events/schema/%s", rc.Bag.EventServiceUrl, schemaName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to initiate request. %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.appd.events+json;v=2")
+	req.Header.Set("Content-Type", "application/vnd.appd.events+json;v=2")
+	req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
+	req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
+
+	resp, err := rc.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load event schema %s. %v", schemaName, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound || (resp.StatusCode == http.StatusOK && len(body) == 0) {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
+		return nil, &PublishError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(body)}
+	}
+	var wrapper struct {
+		Schema map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, fmt.Errorf("Unable to deserialize the schema %s. %v", schemaName, err)
+	}
+	fields := make(map[string]string)
+	for field, fieldType := range wrapper.Schema {
+		fields[field] = fmt.Sprintf("%v", fieldType)
+	}
+	for _, field := range builtInSchemaFields {
+		delete(fields, field)
+	}
+	return fields, nil
+}
+
+//adds the fields to the live schema. The Events API does not support other changes of an existing schema
+func (rc *RestClient) addSchemaFields(schemaName string, fields map[string]string) error {
+	data, err := json.Marshal([]map[string]map[string]string{{"add": fields}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PATCH", fmt.Sprintf("%s/events/schema/%s", rc.Bag.EventServiceUrl, schemaName), bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("Unable to initiate request. %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.appd.events+json;v=2")
+	req.Header.Set("Content-Type", "application/vnd.appd.events+json;v=2")
+	req.Header.Set("X-Events-API-AccountName", rc.Bag.GlobalAccount)
+	req.Header.Set("X-Events-API-Key", rc.Bag.EventKey)
+
+	resp, err := rc.do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to update event schema %s. %v", schemaName, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
+		return fmt.Errorf("Schema update failed with status %s. Message: %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+/*
+ Finds the schema the records are written to and brings it up to date. A missing schema is created,
+ fields missing in the live schema are added. If the type of a field changed, the next version of the schema is used.
+ During the transition period, the records are also written to the previous version, without the fields that it does not accept.
+ The result is cached until the schema is invalidated
+*/
+func (rc *RestClient) ResolveSchema(schemaName string, current m.AppDSchemaInterface) (*m.SchemaStatus, error) {
+	lockSchemas.Lock()
+	defer lockSchemas.Unlock()
+
+	if isSkippedSchema(schemaName) {
+		return nil, rc.skipSchema(schemaName)
+	}
+	//the name has been restored and can be skipped again
+	delete(skippedSchemas, schemaName)
+
+	now := time.Now()
+	if state, ok := schemaStates[schemaName]; ok && state.Error == "" {
+		if state.TransitionEnd != nil && now.After(*state.TransitionEnd) {
+			rc.logger.Infof("Transition period of schema %s is over. Records are written to %s only\n", state.Previous, state.Target)
+			state.Previous = ""
+			state.TransitionEnd = nil
+		}
+		return state, nil
+	}
+
+	expected, err := GetSchemaFields(current)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize the current schema %s. %v", schemaName, err)
+	}
+	version := getSchemaVersion(current)
+	state := &m.SchemaStatus{Name: schemaName, Version: version, Expected: expected, Added: []string{}, Conflicts: map[string]string{}, Checked: now}
+	schemaStates[schemaName] = state
+
+	err = rc.resolveSchemaVersion(state, current)
+	if err != nil {
+		state.Error = err.Error()
+		return nil, err
+	}
+
+	//records of the previous version go to both schemas for a while
+	if state.Previous != "" {
+		start := rc.getTransitionStart(state.Previous, now)
+		end := start.Add(time.Duration(rc.Bag.SchemaTransitionPeriod) * time.Hour)
+		if now.Before(end) {
+			state.TransitionEnd = &end
+			rc.logger.Infof("Records of schema %s are written to %s and %s until %s\n", schemaName, state.Target, state.Previous, end.Format(time.RFC3339))
+		} else {
+			state.Previous = ""
+		}
+	}
+	return state, nil
+}
+
+/*
+ Start of the transition period of the previous version. The starts are saved to SchemaStateFile,
+ so that restarts of the agent do not extend the transition periods. Must be called under lockSchemas
+*/
+func (rc *RestClient) getTransitionStart(previous string, now time.Time) time.Time {
+	if !schemaTransitionsLoaded {
+		schemaTransitionsLoaded = true
+		rc.loadSchemaTransitions()
+	}
+	start, ok := schemaTransitions[previous]
+	if !ok {
+		start = now
+		schemaTransitions[previous] = start
+		rc.saveSchemaTransitions()
+	}
+	return start
+}
+
+func (rc *RestClient) loadSchemaTransitions() {
+	path := rc.Bag.SchemaStateFile
+	if path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			rc.logger.Warnf("Unable to read the transitions of the schemas from %s. %v\n", path, err)
+		}
+		return
+	}
+	var saved map[string]time.Time
+	if err := json.Unmarshal(data, &saved); err != nil {
+		rc.logger.Warnf("Unable to deserialize the transitions of the schemas in %s. %v\n", path, err)
+		return
+	}
+	for previous, start := range saved {
+		if _, ok := schemaTransitions[previous]; !ok {
+			schemaTransitions[previous] = start
+		}
+	}
+}
+
+func (rc *RestClient) saveSchemaTransitions() {
+	path := rc.Bag.SchemaStateFile
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(schemaTransitions)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(path+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		rc.logger.Warnf("Unable to save the transitions of the schemas to %s. The transition periods restart with the agent. %v\n", path, err)
+	}
+}
+
+/*
+ True if the schema name is set to "skip". When the name is changed to "skip" while the agent is running,
+ the config manager keeps the previous name as <previous name>^skip and the schema of the previous name is deleted
+*/
+func isSkippedSchema(schemaName string) bool {
+	ar := strings.Split(schemaName, SCHEMA_SKIP_SEPARATOR)
+	return strings.EqualFold(ar[len(ar)-1], m.SCHEMA_SKIP)
+}
+
+//deletes the versions of the previous schema once. Must be called under lockSchemas
+func (rc *RestClient) skipSchema(schemaName string) error {
+	ar := strings.SplitN(schemaName, SCHEMA_SKIP_SEPARATOR, 2)
+	if len(ar) != 2 || ar[0] == "" || skippedSchemas[ar[0]] {
+		return ErrSchemaSkipped
+	}
+	previous := ar[0]
+	names := []string{previous}
+	if state, ok := schemaStates[previous]; ok && state.Target != "" {
+		names = []string{state.Target}
+		if state.Previous != "" {
+			names = append(names, state.Previous)
+		}
+	}
+	for _, name := range names {
+		live, err := rc.loadSchemaFields(name)
+		if err != nil {
+			return fmt.Errorf("The schema %s was marked for deletion, but the schema could not be loaded. %v", name, err)
+		}
+		if live == nil {
+			continue
+		}
+		if err := rc.DeleteSchema(name); err != nil {
+			return fmt.Errorf("The schema %s was marked for deletion, but Delete call failed. %v", name, err)
+		}
+		rc.logger.Infof("Schema %s deleted per user request\n", name)
+	}
+	skippedSchemas[previous] = true
+	delete(schemaStates, previous)
+	return ErrSchemaSkipped
+}
+
+func (rc *RestClient) resolveSchemaVersion(state *m.SchemaStatus, current m.AppDSchemaInterface) error {
+	if state.Version > 1 {
+		previous := GetSchemaVersionName(state.Name, state.Version-1)
+		live, err := rc.loadSchemaFields(previous)
+		if err != nil {
+			return err
+		}
+		if live != nil {
+			state.Previous = previous
+			state.Conflicts = getSchemaConflicts(live, state.Expected)
+		}
+	}
+
+	for v := state.Version; v < state.Version+maxSchemaVersions; v++ {
+		target := GetSchemaVersionName(state.Name, v)
+		live, err := rc.loadSchemaFields(target)
+		if err != nil {
+			return err
+		}
+		if live == nil {
+			schemaDef, err := json.Marshal(current)
+			if err != nil {
+				return fmt.Errorf("Unable to serialize the current schema %s. %v", target, err)
+			}
+			if _, err := rc.CreateSchema(target, schemaDef); err != nil {
+				return fmt.Errorf("Unable to create schema %s. %v", target, err)
+			}
+			rc.logger.Infof("Schema %s created\n", target)
+			state.Target = target
+			state.Live = state.Expected
+			state.Missing = []string{}
+			state.Extra = []string{}
+			return nil
+		}
+
+		conflicts := getSchemaConflicts(live, state.Expected)
+		if len(conflicts) > 0 {
+			rc.logger.Warnf("Schema %s has incompatible field types %v. Moving to the next version\n", target, conflicts)
+			state.Previous = target
+			state.Conflicts = conflicts
+			continue
+		}
+
+		state.Target = target
+		state.Live = live
+		state.Extra = getMissingFields(live, state.Expected)
+		state.Missing = getMissingFields(state.Expected, live)
+		if len(state.Missing) > 0 {
+			add := make(map[string]string)
+			for _, field := range state.Missing {
+				add[field] = state.Expected[field]
+			}
+			if err := rc.addSchemaFields(target, add); err != nil {
+				return err
+			}
+			rc.logger.Infof("Added fields %s to schema %s\n", strings.Join(state.Missing, ", "), target)
+			state.Added = state.Missing
+			state.Missing = []string{}
+			merged := make(map[string]string)
+			for k, v := range live {
+				merged[k] = v
+			}
+			for k, v := range add {
+				merged[k] = v
+			}
+			state.Live = merged
+		}
+		return nil
+	}
+	return fmt.Errorf("No compatible version of schema %s found in %d versions", state.Name, maxSchemaVersions)
+}
+
+//forces the schema to be checked again before the next post, e.g. when its fields change
+func InvalidateSchema(schemaName string) {
+	lockSchemas.Lock()
+	defer lockSchemas.Unlock()
+	delete(schemaStates, schemaName)
+}
+
+func GetSchemaStates() []m.SchemaStatus {
+	lockSchemas.Lock()
+	defer lockSchemas.Unlock()
+	list := []m.SchemaStatus{}
+	for _, state := range schemaStates {
+		list = append(list, *state)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+//the records without the fields that the previous version does not accept
+func getPreviousVersionBatch(state *m.SchemaStatus, batch EventBatch) (EventBatch, error) {
+	previous := EventBatch{}
+	for _, record := range batch.Records {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(record, &obj); err != nil {
+			return previous, err
+		}
+		for field := range state.Conflicts {
+			delete(obj, field)
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return previous, err
+		}
+		previous.add(data)
+	}
+	return previous, nil
+}
+
+func isSchemaNotFound(err error) bool {
+	pe, ok := err.(*PublishError)
+	return ok && pe.StatusCode == http.StatusNotFound
+}
+
+//posts the batch to the current version of the schema and, during the transition period, to the previous version
+func (rc *RestClient) PostSchemaEvents(state *m.SchemaStatus, batch EventBatch) error {
+	lockSchemas.Lock()
+	target := state.Target
+	previous := state.Previous
+	lockSchemas.Unlock()
+
+	_, err := rc.PostAppDEvents(target, batch.JSON())
+	if isSchemaNotFound(err) {
+		//deleted outside of the agent. The schema is created again with the next batch
+		rc.logger.Warnf("Schema %s does not exist. It will be created with the next batch\n", target)
+		lockSchemas.Lock()
+		if schemaStates[state.Name] == state {
+			delete(schemaStates, state.Name)
+		}
+		lockSchemas.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		previousBatch, errPrev := getPreviousVersionBatch(state, batch)
+		if errPrev == nil {
+			_, errPrev = rc.PostAppDEvents(previous, previousBatch.JSON())
+		}
+		if isSchemaNotFound(errPrev) {
+			rc.logger.Warnf("Previous version %s of schema %s does not exist. Records are written to %s only\n", previous, state.Name, target)
+			lockSchemas.Lock()
+			if state.Previous == previous {
+				state.Previous = ""
+				state.TransitionEnd = nil
+			}
+			lockSchemas.Unlock()
+		} else if errPrev != nil {
+			rc.logger.Warnf("Unable to write %s records to the previous version %s. %v\n", state.Name, previous, errPrev)
+		}
+	}
+	return nil
+}
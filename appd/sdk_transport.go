@@ -0,0 +1,88 @@
+//go:build cgo
+// +build cgo
+
+package controller
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+
+	appd "appdynamics"
+)
+
+func init() {
+	RegisterMetricTransport(m.METRIC_TRANSPORT_SDK, func(cm *config.MutexConfigManager, logger *log.Logger) (MetricTransport, error) {
+		transport, err := NewSDKTransport(cm, logger)
+		if err != nil {
+			return nil, err
+		}
+		return transport, nil
+	})
+}
+
+//reports the metrics with the AppDynamics C++ SDK
+type SDKTransport struct {
+	logger *log.Logger
+}
+
+func NewSDKTransport(cm *config.MutexConfigManager, logger *log.Logger) (*SDKTransport, error) {
+	cfg := appd.Config{}
+
+	bag := (*cm).Get()
+
+	cfg.AppName = bag.AppName
+	cfg.TierName = bag.TierName
+	cfg.NodeName = bag.NodeName
+	cfg.Controller.Host = bag.ControllerUrl
+	cfg.Controller.Port = bag.ControllerPort
+	cfg.Controller.UseSSL = bag.SSLEnabled
+	if bag.SSLEnabled {
+		if bag.AgentSSLCert != "" {
+			logger.Infof("Setting custom agent cert:  %s", bag.AgentSSLCert)
+			cfg.Controller.CertificateFile = bag.AgentSSLCert
+		} else {
+			logger.Infof("Using default system cert: %s", bag.SystemSSLCert)
+			cfg.Controller.CertificateFile = bag.SystemSSLCert
+		}
+	}
+	cfg.Controller.Account = bag.Account
+	cfg.Controller.AccessKey = bag.AccessKey
+	cfg.UseConfigFromEnv = false
+	cfg.InitTimeoutMs = 1000
+	//	cfg.Logging.BaseDir = "__console__"
+	cfg.Logging.MinimumLevel = appd.APPD_LOG_LEVEL_DEBUG
+	if err := appd.InitSDK(&cfg); err != nil {
+		logger.WithField("error", err).Error("Error initializing the AppDynamics SDK.")
+		return nil, err
+	} else {
+		logger.Info("Initialized AppDynamics SDK successfully")
+	}
+	logger.Debugf("AppD Controller info: %v", &cfg.Controller)
+	return &SDKTransport{logger: logger}, nil
+}
+
+func (t *SDKTransport) RegisterMetric(metric m.AppDMetric) error {
+	appd.AddCustomMetric("", metric.MetricPath,
+		appd.RollupType(metric.MetricTimeRollUpType),
+		appd.ClusterRollupType(metric.MetricClusterRollUpType),
+		appd.APPD_HOLEHANDLING_TYPE_REGULAR_COUNTER)
+	appd.ReportCustomMetric("", metric.MetricPath, 0)
+	return nil
+}
+
+func (t *SDKTransport) ReportMetrics(metrics []m.AppDMetric) error {
+	for _, metric := range metrics {
+		appd.ReportCustomMetric("", metric.MetricPath, metric.MetricValue)
+	}
+	return nil
+}
+
+func (t *SDKTransport) StartBT(name string) BtHandle {
+	return BtHandle(appd.StartBT(name, ""))
+}
+
+func (t *SDKTransport) EndBT(bth BtHandle) {
+	appd.EndBT(appd.BtHandle(bth))
+}
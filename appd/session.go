@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var lockControllerSessions = sync.Mutex{}
+
+const (
+	OAUTH_TOKEN_PATH string = "api/oauth/access_token"
+
+	//tokens are renewed this long before they expire
+	oauthRefreshMargin time.Duration = time.Minute
+)
+
+//controller url -> session
+var controllerSessions = make(map[string]*ControllerSession)
+
+/*
+ Authentication with the controller shared by all REST calls of the agent.
+ The session of the user in RestAPICred is reused until the controller rejects it.
+ The access token of the API client in RestAPIClient is renewed before it expires
+*/
+type ControllerSession struct {
+	Auth        AppDRestAuth
+	AccessToken string
+	Expires     time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"` //seconds
+}
+
+func (rc *RestClient) useAPIClient() bool {
+	return rc.Bag.RestAPIClient != ""
+}
+
+//must be called under lockControllerSessions
+func (rc *RestClient) getControllerSession() *ControllerSession {
+	session, ok := controllerSessions[rc.getControllerUrl()]
+	if !ok {
+		session = &ControllerSession{}
+		controllerSessions[rc.getControllerUrl()] = session
+	}
+	return session
+}
+
+/*
+ Session cookie and CSRF token of the user. A new session is created if there is none or renew is set.
+ The login runs outside of the lock, so that the other calls are not blocked by a slow controller
+*/
+func (rc *RestClient) getSessionAuth(renew bool) (AppDRestAuth, error) {
+	lockControllerSessions.Lock()
+	current := rc.getControllerSession().Auth
+	lockControllerSessions.Unlock()
+	if !renew && current.SessionID != "" {
+		return current, nil
+	}
+
+	auth, err := rc.GetRestAuth()
+
+	lockControllerSessions.Lock()
+	defer lockControllerSessions.Unlock()
+	session := rc.getControllerSession()
+	if err != nil {
+		//a session created by a concurrent login is kept
+		if session.Auth.SessionID == current.SessionID {
+			session.Auth = NewRestAuth("", "")
+		}
+		return auth, err
+	}
+	session.Auth = auth
+	return auth, nil
+}
+
+//access token of the API client. A new token is obtained outside of the lock if the current one is about to expire or renew is set
+func (rc *RestClient) getAccessToken(renew bool) (string, error) {
+	lockControllerSessions.Lock()
+	current := *rc.getControllerSession()
+	lockControllerSessions.Unlock()
+	if !renew && current.AccessToken != "" && time.Until(current.Expires) > oauthRefreshMargin {
+		return current.AccessToken, nil
+	}
+
+	token, expires, err := rc.GetOAuthToken()
+
+	lockControllerSessions.Lock()
+	defer lockControllerSessions.Unlock()
+	session := rc.getControllerSession()
+	if err != nil {
+		if session.AccessToken == current.AccessToken {
+			session.AccessToken = ""
+		}
+		return "", err
+	}
+	session.AccessToken = token
+	session.Expires = expires
+	return token, nil
+}
+
+//client_credentials grant of the API client in the <clientName>@<account>:<secret> format
+func (rc *RestClient) GetOAuthToken() (string, time.Time, error) {
+	ar := strings.SplitN(rc.Bag.RestAPIClient, ":", 2)
+	if len(ar) != 2 || !strings.Contains(ar[0], "@") {
+		return "", time.Time{}, fmt.Errorf("Rest API client is formatted incorrectly. Must be <clientName>@<account>:<secret>")
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", ar[0])
+	form.Set("client_secret", ar[1])
+	req, err := http.NewRequest("POST", rc.getControllerUrl()+OAUTH_TOKEN_PATH, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Unable to create request for access token. %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := rc.do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Unable to obtain access token. %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 202 {
+		return "", time.Time{}, fmt.Errorf("Access token request failed with status %s. %s", resp.Status, string(body))
+	}
+	var tokenObj oauthTokenResponse
+	if err := json.Unmarshal(body, &tokenObj); err != nil || tokenObj.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("Unable to deserialize access token. %v", err)
+	}
+	rc.logger.Debugf("Obtained access token of API client %s. Expires in %d seconds\n", ar[0], tokenObj.ExpiresIn)
+	return tokenObj.AccessToken, time.Now().Add(time.Duration(tokenObj.ExpiresIn) * time.Second), nil
+}
+
+/*
+ Adds the credentials to the request: the access token of the API client, if configured,
+ otherwise the session of the user (session set) or basic authentication with RestAPICred
+*/
+func (rc *RestClient) authorize(req *http.Request, session bool, renew bool) error {
+	if rc.useAPIClient() {
+		token, err := rc.getAccessToken(renew)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	if session {
+		auth, err := rc.getSessionAuth(renew)
+		if err != nil {
+			return fmt.Errorf("Auth failed. Cannot call AppD controller. %v", err)
+		}
+		req.Header.Set("X-CSRF-TOKEN", auth.Token)
+		req.Header.Set("Cookie", auth.getAuthCookie())
+		return nil
+	}
+	//the password may contain ":"
+	ar := strings.SplitN(rc.Bag.RestAPICred, ":", 2)
+	if len(ar) != 2 {
+		return fmt.Errorf("Rest API credentials are formatted incorrectly. Must be <username>@<account>:<password>")
+	}
+	req.SetBasicAuth(ar[0], ar[1])
+	return nil
+}
+
+//sends the request to the controller. If the session or token is rejected with 401, authenticates again and repeats the request once
+func (rc *RestClient) doAuthorized(req *http.Request, session bool) (*http.Response, error) {
+	if err := rc.authorize(req, session, false); err != nil {
+		return nil, err
+	}
+	resp, err := rc.do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !(session || rc.useAPIClient()) {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, err
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	rc.logger.Debugf("Controller rejected the credentials of %s %s. Authenticating again\n", req.Method, req.URL.Path)
+
+	if req.GetBody != nil {
+		body, errBody := req.GetBody()
+		if errBody != nil {
+			return nil, errBody
+		}
+		req.Body = body
+	}
+	if err := rc.authorize(req, session, true); err != nil {
+		return nil, err
+	}
+	return rc.do(req)
+}
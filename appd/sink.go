@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+//receives the analytics records of a schema
+type EventSink interface {
+	PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error
+}
+
+//receives the metrics collected in one sync interval
+type MetricSink interface {
+	PostMetrics(metrics m.AppDMetricList) error
+}
+
+type EventSinkFactory func(cm *config.MutexConfigManager, logger *log.Logger) (EventSink, error)
+type MetricSinkFactory func(cm *config.MutexConfigManager, logger *log.Logger) (MetricSink, error)
+
+var lockSinkFactories = sync.RWMutex{}
+
+var eventSinkFactories = map[string]EventSinkFactory{
+	m.SINK_APPD: func(cm *config.MutexConfigManager, logger *log.Logger) (EventSink, error) {
+		return NewAppDEventSink(cm, logger), nil
+	},
+	m.SINK_LOG: func(cm *config.MutexConfigManager, logger *log.Logger) (EventSink, error) {
+		return NewLogSink(cm, logger), nil
+	},
+	m.SINK_OTLP: func(cm *config.MutexConfigManager, logger *log.Logger) (EventSink, error) {
+		sink, err := NewOtlpSink(cm, logger)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	},
+}
+
+var metricSinkFactories = map[string]MetricSinkFactory{
+	m.SINK_APPD: func(cm *config.MutexConfigManager, logger *log.Logger) (MetricSink, error) {
+		sink, err := NewAppDMetricSink(cm, logger)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	},
+	m.SINK_LOG: func(cm *config.MutexConfigManager, logger *log.Logger) (MetricSink, error) {
+		return NewLogSink(cm, logger), nil
+	},
+	m.SINK_PROMETHEUS: func(cm *config.MutexConfigManager, logger *log.Logger) (MetricSink, error) {
+		return NewPrometheusSink(cm, logger), nil
+	},
+	m.SINK_OTLP: func(cm *config.MutexConfigManager, logger *log.Logger) (MetricSink, error) {
+		sink, err := NewOtlpSink(cm, logger)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	},
+}
+
+//makes an event sink available to the EventSinks setting under the name
+func RegisterEventSink(name string, factory EventSinkFactory) {
+	lockSinkFactories.Lock()
+	defer lockSinkFactories.Unlock()
+	eventSinkFactories[name] = factory
+}
+
+//makes a metric sink available to the MetricSinks setting under the name
+func RegisterMetricSink(name string, factory MetricSinkFactory) {
+	lockSinkFactories.Lock()
+	defer lockSinkFactories.Unlock()
+	metricSinkFactories[name] = factory
+}
+
+//metric sinks that report the work of the agent as business transactions
+type TransactionSink interface {
+	StartBT(name string) BtHandle
+	StopBT(bth BtHandle)
+}
+
+/*
+ Fans the records and metrics out to all configured sinks.
+ The sinks are created once, at startup, independently of the controller client
+*/
+type SinkSet struct {
+	EventSinks  map[string]EventSink
+	MetricSinks map[string]MetricSink
+	logger      *log.Logger
+}
+
+func NewSinkSet(cm *config.MutexConfigManager, logger *log.Logger) (*SinkSet, error) {
+	bag := (*cm).Get()
+	ss := SinkSet{EventSinks: make(map[string]EventSink), MetricSinks: make(map[string]MetricSink), logger: logger}
+
+	lockSinkFactories.RLock()
+	defer lockSinkFactories.RUnlock()
+	for _, name := range bag.EventSinks {
+		factory, ok := eventSinkFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown event sink %s", name)
+		}
+		sink, err := factory(cm, logger)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to initialize event sink %s. %v", name, err)
+		}
+		ss.EventSinks[name] = sink
+	}
+	for _, name := range bag.MetricSinks {
+		factory, ok := metricSinkFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown metric sink %s", name)
+		}
+		sink, err := factory(cm, logger)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to initialize metric sink %s. %v", name, err)
+		}
+		ss.MetricSinks[name] = sink
+	}
+	logger.Infof("Event sinks: %s. Metric sinks: %s", strings.Join(bag.EventSinks, ", "), strings.Join(bag.MetricSinks, ", "))
+	return &ss, nil
+}
+
+//a failing sink does not prevent delivery to the others
+func (ss *SinkSet) PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error {
+	errs := []string{}
+	for name, sink := range ss.EventSinks {
+		if err := sink.PostEvents(schemaName, schemaDef, records); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Unable to publish %s records. %s", schemaName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+/*
+ True while the circuit breaker of the Events API is open and the batches cannot be spooled.
+ The workers drop the snapshots until the Events API recovers. With a spool, the snapshots are spooled instead
+*/
+func (ss *SinkSet) SnapshotsPaused() bool {
+	sink, ok := ss.EventSinks[m.SINK_APPD].(*AppDEventSink)
+	if !ok || sink.Spool != nil {
+		return false
+	}
+	bag := (*sink.ConfManager).Get()
+	return GetCircuitBreaker(bag.EventServiceUrl).IsOpen(bag)
+}
+
+//starts a business transaction in the appd metric sink. 0 if the sink is not configured or does not track transactions
+func (ss *SinkSet) StartBT(name string) BtHandle {
+	if ts, ok := ss.MetricSinks[m.SINK_APPD].(TransactionSink); ok {
+		return ts.StartBT(name)
+	}
+	return 0
+}
+
+func (ss *SinkSet) StopBT(bth BtHandle) {
+	if ts, ok := ss.MetricSinks[m.SINK_APPD].(TransactionSink); ok {
+		ts.StopBT(bth)
+	}
+}
+
+func (ss *SinkSet) PostMetrics(metrics m.AppDMetricList) error {
+	errs := []string{}
+	for name, sink := range ss.MetricSinks {
+		if err := sink.PostMetrics(metrics); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Unable to publish metrics. %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//AppDynamics Events API. Batches that fail while the Events API is unavailable are spooled and replayed
+type AppDEventSink struct {
+	ConfManager *config.MutexConfigManager
+	Spool       *EventSpool //nil if spooling is disabled
+	logger      *log.Logger
+}
+
+func NewAppDEventSink(cm *config.MutexConfigManager, logger *log.Logger) *AppDEventSink {
+	sink := AppDEventSink{ConfManager: cm, logger: logger}
+	if (*cm).Get().SpoolDir != "" {
+		spool, err := NewEventSpool(cm, logger)
+		if err != nil {
+			logger.Errorf("Failed batches of records will not be spooled. %v\n", err)
+		} else {
+			sink.Spool = spool
+		}
+	}
+	return &sink
+}
+
+func (s *AppDEventSink) PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error {
+	bag := (*s.ConfManager).Get()
+	rc := NewRestClient(bag, s.logger)
+	batches, err := SplitRecords(records, bag.EventAPILimit, bag.EventAPIMaxBytes)
+	if err != nil {
+		return fmt.Errorf("Problems when serializing array of %s records. %v", schemaName, err)
+	}
+	if s.Spool != nil {
+		s.Spool.RegisterSchema(schemaName, schemaDef)
+	}
+
+	state, err := rc.ResolveSchema(schemaName, schemaDef)
+	if err == ErrSchemaSkipped {
+		s.logger.Debugf("Publishing of %s is turned off. Dropped %d batches of records\n", schemaName, len(batches))
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("Issues when ensuring %s schema. %v", schemaName, err)
+		if s.Spool == nil {
+			return err
+		}
+		s.logger.Warnf("Unable to publish %s records. Spooling the batches. %v\n", schemaName, err)
+	}
+	errs := []string{}
+	for _, batch := range batches {
+		var errBatch error
+		if err != nil {
+			errBatch = s.Spool.Add(schemaName, batch.JSON(), len(batch.Records))
+		} else {
+			errBatch = s.postBatch(rc, state, batch)
+		}
+		if errBatch != nil {
+			errs = append(errs, errBatch.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//batches rejected as too large are split in halves and sent again
+func (s *AppDEventSink) postBatch(rc *RestClient, state *m.SchemaStatus, batch EventBatch) error {
+	schemaName := state.Name
+	//while batches are waiting in the spool, new batches are added to it to keep the order of the records
+	if s.Spool != nil && s.Spool.Pending() {
+		return s.Spool.Add(schemaName, batch.JSON(), len(batch.Records))
+	}
+	err := rc.PostSchemaEvents(state, batch)
+	if IsPayloadTooLarge(err) && len(batch.Records) > 1 {
+		first, second := batch.Split()
+		s.logger.Debugf("Batch of %d %s records (%d bytes) is too large. Splitting\n", len(batch.Records), schemaName, batch.Size)
+		errFirst := s.postBatch(rc, state, first)
+		errSecond := s.postBatch(rc, state, second)
+		if errFirst != nil {
+			return errFirst
+		}
+		return errSecond
+	}
+	if err != nil && s.Spool != nil && IsRetriable(err) {
+		s.logger.Warnf("Unable to publish %s records. Spooling the batch. %v\n", schemaName, err)
+		return s.Spool.Add(schemaName, batch.JSON(), len(batch.Records))
+	}
+	return err
+}
+
+//writes the records and metrics to the agent log
+type LogSink struct {
+	ConfManager *config.MutexConfigManager
+	logger      *log.Logger
+}
+
+func NewLogSink(cm *config.MutexConfigManager, logger *log.Logger) *LogSink {
+	return &LogSink{ConfManager: cm, logger: logger}
+}
+
+func (s *LogSink) PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("Problems when serializing array of %s records. %v", schemaName, err)
+	}
+	s.logger.Infof("Publishing %s records\n", schemaName)
+	s.logger.Debugf("%s: %s\n", schemaName, string(data))
+	return nil
+}
+
+func (s *LogSink) PostMetrics(metrics m.AppDMetricList) error {
+	tierName := (*s.ConfManager).Get().TierName
+	s.logger.Infof("Publishing %d metrics\n", len(metrics.Items))
+	for _, metric := range metrics.Items {
+		s.logger.Debugf("%s = %d\n", fmt.Sprintf(metric.MetricPath, tierName), metric.MetricValue)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockSpool = sync.Mutex{}
+
+var errSpoolCorrupted = errors.New("Spooled batch is not a JSON array")
+
+const (
+	spoolFileExt    string        = ".batch"
+	spoolTempExt    string        = ".tmp"
+	spoolMinBackoff time.Duration = time.Second
+	spoolMaxBackoff time.Duration = 5 * time.Minute
+)
+
+//batch of records waiting in the spool. The file name carries the creation time, sequence, number of records and schema
+type spoolEntry struct {
+	File       string
+	SchemaName string
+	Records    int
+	Size       int64
+	Created    time.Time
+}
+
+func spoolFileName(created time.Time, seq int, records int, schemaName string) string {
+	return fmt.Sprintf("%020d-%06d-%d-%s%s", created.UnixNano(), seq, records, schemaName, spoolFileExt)
+}
+
+func parseSpoolFileName(name string) (spoolEntry, bool) {
+	entry := spoolEntry{File: name}
+	parts := strings.SplitN(strings.TrimSuffix(name, spoolFileExt), "-", 4)
+	if len(parts) != 4 || !strings.HasSuffix(name, spoolFileExt) {
+		return entry, false
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return entry, false
+	}
+	records, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return entry, false
+	}
+	entry.Created = time.Unix(0, nanos)
+	entry.Records = records
+	entry.SchemaName = parts[3]
+	return entry, true
+}
+
+/*
+ Write-ahead spool of the batches that could not be published to the Events API.
+ The batches are stored as files in SpoolDir, bounded by SpoolMaxSize and SpoolMaxAge,
+ and replayed in order with exponential backoff once the Events API recovers.
+ Batches that survive a restart of the agent are replayed as well
+*/
+type EventSpool struct {
+	ConfManager    *config.MutexConfigManager
+	Dir            string
+	Entries        []spoolEntry
+	Size           int64
+	DroppedBatches int64
+	DroppedRecords int64
+	LastError      string
+	Schemas        map[string]m.AppDSchemaInterface //schema definitions for the replay
+	seq            int
+	notify         chan struct{}
+	logger         *log.Logger
+}
+
+func NewEventSpool(cm *config.MutexConfigManager, logger *log.Logger) (*EventSpool, error) {
+	dir := (*cm).Get().SpoolDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create spool directory %s. %v", dir, err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read spool directory %s. %v", dir, err)
+	}
+	s := EventSpool{ConfManager: cm, Dir: dir, Entries: []spoolEntry{}, Schemas: make(map[string]m.AppDSchemaInterface), notify: make(chan struct{}, 1), logger: logger}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		//partial writes of a previous instance
+		if strings.HasSuffix(f.Name(), spoolTempExt) {
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		entry, ok := parseSpoolFileName(f.Name())
+		if !ok {
+			continue
+		}
+		entry.Size = f.Size()
+		s.Entries = append(s.Entries, entry)
+		s.Size += entry.Size
+	}
+	sort.Slice(s.Entries, func(i, j int) bool { return s.Entries[i].File < s.Entries[j].File })
+	if len(s.Entries) > 0 {
+		logger.Infof("Found %d spooled batches in %s\n", len(s.Entries), dir)
+	}
+	return &s, nil
+}
+
+//replays the spooled batches until the agent stops
+func (s *EventSpool) Start(stopCh <-chan struct{}) {
+	go s.run(stopCh)
+}
+
+func (s *EventSpool) maxSize(bag *m.AppDBag) int64 {
+	return int64(bag.SpoolMaxSize) * 1024 * 1024
+}
+
+//true if batches are waiting. New batches are then added to the spool to keep the order of the records
+func (s *EventSpool) Pending() bool {
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+	return len(s.Entries) > 0
+}
+
+func (s *EventSpool) RegisterSchema(schemaName string, schemaDef m.AppDSchemaInterface) {
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+	s.Schemas[schemaName] = schemaDef
+}
+
+func (s *EventSpool) Add(schemaName string, data []byte, records int) error {
+	bag := (*s.ConfManager).Get()
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+
+	size := int64(len(data))
+	if size > s.maxSize(bag) {
+		s.dropBatch(records, fmt.Sprintf("batch of %d bytes exceeds SpoolMaxSize", size))
+		return fmt.Errorf("Batch of %s records is too large for the spool", schemaName)
+	}
+	now := time.Now()
+	s.seq = (s.seq + 1) % 1000000
+	entry := spoolEntry{File: spoolFileName(now, s.seq, records, schemaName), SchemaName: schemaName, Records: records, Size: size, Created: now}
+	path := filepath.Join(s.Dir, entry.File)
+	if err := ioutil.WriteFile(path+spoolTempExt, data, 0644); err != nil {
+		s.dropBatch(records, err.Error())
+		return fmt.Errorf("Unable to spool %s records. %v", schemaName, err)
+	}
+	if err := os.Rename(path+spoolTempExt, path); err != nil {
+		os.Remove(path + spoolTempExt)
+		s.dropBatch(records, err.Error())
+		return fmt.Errorf("Unable to spool %s records. %v", schemaName, err)
+	}
+	s.Entries = append(s.Entries, entry)
+	s.Size += size
+
+	//the oldest batches make room for the new ones
+	for s.Size > s.maxSize(bag) && len(s.Entries) > 1 {
+		s.removeFirst(true, "SpoolMaxSize exceeded")
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *EventSpool) dropBatch(records int, reason string) {
+	s.DroppedBatches++
+	s.DroppedRecords += int64(records)
+	s.logger.Warnf("Dropped a batch of %d records. %s\n", records, reason)
+}
+
+//must be called under lockSpool
+func (s *EventSpool) removeFirst(dropped bool, reason string) {
+	entry := s.Entries[0]
+	s.Entries = s.Entries[1:]
+	s.Size -= entry.Size
+	if err := os.Remove(filepath.Join(s.Dir, entry.File)); err != nil && !os.IsNotExist(err) {
+		s.logger.Errorf("Unable to delete spooled batch %s. %v\n", entry.File, err)
+	}
+	if dropped {
+		s.dropBatch(entry.Records, fmt.Sprintf("%s: %s", entry.SchemaName, reason))
+	}
+}
+
+//drops the batches older than SpoolMaxAge and returns the oldest remaining batch
+func (s *EventSpool) next(now time.Time) (spoolEntry, bool) {
+	bag := (*s.ConfManager).Get()
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+	maxAge := time.Duration(bag.SpoolMaxAge) * time.Second
+	for len(s.Entries) > 0 && bag.SpoolMaxAge > 0 && now.Sub(s.Entries[0].Created) > maxAge {
+		s.removeFirst(true, "SpoolMaxAge exceeded")
+	}
+	if len(s.Entries) == 0 {
+		return spoolEntry{}, false
+	}
+	return s.Entries[0], true
+}
+
+//removes the batch, unless it has been evicted in the meantime
+func (s *EventSpool) complete(entry spoolEntry, dropped bool, reason string) {
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+	if len(s.Entries) > 0 && s.Entries[0].File == entry.File {
+		s.removeFirst(dropped, reason)
+	}
+}
+
+func (s *EventSpool) publish(entry spoolEntry) error {
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, entry.File))
+	if err != nil {
+		return err
+	}
+	bag := (*s.ConfManager).Get()
+	rc := NewRestClient(bag, s.logger)
+	lockSpool.Lock()
+	schemaDef, known := s.Schemas[entry.SchemaName]
+	lockSpool.Unlock()
+	//without the definition, the batch goes to the schema as is
+	state := &m.SchemaStatus{Name: entry.SchemaName, Target: entry.SchemaName}
+	if known {
+		state, err = rc.ResolveSchema(entry.SchemaName, schemaDef)
+		if err == ErrSchemaSkipped {
+			s.logger.Infof("Publishing of %s is turned off. Dropped the spooled batch of %d records\n", entry.SchemaName, entry.Records)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Issues when ensuring %s schema. %v", entry.SchemaName, err)
+		}
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		s.logger.Errorf("Spooled batch %s is corrupted. %v\n", entry.File, err)
+		return errSpoolCorrupted
+	}
+	batch := EventBatch{}
+	for _, record := range records {
+		batch.add(record)
+	}
+	return rc.PostSchemaEvents(state, batch)
+}
+
+func (s *EventSpool) run(stopCh <-chan struct{}) {
+	backoff := spoolMinBackoff
+	var wait time.Duration = 0
+	for {
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-stopCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+		}
+		entry, ok := s.next(time.Now())
+		if !ok {
+			wait = 0
+			select {
+			case <-stopCh:
+				return
+			case <-s.notify:
+			}
+			continue
+		}
+		err := s.publish(entry)
+		if os.IsNotExist(err) {
+			s.complete(entry, true, "the file of the batch is missing")
+			wait = 0
+			continue
+		}
+		if err == errSpoolCorrupted {
+			s.complete(entry, true, "the file of the batch is corrupted")
+			wait = 0
+			continue
+		}
+		if err == nil {
+			s.complete(entry, false, "")
+			lockSpool.Lock()
+			s.LastError = ""
+			lockSpool.Unlock()
+			if backoff > spoolMinBackoff {
+				s.logger.Infof("Events API is available. Replaying spooled batches\n")
+			}
+			backoff = spoolMinBackoff
+			wait = 0
+			continue
+		}
+		if !IsRetriable(err) {
+			s.logger.Errorf("Spooled batch of %s records was rejected. %v\n", entry.SchemaName, err)
+			s.complete(entry, true, "rejected by the Events API")
+			wait = 0
+			continue
+		}
+
+		lockSpool.Lock()
+		s.LastError = err.Error()
+		lockSpool.Unlock()
+		s.logger.Warnf("Unable to replay spooled batches. Next attempt in %v. %v\n", backoff, err)
+		wait = backoff
+		maxBackoff := spoolMaxBackoff
+		if bag := (*s.ConfManager).Get(); bag.SpoolMaxBackoff > 0 {
+			maxBackoff = time.Duration(bag.SpoolMaxBackoff) * time.Second
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *EventSpool) Status() *m.SpoolStatus {
+	lockSpool.Lock()
+	defer lockSpool.Unlock()
+	status := m.SpoolStatus{Batches: len(s.Entries), Bytes: s.Size, DroppedBatches: s.DroppedBatches, DroppedRecords: s.DroppedRecords, LastError: s.LastError}
+	for _, entry := range s.Entries {
+		status.Records += entry.Records
+	}
+	if len(s.Entries) > 0 {
+		status.Oldest = s.Entries[0].Created
+	}
+	return &status
+}
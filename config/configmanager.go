@@ -58,6 +58,7 @@ func NewMutexConfigManager(env *m.AppDBag, l *log.Logger) *MutexConfigManager {
 func (self *MutexConfigManager) setDefaults(env *m.AppDBag) {
 	//set all secrets passed via env vars
 	self.Conf.RestAPICred = env.RestAPICred
+	self.Conf.RestAPIClient = env.RestAPIClient
 	self.Conf.AccessKey = env.AccessKey
 	self.Conf.EventKey = env.EventKey
 	self.Conf.AgentNamespace = env.AgentNamespace
@@ -80,14 +81,6 @@ func (self *MutexConfigManager) onConfigUpdate() {
 
 func (self *MutexConfigManager) Set(conf *m.AppDBag) {
 	self.Mutex.Lock()
-	if self.Conf != nil && self.Conf.SchemaUpdateCache != nil {
-		conf.SchemaUpdateCache = self.Conf.SchemaUpdateCache
-	}
-
-	if self.Conf != nil && self.Conf.SchemaSkipCache != nil {
-		conf.SchemaSkipCache = self.Conf.SchemaSkipCache
-	}
-
 	self.Conf = conf
 
 	self.validate()
@@ -103,11 +96,44 @@ func (self *MutexConfigManager) validate() {
 	if self.Conf.InstrumentMatchString == nil {
 		self.Conf.InstrumentMatchString = []string{}
 	}
-	if self.Conf.SchemaUpdateCache == nil {
-		self.Conf.SchemaUpdateCache = []string{}
+	if self.Conf.CustomResources == nil {
+		self.Conf.CustomResources = []m.CustomResourceConfig{}
+	}
+	if self.Conf.LogParsers == nil {
+		self.Conf.LogParsers = []m.LogParserConfig{}
+	}
+	if self.Conf.LogCustomFields == nil {
+		self.Conf.LogCustomFields = []m.LogCustomField{}
+	}
+	if self.Conf.EventRules == nil {
+		self.Conf.EventRules = []m.EventRule{}
+	}
+	if self.Conf.EventSinks == nil {
+		self.Conf.EventSinks = []string{m.SINK_APPD}
+	}
+	if self.Conf.MetricSinks == nil {
+		self.Conf.MetricSinks = []string{m.SINK_APPD}
+	}
+	if self.Conf.MetricTransport == "" {
+		self.Conf.MetricTransport = m.METRIC_TRANSPORT_SDK
+	}
+	if self.Conf.MetricBudgets == nil {
+		self.Conf.MetricBudgets = map[string]int{}
+	}
+	if self.Conf.MetricAllowPatterns == nil {
+		self.Conf.MetricAllowPatterns = []string{}
+	}
+	if self.Conf.MetricDenyPatterns == nil {
+		self.Conf.MetricDenyPatterns = []string{}
+	}
+	if self.Conf.MetricRollup == "" {
+		self.Conf.MetricRollup = m.METRIC_ROLLUP_FULL
+	}
+	if self.Conf.OtlpHeaders == nil {
+		self.Conf.OtlpHeaders = map[string]string{}
 	}
-	if self.Conf.SchemaSkipCache == nil {
-		self.Conf.SchemaSkipCache = []string{}
+	if self.Conf.EventStormReasonThresholds == nil {
+		self.Conf.EventStormReasonThresholds = map[string]int{}
 	}
 	//update log level
 	l, errLevel := log.ParseLevel(self.Conf.LogLevel)
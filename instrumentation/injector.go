@@ -45,11 +45,12 @@ type AgentInjector struct {
 	K8sConfig      *rest.Config
 	Bag            *m.AppDBag
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	Logger         *log.Logger
 }
 
-func NewAgentInjector(client *kubernetes.Clientset, config *rest.Config, bag *m.AppDBag, appdController *app.ControllerClient, l *log.Logger) AgentInjector {
-	return AgentInjector{ClientSet: client, K8sConfig: config, Bag: bag, AppdController: appdController, Logger: l}
+func NewAgentInjector(client *kubernetes.Clientset, config *rest.Config, bag *m.AppDBag, appdController *app.ControllerClient, sinks *app.SinkSet, l *log.Logger) AgentInjector {
+	return AgentInjector{ClientSet: client, K8sConfig: config, Bag: bag, AppdController: appdController, Sinks: sinks, Logger: l}
 }
 
 func AnalyticsAgentExists(podSpec *v1.PodSpec, bag *m.AppDBag) bool {
@@ -420,12 +421,12 @@ func (ai AgentInjector) instrumentContainer(appName string, tierName string, con
 }
 
 func (ai AgentInjector) copyArtifactsSync(exec *Executor, podObj *v1.Pod, containerName string) error {
-	bth := ai.AppdController.StartBT("CopyArtifacts")
+	bth := ai.Sinks.StartBT("CopyArtifacts")
 	err := ai.copyFileSync(exec, "assets/tools.jar", ai.Bag.AgentMountPath, podObj, containerName)
 	if err == nil {
 		err = ai.copyFileSync(exec, "assets/AppServerAgent/", ai.Bag.AgentMountPath, podObj, containerName)
 	}
-	ai.AppdController.StopBT(bth)
+	ai.Sinks.StopBT(bth)
 	return err
 }
 
@@ -490,7 +491,7 @@ func (ai AgentInjector) instrument(podObj *v1.Pod, pid int, appName string, tier
 	if nodePrefix == "" {
 		nodePrefix = tierName
 	}
-	bth := ai.AppdController.StartBT("InstrumentJavaAttach")
+	bth := ai.Sinks.StartBT("InstrumentJavaAttach")
 	cmd := fmt.Sprintf("java -Xbootclasspath/a:%s/tools.jar -jar %s/javaagent.jar %d appdynamics.controller.hostName=%s,appdynamics.controller.port=%d,appdynamics.controller.ssl.enabled=%t,appdynamics.agent.accountName=%s,appdynamics.agent.accountAccessKey=%s,appdynamics.agent.applicationName=%s,appdynamics.agent.tierName=%s,appdynamics.agent.reuse.nodeName=true,appdynamics.agent.reuse.nodeName.prefix=%s",
 		jarPath, jarPath, pid, ai.Bag.ControllerUrl, ai.Bag.ControllerPort, ai.Bag.SSLEnabled, ai.Bag.Account, ai.Bag.AccessKey, appName, tierName, nodePrefix)
 
@@ -514,7 +515,7 @@ func (ai AgentInjector) instrument(podObj *v1.Pod, pid int, appName string, tier
 	} else {
 		return fmt.Errorf("Unable to attach Java agent. Error code = %d. Output: %s, Error: %v\n", code, output, err)
 	}
-	ai.AppdController.StopBT(bth)
+	ai.Sinks.StopBT(bth)
 	return nil
 }
 
@@ -51,6 +51,7 @@ func buildParams() Flags {
 	flag.StringVar(&params.Bag.AccessKey, "access-key", getAccessKey(), "AppD Controller Access Key")
 	flag.StringVar(&params.Bag.EventKey, "event-key", getEventKey(), "Event API Key")
 	flag.StringVar(&params.Bag.RestAPICred, "rest-api-creds", getRestAPICred(), "Rest API Credentials")
+	flag.StringVar(&params.Bag.RestAPIClient, "rest-api-client", getRestAPIClient(), "Rest API Client (OAuth)")
 	flag.BoolVar(&params.Bag.SSLEnabled, "use-ssl", getSslEnabled(), "Controller uses SSL connection")
 	flag.StringVar(&params.Bag.PodSchemaName, "schema-pods", bagDefaults.PodSchemaName, "Pod schema name")
 	flag.StringVar(&params.Bag.NodeSchemaName, "schema-nodes", bagDefaults.NodeSchemaName, "Node schema name")
@@ -282,6 +283,10 @@ func getRestAPICred() string {
 	return os.Getenv("APPDYNAMICS_REST_API_CREDENTIALS")
 }
 
+func getRestAPIClient() string {
+	return os.Getenv("APPDYNAMICS_REST_API_CLIENT")
+}
+
 func getEventServiceURL() string {
 	return os.Getenv("APPDYNAMICS_EVENTS_API_URL")
 }
@@ -4,6 +4,24 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
+)
+
+//schema name that turns off the publishing of the records and deletes the schema
+const SCHEMA_SKIP string = "skip"
+
+//built-in telemetry sinks
+const (
+	SINK_APPD       string = "appd"
+	SINK_LOG        string = "log" //writes the records and metrics to the agent log
+	SINK_PROMETHEUS string = "prometheus"
+	SINK_OTLP       string = "otlp"
+)
+
+//transports of the metrics of the appd sink
+const (
+	METRIC_TRANSPORT_SDK  string = "sdk"  //AppDynamics C++ SDK, requires cgo
+	METRIC_TRANSPORT_HTTP string = "http" //HTTP listener of the Machine Agent
 )
 
 type AppDBag struct {
@@ -26,7 +44,10 @@ type AppDBag struct {
 	EventKey                    string
 	EventServiceUrl             string
 	RestAPICred                 string
+	RestAPIClient               string //API client in the <clientName>@<account>:<secret> format. Replaces RestAPICred
 	EventAPILimit               int
+	EventAPIMaxBytes            int //bytes of a batch. 0 - no limit
+	EventAPICompression         bool
 	PodSchemaName               string
 	NodeSchemaName              string
 	DeploySchemaName            string
@@ -39,6 +60,13 @@ type AppDBag struct {
 	RqSchemaName                string
 	JobSchemaName               string
 	LogSchemaName               string
+	RolloutSchemaName           string
+	ChangeSchemaName            string
+	PdbSchemaName               string
+	CrashSchemaName             string
+	ImagePullSchemaName         string
+	SchemaTransitionPeriod      int    //hours during which records are also written to the previous version of a schema
+	SchemaStateFile             string //starts of the transition periods. "" - not kept across restarts
 	DashboardTemplatePath       string
 	DashboardSuffix             string
 	DashboardDelayMin           int
@@ -88,14 +116,53 @@ type AppDBag struct {
 	BiqRequestMem               string
 	BiqRequestCpu               string
 	LogLines                    int //0 - no logging
+	CrashForensics              bool
+	CrashLogLines               int //lines of the previous container instance in crash records
+	LogTailAnnotation           string
+	LogTailLinesPerSecond       int    //per pod. 0 - no limit
+	LogTailBytesPerMinute       int    //per pod. 0 - no limit
+	LogTailContinuationPattern  string //lines matching the pattern are appended to the previous entry
+	LogParsers                  []LogParserConfig
+	LogParserAnnotation         string
+	LogCustomFields             []LogCustomField
 	PodEventNumber              int
 	RemoteBiqProtocol           string
 	RemoteBiqHost               string
 	RemoteBiqPort               int
-	SchemaUpdateCache           []string
-	SchemaSkipCache             []string
 	LogLevel                    string
 	OverconsumptionThreshold    int //percent
+	EventDedupWindow            int //seconds. 0 - no deduplication
+	EventStormThreshold         int //occurrences within the dedup window. 0 - no storm detection
+	EventStormReasonThresholds  map[string]int
+	CustomResources             []CustomResourceConfig
+	EventRules                  []EventRule
+	EventRulesConfigMap         string //configMap in the agent namespace with additional event rules
+	EventSinks                  []string
+	MetricSinks                 []string
+	MetricTransport             string
+	MetricBudgets               map[string]int //metric family -> max series per sync. 0 - no limit
+	MetricAllowPatterns         []string       //regular expressions of the reported metric paths. Empty - all
+	MetricDenyPatterns          []string
+	MetricRollup                string
+	MachineAgentUrl             string //HTTP listener of the Machine Agent, e.g. http://localhost:8293
+	OtlpEndpoint                string //OTLP/HTTP receiver, e.g. http://otel-collector:4318
+	OtlpHeaders                 map[string]string
+	OtlpCACert                  string
+	OtlpClientCert              string
+	OtlpClientKey               string
+	OtlpInsecureSkipVerify      bool
+	OtlpTimeout                 int    //seconds
+	SpoolDir                    string //directory of the batches that could not be published. "" - no spooling
+	SpoolMaxSize                int    //MB
+	SpoolMaxAge                 int    //seconds. 0 - no limit
+	SpoolMaxBackoff             int    //seconds
+	HttpTimeout                 int    //seconds
+	HttpMaxRetries              int    //-1 - no retries
+	HttpMaxBackoff              int    //seconds
+	HttpClientCert              string
+	HttpClientKey               string
+	CircuitBreakerThreshold     int //consecutive failures. -1 - no circuit breaker
+	CircuitBreakerCooldown      int //seconds
 	ControllerVer1              int
 	ControllerVer2              int
 	ControllerVer3              int
@@ -122,11 +189,41 @@ type AgentStatus struct {
 	AnalyticsAgentImage        string
 	AppDJavaAttachImage        string
 	AppDDotNetAttachImage      string
+	Spool                      *SpoolStatus
+	Endpoints                  map[string]string //endpoint -> state of the circuit breaker
+}
+
+//expected and live definition of an analytics schema
+type SchemaStatus struct {
+	Name          string //configured schema name
+	Version       int
+	Target        string //schema the records are written to
+	Previous      string //previous version that receives the records during the transition period
+	TransitionEnd *time.Time
+	Expected      map[string]string //field -> type
+	Live          map[string]string
+	Added         []string          //fields added to the live schema by the agent
+	Missing       []string          //fields of the expected schema that the live schema does not have
+	Extra         []string          //fields of the live schema that are no longer expected
+	Conflicts     map[string]string //field -> "live type != expected type" of the incompatible version
+	Checked       time.Time
+	Error         string
+}
+
+//state of the spool of the batches that could not be published to the Events API
+type SpoolStatus struct {
+	Batches        int
+	Records        int
+	Bytes          int64
+	Oldest         time.Time
+	DroppedBatches int64
+	DroppedRecords int64
+	LastError      string
 }
 
 func IsUpdatable(fieldName string) bool {
 	arr := []string{"AgentNamespace", "AppName", "TierName", "NodeName", "AppID", "TierID", "NodeID", "Account", "GlobalAccount", "AccessKey", "ControllerUrl",
-		"ControllerPort", "RestAPIUrl", "SSLEnabled", "SystemSSLCert", "AgentSSLCert", "EventKey", "EventServiceUrl", "RestAPICred"}
+		"ControllerPort", "RestAPIUrl", "SSLEnabled", "SystemSSLCert", "AgentSSLCert", "EventKey", "EventServiceUrl", "RestAPICred", "RestAPIClient"}
 	for _, s := range arr {
 		if s == fieldName {
 			return false
@@ -136,34 +233,14 @@ func IsUpdatable(fieldName string) bool {
 }
 
 func UpdateField(fieldName string, current *reflect.Value, updated *reflect.Value) {
-	arr := []string{"PodSchemaName",
-		"NodeSchemaName",
-		"DeploySchemaName",
-		"RSSchemaName",
-		"DaemonSchemaName",
-		"EventSchemaName",
-		"ContainerSchemaName",
-		"EpSchemaName",
-		"NsSchemaName",
-		"RqSchemaName",
-		"JobSchemaName",
-		"LogSchemaName"}
-
-	found := false
-	for _, s := range arr {
-		if s == fieldName {
-			found = true
-			break
+	//the previous name of a skipped schema is kept for the deletion: <previous name>^skip
+	if strings.HasSuffix(fieldName, "SchemaName") && strings.EqualFold(updated.String(), SCHEMA_SKIP) {
+		if !strings.Contains(current.String(), "^") && !strings.EqualFold(current.String(), SCHEMA_SKIP) {
+			current.SetString(fmt.Sprintf("%s^%s", current.String(), updated.String()))
 		}
+		return
 	}
-
-	if found && strings.ToLower(updated.String()) == "skip" {
-		newVal := fmt.Sprintf("%s^%s", current.String(), updated.String())
-		current.SetString(newVal)
-	} else {
-		current.Set(*updated)
-	}
-
+	current.Set(*updated)
 }
 
 /// current controller version is -1 = older, 1 - newer or equal
@@ -220,6 +297,43 @@ func (self *AppDBag) EnsureDefaults() {
 	if self.DaemonSchemaName == "" {
 		self.DaemonSchemaName = bag.DaemonSchemaName
 	}
+	if self.RolloutSchemaName == "" {
+		self.RolloutSchemaName = bag.RolloutSchemaName
+	}
+	if self.ChangeSchemaName == "" {
+		self.ChangeSchemaName = bag.ChangeSchemaName
+	}
+	if self.PdbSchemaName == "" {
+		self.PdbSchemaName = bag.PdbSchemaName
+	}
+	if self.CrashSchemaName == "" {
+		self.CrashSchemaName = bag.CrashSchemaName
+	}
+	if self.ImagePullSchemaName == "" {
+		self.ImagePullSchemaName = bag.ImagePullSchemaName
+	}
+	//omitted limits of the spool and the http client. Retries and the circuit breaker are disabled with negative values
+	if self.SpoolMaxSize <= 0 {
+		self.SpoolMaxSize = bag.SpoolMaxSize
+	}
+	if self.SpoolMaxBackoff <= 0 {
+		self.SpoolMaxBackoff = bag.SpoolMaxBackoff
+	}
+	if self.HttpTimeout <= 0 {
+		self.HttpTimeout = bag.HttpTimeout
+	}
+	if self.HttpMaxRetries == 0 {
+		self.HttpMaxRetries = bag.HttpMaxRetries
+	}
+	if self.HttpMaxBackoff <= 0 {
+		self.HttpMaxBackoff = bag.HttpMaxBackoff
+	}
+	if self.CircuitBreakerThreshold == 0 {
+		self.CircuitBreakerThreshold = bag.CircuitBreakerThreshold
+	}
+	if self.CircuitBreakerCooldown <= 0 {
+		self.CircuitBreakerCooldown = bag.CircuitBreakerCooldown
+	}
 }
 
 func GetDefaultProperties() *AppDBag {
@@ -231,6 +345,8 @@ func GetDefaultProperties() *AppDBag {
 		SystemSSLCert:               "/opt/appdynamics/ssl/systemSSL.crt",
 		AgentSSLCert:                "",
 		EventAPILimit:               100,
+		EventAPIMaxBytes:            1000000,
+		EventAPICompression:         true,
 		MetricsSyncInterval:         60,
 		SnapshotSyncInterval:        15,
 		PodSchemaName:               "kube_pod_snapshots",
@@ -245,6 +361,13 @@ func GetDefaultProperties() *AppDBag {
 		DeploySchemaName:            "kube_deploy_snapshots",
 		RSSchemaName:                "kube_rs_snapshots",
 		DaemonSchemaName:            "kube_daemon_snapshots",
+		RolloutSchemaName:           "kube_rollouts",
+		ChangeSchemaName:            "kube_changes",
+		PdbSchemaName:               "kube_pdb_snapshots",
+		CrashSchemaName:             "kube_crash_forensics",
+		ImagePullSchemaName:         "kube_image_pulls",
+		SchemaTransitionPeriod:      168,
+		SchemaStateFile:             "/opt/appdynamics/spool/schemas.json",
 		DashboardTemplatePath:       "/opt/appdynamics/templates/cluster-template.json",
 		DashboardSuffix:             "SUMMARY",
 		DashboardDelayMin:           2,
@@ -287,9 +410,50 @@ func GetDefaultProperties() *AppDBag {
 		ProxyUser:                   "",
 		ProxyPass:                   "",
 		LogLines:                    0, //0 - no logging}
+		CrashForensics:              true,
+		CrashLogLines:               100,
+		LogTailAnnotation:           "appdynamics.com/tail-logs",
+		LogTailLinesPerSecond:       100,
+		LogTailBytesPerMinute:       1048576,
+		LogTailContinuationPattern:  `^(\s|Caused by:|\.\.\. \d+ more)`,
+		LogParsers:                  []LogParserConfig{},
+		LogParserAnnotation:         "appdynamics.com/log-format",
+		LogCustomFields:             []LogCustomField{},
 		PodEventNumber:              1,
 		LogLevel:                    "info",
 		OverconsumptionThreshold:    80,
+		EventDedupWindow:            60,
+		EventStormThreshold:         50,
+		EventStormReasonThresholds:  map[string]int{},
+		CustomResources:             []CustomResourceConfig{},
+		EventRules:                  []EventRule{},
+		EventRulesConfigMap:         "",
+		EventSinks:                  []string{SINK_APPD},
+		MetricSinks:                 []string{SINK_APPD},
+		MetricTransport:             METRIC_TRANSPORT_SDK,
+		MetricBudgets:               map[string]int{},
+		MetricAllowPatterns:         []string{},
+		MetricDenyPatterns:          []string{},
+		MetricRollup:                METRIC_ROLLUP_FULL,
+		MachineAgentUrl:             "http://localhost:8293",
+		OtlpEndpoint:                "",
+		OtlpHeaders:                 map[string]string{},
+		OtlpCACert:                  "",
+		OtlpClientCert:              "",
+		OtlpClientKey:               "",
+		OtlpInsecureSkipVerify:      false,
+		OtlpTimeout:                 10,
+		SpoolDir:                    "/opt/appdynamics/spool",
+		SpoolMaxSize:                100,
+		SpoolMaxAge:                 86400,
+		SpoolMaxBackoff:             300,
+		HttpTimeout:                 30,
+		HttpMaxRetries:              3,
+		HttpMaxBackoff:              30,
+		HttpClientCert:              "",
+		HttpClientKey:               "",
+		CircuitBreakerThreshold:     5,
+		CircuitBreakerCooldown:      60,
 	}
 
 	return &bag
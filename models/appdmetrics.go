@@ -1,7 +1,6 @@
 package models
 
 import (
-	appd "appdynamics"
 	"fmt"
 )
 
@@ -9,12 +8,43 @@ type AppDSchemaInterface interface {
 	Unwrap() *map[string]interface{}
 }
 
+/*
+ Version of the schema definition. Definitions without the method are version 1. Fields can be added without a new version.
+ The version must be increased when the type of a field changes. The records are then written to
+ <schema name>_v<version>, and to the previous version during the transition period
+*/
+type AppDVersionedSchema interface {
+	Version() int
+}
+
 type AppDMetricInterface interface {
 	ShouldExcludeField(fieldName string) bool
 	GetPath() string
 	Unwrap() *map[string]interface{}
 }
 
+//metrics with fields that count the occurrences within the metrics interval rather than the current state
+type AppDDeltaMetrics interface {
+	IsDeltaField(fieldName string) bool
+}
+
+//time rollup of a metric, in the order of the AppDynamics SDK
+type RollupType int
+
+const (
+	TIMEROLLUP_TYPE_AVERAGE RollupType = iota + 1
+	TIMEROLLUP_TYPE_SUM
+	TIMEROLLUP_TYPE_CURRENT
+)
+
+//cluster rollup of a metric, in the order of the AppDynamics SDK
+type ClusterRollupType int
+
+const (
+	CLUSTERROLLUP_TYPE_INDIVIDUAL ClusterRollupType = iota + 1
+	CLUSTERROLLUP_TYPE_COLLECTIVE
+)
+
 const RootPath string = "Server|Component:%s|Custom Metrics|Cluster Stats|"
 const ALL string = "all"
 const METRIC_SEPARATOR string = "|"
@@ -29,6 +59,27 @@ const METRIC_PATH_EVENTS string = "Events"
 const METRIC_PATH_SERVICES_EP string = "Endpoints"
 const METRIC_PATH_RQSPEC string = "QuotaSpecs"
 const METRIC_PATH_RQUSED string = "QuotaUsed"
+const METRIC_PATH_CUSTOM_RESOURCES string = "CustomResources"
+const METRIC_PATH_REGISTRIES string = "Registries"
+const METRIC_PATH_CARDINALITY string = "Cardinality"
+
+//families of the pod metrics, the units of the cardinality budgets
+const (
+	METRIC_FAMILY_CLUSTER   string = "cluster" //cluster, namespace and node summaries
+	METRIC_FAMILY_TIER      string = "tier"
+	METRIC_FAMILY_SERVICE   string = "service"
+	METRIC_FAMILY_ENDPOINT  string = "endpoint"
+	METRIC_FAMILY_CONTAINER string = "container"
+	METRIC_FAMILY_INSTANCE  string = "instance"
+	METRIC_FAMILY_PORT      string = "port"
+)
+
+//levels of detail of the container, instance and port metrics
+const (
+	METRIC_ROLLUP_FULL      string = "full"      //all tiers
+	METRIC_ROLLUP_DASHBOARD string = "dashboard" //tiers in DeploysToDashboard only, tier-level metrics for the rest
+	METRIC_ROLLUP_TIER      string = "tier"      //tier-level metrics only
+)
 
 type AppDMetric struct {
 	MetricName              string
@@ -37,8 +88,8 @@ type AppDMetric struct {
 	MetricAlias             string
 	MetricMultiplier        float64
 	MetricAggregationType   string
-	MetricTimeRollUpType    appd.RollupType
-	MetricClusterRollUpType appd.ClusterRollupType
+	MetricTimeRollUpType    RollupType
+	MetricClusterRollUpType ClusterRollupType
 	MetricDelta             bool
 }
 
@@ -48,7 +99,7 @@ type AppDMetricList struct {
 
 func NewAppDMetric(name string, val int64, path string) AppDMetric {
 	p := fmt.Sprintf("%s%s", path, name)
-	return AppDMetric{MetricName: name, MetricValue: val, MetricPath: p, MetricAggregationType: "OBSERVATION", MetricTimeRollUpType: appd.APPD_TIMEROLLUP_TYPE_CURRENT, MetricClusterRollUpType: appd.APPD_CLUSTERROLLUP_TYPE_INDIVIDUAL}
+	return AppDMetric{MetricName: name, MetricValue: val, MetricPath: p, MetricAggregationType: "OBSERVATION", MetricTimeRollUpType: TIMEROLLUP_TYPE_CURRENT, MetricClusterRollUpType: CLUSTERROLLUP_TYPE_INDIVIDUAL}
 }
 
 func (am AppDMetric) ToString() string {
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	CHANGE_KIND_DEPLOYMENT  string = "Deployment"
+	CHANGE_KIND_DAEMONSET   string = "DaemonSet"
+	CHANGE_KIND_STATEFULSET string = "StatefulSet"
+	CHANGE_KIND_CONFIGMAP   string = "ConfigMap"
+	CHANGE_KIND_SECRET      string = "Secret"
+)
+
+type FieldChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+type ChangeSchemaDefWrapper struct {
+	Schema ChangeSchemaDef `json:"schema"`
+}
+
+func (sd ChangeSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type ChangeSchemaDef struct {
+	ClusterName     string `json:"clusterName"`
+	Namespace       string `json:"namespace"`
+	ObjectKind      string `json:"objectKind"`
+	ObjectName      string `json:"objectName"`
+	ObjectUid       string `json:"objectUid"`
+	ResourceVersion string `json:"resourceVersion"`
+	Field           string `json:"field"`
+	OldValue        string `json:"oldValue"`
+	NewValue        string `json:"newValue"`
+	Actor           string `json:"actor"`
+	ChangeTimestamp string `json:"changeTimestamp"`
+	TierName        string `json:"tierName"`
+}
+
+func NewChangeSchemaDefWrapper() ChangeSchemaDefWrapper {
+	schema := NewChangeSchemaDef()
+	wrapper := ChangeSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewChangeSchemaDef() ChangeSchemaDef {
+	pdsd := ChangeSchemaDef{ClusterName: "string", Namespace: "string", ObjectKind: "string", ObjectName: "string",
+		ObjectUid: "string", ResourceVersion: "string", Field: "string", OldValue: "string", NewValue: "string",
+		Actor: "string", ChangeTimestamp: "date", TierName: "string"}
+	return pdsd
+}
+
+func (sd ChangeSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type ChangeSchema struct {
+	ClusterName     string    `json:"clusterName"`
+	Namespace       string    `json:"namespace"`
+	ObjectKind      string    `json:"objectKind"`
+	ObjectName      string    `json:"objectName"`
+	ObjectUid       string    `json:"objectUid"`
+	ResourceVersion string    `json:"resourceVersion"`
+	Field           string    `json:"field"`
+	OldValue        string    `json:"oldValue"`
+	NewValue        string    `json:"newValue"`
+	Actor           string    `json:"actor"`
+	ChangeTimestamp time.Time `json:"changeTimestamp"`
+	TierName        string    `json:"tierName"`
+}
@@ -7,37 +7,56 @@ import (
 )
 
 type ClusterAppMetrics struct {
-	Path                string
-	Metadata            map[string]AppDMetricMetadata
-	Namespace           string
-	TierName            string
-	PodCount            int64
-	Privileged          int64
-	Evictions           int64
-	PodRestarts         int64
-	PodRunning          int64
-	PodFailed           int64
-	PodPending          int64
-	PendingTime         int64
-	UpTime              int64
-	ContainerCount      int64
-	InitContainerCount  int64
-	RequestCpu          int64
-	RequestMemory       int64
-	LimitCpu            int64
-	LimitMemory         int64
-	UseCpu              int64
-	UseMemory           int64
-	ConsumptionCpu      int64
-	ConsumptionMem      int64
-	NoLimits            int64
-	NoReadinessProbe    int64
-	NoLivenessProbe     int64
-	MissingDependencies int64
-	NoConnectivity      int64
-	Services            []ClusterServiceMetrics
-	QuotasSpec          RQFields
-	QuotasUsed          RQFields
+	Path                  string
+	Metadata              map[string]AppDMetricMetadata
+	Namespace             string
+	TierName              string
+	PodCount              int64
+	Privileged            int64
+	Evictions             int64
+	PodRestarts           int64
+	PodRunning            int64
+	PodFailed             int64
+	PodPending            int64
+	PendingTime           int64
+	UpTime                int64
+	ContainerCount        int64
+	InitContainerCount    int64
+	RequestCpu            int64
+	RequestMemory         int64
+	LimitCpu              int64
+	LimitMemory           int64
+	UseCpu                int64
+	UseMemory             int64
+	ConsumptionCpu        int64
+	ConsumptionMem        int64
+	NoLimits              int64
+	NoReadinessProbe      int64
+	NoLivenessProbe       int64
+	MissingDependencies   int64
+	NoConnectivity        int64
+	StaleConfigPods       int64
+	RestartsOOMKilled     int64
+	RestartsLivenessProbe int64
+	RestartsNonZeroExit   int64
+	RestartsEvicted       int64
+	RestartsNodeShutdown  int64
+	RestartsOther         int64
+	//pod startup latencies, ms
+	ScheduleLatencyAvg       int64
+	ScheduleLatencyMax       int64
+	ImagePullLatencyAvg      int64
+	ImagePullLatencyMax      int64
+	ContainerStartLatencyAvg int64
+	ContainerStartLatencyMax int64
+	ReadyLatencyAvg          int64
+	ReadyLatencyMax          int64
+	StartupLatencyAvg        int64
+	StartupLatencyMax        int64
+	Services                 []ClusterServiceMetrics
+	QuotasSpec               RQFields
+	QuotasUsed               RQFields
+	latencies                map[string]*latencyStats
 }
 
 type ClusterServiceMetrics struct {
@@ -137,7 +156,8 @@ func NewClusterAppMetrics(bag *AppDBag, podObject *PodSchema) ClusterAppMetrics
 		PodRestarts: 0, PodRunning: 0, PodFailed: 0, PodPending: 0, PendingTime: 0, UpTime: 0, ContainerCount: 0, InitContainerCount: 0,
 		RequestCpu: 0, RequestMemory: 0, LimitCpu: 0, LimitMemory: 0, UseCpu: 0, UseMemory: 0,
 		ConsumptionCpu: 0, ConsumptionMem: 0, NoLimits: 0, NoReadinessProbe: 0, NoLivenessProbe: 0,
-		MissingDependencies: 0, NoConnectivity: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
+		MissingDependencies: 0, NoConnectivity: 0, StaleConfigPods: 0,
+		RestartsOOMKilled: 0, RestartsLivenessProbe: 0, RestartsNonZeroExit: 0, RestartsEvicted: 0, RestartsNodeShutdown: 0, RestartsOther: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
 
 	for _, svc := range podObject.Services {
 		svcMetrics := NewClusterServiceMetrics(bag, podObject.Namespace, podObject.Owner, &svc)
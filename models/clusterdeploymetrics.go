@@ -13,6 +13,10 @@ type ClusterDeployMetrics struct {
 	DeployReplicas            int64
 	DeployReplicasUnAvailable int64
 	DeployCollisionCount      int64
+	RolloutsInProgress        int64
+	RolloutsStalled           int64
+	SingleReplicaNoPdb        int64
+	PdbZeroDisruptions        int64
 }
 
 func (cpm ClusterDeployMetrics) GetPath() string {
@@ -39,5 +43,5 @@ func NewClusterDeployMetrics(bag *AppDBag, ns string) ClusterDeployMetrics {
 		p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR)
 	}
 	return ClusterDeployMetrics{Namespace: ns, DeployCount: 0, DeployReplicas: 0, DeployReplicasUnAvailable: 0, DeployCollisionCount: 0,
-		Path: p}
+		RolloutsInProgress: 0, RolloutsStalled: 0, SingleReplicaNoPdb: 0, PdbZeroDisruptions: 0, Path: p}
 }
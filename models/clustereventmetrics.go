@@ -7,22 +7,19 @@ import (
 )
 
 type ClusterEventMetrics struct {
-	Path            string
-	Metadata        map[string]AppDMetricMetadata
-	Namespace       string
-	TierName        string
-	EventCount      int64
-	EventError      int64
-	EventInfo       int64
-	ScaleDowns      int64
-	CrashLoops      int64
-	QuotaViolations int64
-	PodIssues       int64
-	PodKills        int64
-	EvictionThreats int64
-	ImagePullErrors int64
-	ImagePulls      int64
-	StorageIssues   int64
+	Path             string
+	Metadata         map[string]AppDMetricMetadata
+	Namespace        string
+	TierName         string
+	EventCount       int64
+	EventError       int64
+	EventInfo        int64
+	CrashLoops       int64
+	PodKills         int64
+	ImagePulls       int64
+	SuppressedEvents int64
+	EventStorms      int64
+	SubCategories    map[string]int64 //metric name of the subcategory -> count
 }
 
 func (cpm ClusterEventMetrics) GetPath() string {
@@ -31,12 +28,17 @@ func (cpm ClusterEventMetrics) GetPath() string {
 }
 
 func (cpm ClusterEventMetrics) ShouldExcludeField(fieldName string) bool {
-	if fieldName == "Namespace" || fieldName == "Path" || fieldName == "Metadata" || fieldName == "TierName" || fieldName == "PodName" {
+	if fieldName == "Namespace" || fieldName == "Path" || fieldName == "Metadata" || fieldName == "TierName" || fieldName == "PodName" || fieldName == "SubCategories" {
 		return true
 	}
 	return false
 }
 
+//the summaries are rebuilt from the events received in the current interval
+func (cpm ClusterEventMetrics) IsDeltaField(fieldName string) bool {
+	return true
+}
+
 func (cpm ClusterEventMetrics) Unwrap() *map[string]interface{} {
 	objMap := structs.Map(cpm)
 
@@ -53,8 +55,14 @@ func NewClusterEventMetrics(bag *AppDBag, ns string, tierName string) ClusterEve
 			p = fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR, METRIC_PATH_APPS, METRIC_SEPARATOR, tierName, METRIC_SEPARATOR, METRIC_PATH_EVENTS, METRIC_SEPARATOR)
 		}
 	}
-	return ClusterEventMetrics{Namespace: ns, Path: p, EventCount: 0, EventError: 0, EventInfo: 0, ScaleDowns: 0, CrashLoops: 0, QuotaViolations: 0, PodIssues: 0,
-		PodKills: 0, EvictionThreats: 0, ImagePullErrors: 0, ImagePulls: 0, StorageIssues: 0, TierName: tierName}
+	return ClusterEventMetrics{Namespace: ns, Path: p, EventCount: 0, EventError: 0, EventInfo: 0, CrashLoops: 0,
+		PodKills: 0, ImagePulls: 0, SuppressedEvents: 0, EventStorms: 0, SubCategories: make(map[string]int64), TierName: tierName}
+}
+
+func (cpm ClusterEventMetrics) IncrementSubCategory(metricName string) {
+	if metricName != "" {
+		cpm.SubCategories[metricName]++
+	}
 }
 
 func NewClusterEventMetricsMetadata(bag *AppDBag, ns string, tierName string) ClusterEventMetrics {
@@ -49,6 +49,21 @@ type ClusterPodMetrics struct {
 	NoConnectivity      int64
 	QuotasSpec          RQFields
 	QuotasUsed          RQFields
+	//pending pods that cannot be scheduled, per predicate
+	UnschedulableInsufficientCpu       int64
+	UnschedulableInsufficientMemory    int64
+	UnschedulableInsufficientResources int64
+	UnschedulableNodeUnschedulable     int64
+	UnschedulableTaints                int64
+	UnschedulableNodeAffinity          int64
+	UnschedulablePodAffinity           int64
+	UnschedulableVolumeAffinity        int64
+	UnschedulableVolumeBinding         int64
+	UnschedulableHostPorts             int64
+	UnschedulableTopologySpread        int64
+	UnschedulableOther                 int64
+	UnschedulableCapacity              int64 //pods that need more capacity
+	UnschedulableSpec                  int64 //pods whose spec prevents scheduling
 }
 
 func (cpm ClusterPodMetrics) GetPath() string {
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+//image pulls from one registry in the current metrics interval
+type ClusterRegistryMetrics struct {
+	Path            string
+	Registry        string
+	Pulls           int64
+	CachedPulls     int64
+	PullFailures    int64
+	PullTimeAvg     int64
+	PullTimeMax     int64
+	PullBytes       int64
+	AuthErrors      int64
+	NotFoundErrors  int64
+	TimeoutErrors   int64
+	RateLimitErrors int64
+	OtherErrors     int64
+	timedPulls      int64
+	pullTimeTotal   int64
+}
+
+func (cpm ClusterRegistryMetrics) GetPath() string {
+
+	return cpm.Path
+}
+
+func (cpm ClusterRegistryMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Path" || fieldName == "Registry" {
+		return true
+	}
+	return false
+}
+
+//all but the pull times are counts of the current interval
+func (cpm ClusterRegistryMetrics) IsDeltaField(fieldName string) bool {
+	return fieldName != "PullTimeAvg" && fieldName != "PullTimeMax"
+}
+
+func (cpm ClusterRegistryMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+
+	return &objMap
+}
+
+func NewClusterRegistryMetrics(registry string) ClusterRegistryMetrics {
+	//the port separator of the registry host would break the metric path
+	p := fmt.Sprintf("%s%s%s%s%s", RootPath, METRIC_PATH_REGISTRIES, METRIC_SEPARATOR, strings.Replace(registry, ":", "_", -1), METRIC_SEPARATOR)
+	return ClusterRegistryMetrics{Registry: registry, Pulls: 0, CachedPulls: 0, PullFailures: 0, PullTimeAvg: 0, PullTimeMax: 0,
+		PullBytes: 0, AuthErrors: 0, NotFoundErrors: 0, TimeoutErrors: 0, RateLimitErrors: 0, OtherErrors: 0, Path: p}
+}
+
+func (cpm *ClusterRegistryMetrics) AddPull(record *ImagePullSchema) {
+	switch record.Status {
+	case IMAGE_PULL_CACHED:
+		cpm.CachedPulls++
+		return
+	case IMAGE_PULL_FAILED:
+		cpm.PullFailures++
+		switch record.ErrorClass {
+		case IMAGE_PULL_ERROR_AUTH:
+			cpm.AuthErrors++
+		case IMAGE_PULL_ERROR_NOT_FOUND:
+			cpm.NotFoundErrors++
+		case IMAGE_PULL_ERROR_TIMEOUT:
+			cpm.TimeoutErrors++
+		case IMAGE_PULL_ERROR_RATE_LIMIT:
+			cpm.RateLimitErrors++
+		default:
+			cpm.OtherErrors++
+		}
+		return
+	}
+	cpm.Pulls++
+	cpm.PullBytes += record.Bytes
+	if record.Duration > 0 {
+		cpm.timedPulls++
+		cpm.pullTimeTotal += record.Duration
+		cpm.PullTimeAvg = cpm.pullTimeTotal / cpm.timedPulls
+		if record.Duration > cpm.PullTimeMax {
+			cpm.PullTimeMax = record.Duration
+		}
+	}
+}
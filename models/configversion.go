@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+//version of a configmap or secret as observed by the agent
+type ConfigVersion struct {
+	ResourceVersion string
+	Hash            string
+	Updated         time.Time
+}
+
+//config that changed after the given time is stale for pods started before the change
+func (cv *ConfigVersion) IsStale(podStartTime time.Time) bool {
+	return !podStartTime.IsZero() && cv.Updated.After(podStartTime)
+}
@@ -18,6 +18,8 @@ type ContainerSchemaDef struct {
 	ClusterName       string `json:"clusterName"`
 	NodeName          string `json:"nodeName"`
 	PodName           string `json:"podName"`
+	OwnerKind         string `json:"ownerKind"`
+	OwnerName         string `json:"ownerName"`
 	PodInitTime       string `json:"podInitTime"`
 	StartTime         string `json:"startTime"`
 	LiveProbes        string `json:"liveProbes"`
@@ -43,6 +45,9 @@ type ContainerSchemaDef struct {
 	MissingConfigs    string `json:"missingConfigs"`
 	MissingSecrets    string `json:"missingSecrets"`
 	MissingServices   string `json:"missingServices"`
+	StaleConfigs      string `json:"staleConfigs"`
+	StaleSecrets      string `json:"staleSecrets"`
+	RestartCause      string `json:"restartCause"`
 	ConsumptionCpu    string `json:"consumptionCpu"`
 	ConsumptionMem    string `json:"consumptionMem"`
 }
@@ -64,11 +69,11 @@ func (sd ContainerSchemaDefWrapper) Unwrap() *map[string]interface{} {
 }
 
 func NewContainerSchemaDef() ContainerSchemaDef {
-	pdsd := ContainerSchemaDef{Name: "string", Init: "boolean", Namespace: "string", ClusterName: "string", NodeName: "string", PodName: "string", PodInitTime: "date", StartTime: "date", LiveProbes: "integer", ReadyProbes: "integer", Restarts: "integer",
+	pdsd := ContainerSchemaDef{Name: "string", Init: "boolean", Namespace: "string", ClusterName: "string", NodeName: "string", PodName: "string", OwnerKind: "string", OwnerName: "string", PodInitTime: "date", StartTime: "date", LiveProbes: "integer", ReadyProbes: "integer", Restarts: "integer",
 		Privileged: "integer", Ports: "string", MemRequest: "float", CpuRequest: "float", CpuLimit: "float", MemLimit: "float",
 		ConsumptionCpu: "float", ConsumptionMem: "float", PodStorageRequest: "float", PodStorageLimit: "float", StorageRequest: "float", StorageCapacity: "float", CpuUse: "float", MemUse: "float",
 		Image: "string", WaitReason: "string", TermReason: "string", TerminationTime: "date", Mounts: "string", MissingConfigs: "string",
-		MissingSecrets: "string", MissingServices: "string"}
+		MissingSecrets: "string", MissingServices: "string", StaleConfigs: "string", StaleSecrets: "string", RestartCause: "string"}
 	return pdsd
 }
 
@@ -86,6 +91,8 @@ type ContainerSchema struct {
 	ClusterName          string          `json:"clusterName"`
 	NodeName             string          `json:"nodeName"`
 	PodName              string          `json:"podName"`
+	OwnerKind            string          `json:"ownerKind"`
+	OwnerName            string          `json:"ownerName"`
 	PodInitTime          time.Time       `json:"podInitTime"`
 	StartTime            *time.Time      `json:"startTime"`
 	LiveProbes           int             `json:"liveProbes"`
@@ -111,6 +118,9 @@ type ContainerSchema struct {
 	MissingConfigs       string          `json:"missingConfigs"`
 	MissingSecrets       string          `json:"missingSecrets"`
 	MissingServices      string          `json:"missingServices"`
+	StaleConfigs         string          `json:"staleConfigs"`
+	StaleSecrets         string          `json:"staleSecrets"`
+	RestartCause         string          `json:"restartCause"`
 	ConsumptionCpu       float64         `json:"consumptionCpu"`
 	ConsumptionMem       float64         `json:"consumptionMem"`
 	Index                int8            `json:"-"`
@@ -154,6 +164,10 @@ func (p *ContainerSchema) HasMissingDependencies() bool {
 	return p.MissingConfigs != "" || p.MissingSecrets != ""
 }
 
+func (p *ContainerSchema) HasStaleConfig() bool {
+	return p.StaleConfigs != "" || p.StaleSecrets != ""
+}
+
 func (p *ContainerSchema) NoConnectivity() bool {
 	return p.MissingServices != ""
 }
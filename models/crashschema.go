@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/fatih/structs"
+	"k8s.io/api/core/v1"
+)
+
+type CrashSchemaDefWrapper struct {
+	Schema CrashSchemaDef `json:"schema"`
+}
+
+func (sd CrashSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type CrashSchemaDef struct {
+	IncidentID      string `json:"incidentId"`
+	ClusterName     string `json:"clusterName"`
+	Namespace       string `json:"namespace"`
+	PodName         string `json:"podName"`
+	PodOwner        string `json:"podOwner"`
+	OwnerKind       string `json:"ownerKind"`
+	OwnerName       string `json:"ownerName"`
+	ContainerName   string `json:"containerName"`
+	NodeName        string `json:"nodeName"`
+	Image           string `json:"image"`
+	RestartCount    string `json:"restartCount"`
+	RestartCause    string `json:"restartCause"`
+	ExitCode        string `json:"exitCode"`
+	Signal          string `json:"signal"`
+	TermReason      string `json:"termReason"`
+	TermMessage     string `json:"termMessage"`
+	StartedAt       string `json:"startedAt"`
+	FinishedAt      string `json:"finishedAt"`
+	PreviousLogs    string `json:"previousLogs"`
+	Events          string `json:"events"`
+	CpuUse          string `json:"cpuUse"`
+	MemUse          string `json:"memUse"`
+	CpuLimit        string `json:"cpuLimit"`
+	MemLimit        string `json:"memLimit"`
+	ConsumptionCpu  string `json:"consumptionCpu"`
+	ConsumptionMem  string `json:"consumptionMem"`
+	Revision        string `json:"revision"`
+	HealthyRevision string `json:"healthyRevision"`
+	SpecChanges     string `json:"specChanges"`
+	Timestamp       string `json:"timestamp"`
+}
+
+func NewCrashSchemaDefWrapper() CrashSchemaDefWrapper {
+	schema := NewCrashSchemaDef()
+	wrapper := CrashSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewCrashSchemaDef() CrashSchemaDef {
+	pdsd := CrashSchemaDef{IncidentID: "string", ClusterName: "string", Namespace: "string", PodName: "string", PodOwner: "string",
+		OwnerKind: "string", OwnerName: "string", ContainerName: "string", NodeName: "string", Image: "string", RestartCount: "integer",
+		RestartCause: "string", ExitCode: "integer", Signal: "integer", TermReason: "string", TermMessage: "string", StartedAt: "date",
+		FinishedAt: "date", PreviousLogs: "string", Events: "string", CpuUse: "integer", MemUse: "integer", CpuLimit: "integer",
+		MemLimit: "integer", ConsumptionCpu: "float", ConsumptionMem: "float", Revision: "string", HealthyRevision: "string",
+		SpecChanges: "string", Timestamp: "date"}
+	return pdsd
+}
+
+func (sd CrashSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type CrashSchema struct {
+	IncidentID      string    `json:"incidentId"`
+	ClusterName     string    `json:"clusterName"`
+	Namespace       string    `json:"namespace"`
+	PodName         string    `json:"podName"`
+	PodOwner        string    `json:"podOwner"`
+	OwnerKind       string    `json:"ownerKind"`
+	OwnerName       string    `json:"ownerName"`
+	ContainerName   string    `json:"containerName"`
+	NodeName        string    `json:"nodeName"`
+	Image           string    `json:"image"`
+	RestartCount    int32     `json:"restartCount"`
+	RestartCause    string    `json:"restartCause"`
+	ExitCode        int32     `json:"exitCode"`
+	Signal          int32     `json:"signal"`
+	TermReason      string    `json:"termReason"`
+	TermMessage     string    `json:"termMessage"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	PreviousLogs    string    `json:"previousLogs"`
+	Events          string    `json:"events"`
+	CpuUse          int64     `json:"cpuUse"`
+	MemUse          int64     `json:"memUse"`
+	CpuLimit        int64     `json:"cpuLimit"`
+	MemLimit        int64     `json:"memLimit"`
+	ConsumptionCpu  float64   `json:"consumptionCpu"`
+	ConsumptionMem  float64   `json:"consumptionMem"`
+	Revision        string    `json:"revision"`
+	HealthyRevision string    `json:"healthyRevision"`
+	SpecChanges     string    `json:"specChanges"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+//the same failure always maps to the same incident, regardless of how many times it is observed
+func GetIncidentID(namespace, podName, containerName string, term *v1.ContainerStateTerminated) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s/%s/%s/%d", namespace, podName, containerName, term.FinishedAt.UTC().Format(time.RFC3339), term.ExitCode)
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+func NewCrashSchema(podSchema *PodSchema, containerSchema *ContainerSchema, term *v1.ContainerStateTerminated) CrashSchema {
+	return CrashSchema{IncidentID: GetIncidentID(podSchema.Namespace, podSchema.Name, containerSchema.Name, term),
+		ClusterName: podSchema.ClusterName, Namespace: podSchema.Namespace, PodName: podSchema.Name, PodOwner: podSchema.Owner,
+		OwnerKind: podSchema.OwnerKind, OwnerName: podSchema.OwnerName, ContainerName: containerSchema.Name, NodeName: podSchema.NodeName,
+		Image: containerSchema.Image, RestartCount: containerSchema.Restarts, RestartCause: containerSchema.RestartCause,
+		ExitCode: term.ExitCode, Signal: term.Signal, TermReason: term.Reason, TermMessage: term.Message,
+		StartedAt: term.StartedAt.Time, FinishedAt: term.FinishedAt.Time, Timestamp: time.Now()}
+}
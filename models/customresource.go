@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+type CustomResourceField struct {
+	Name string //name of the field in the analytics schema
+	Path string //JSONPath expression, e.g. .status.phase
+	Type string //string, integer, float, boolean, date. Default is string
+}
+
+type CustomResourceConfig struct {
+	Group         string
+	Version       string
+	Resource      string //plural name of the resource, e.g. certificates
+	Kind          string
+	SchemaName    string
+	ConditionType string //condition in status.conditions used for health metrics. Default is Ready
+	Fields        []CustomResourceField
+}
+
+func (crc *CustomResourceConfig) GetKind() string {
+	if crc.Kind != "" {
+		return crc.Kind
+	}
+	return crc.Resource
+}
+
+func (crc *CustomResourceConfig) GetSchemaName() string {
+	if crc.SchemaName != "" {
+		return crc.SchemaName
+	}
+	name := fmt.Sprintf("kube_cr_%s", crc.Resource)
+	if crc.Group != "" {
+		name = fmt.Sprintf("%s_%s", name, crc.Group)
+	}
+	return strings.ToLower(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+func (crc *CustomResourceConfig) GetConditionType() string {
+	if crc.ConditionType != "" {
+		return crc.ConditionType
+	}
+	return "Ready"
+}
+
+func (crc *CustomResourceConfig) IsValid() bool {
+	return crc.Version != "" && crc.Resource != ""
+}
+
+type CustomResourceSchemaDefWrapper struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+func (sd CustomResourceSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := map[string]interface{}{"Schema": sd.Schema}
+	return &objMap
+}
+
+func NewCustomResourceSchemaDefWrapper(crc *CustomResourceConfig) CustomResourceSchemaDefWrapper {
+	schema := map[string]interface{}{"name": "string", "clusterName": "string", "namespace": "string", "kind": "string",
+		"objectUid": "string", "creationTimestamp": "date", "deletionTimestamp": "date", "labels": "string",
+		"annotations": "string", "conditionStatus": "string", "conditionReason": "string"}
+	for _, f := range crc.Fields {
+		t := f.Type
+		if t == "" {
+			t = "string"
+		}
+		schema[f.Name] = t
+	}
+	return CustomResourceSchemaDefWrapper{Schema: schema}
+}
+
+type CustomResourceRecord struct {
+	SchemaName string
+	Data       map[string]interface{}
+}
+
+type ClusterCustomResourceMetrics struct {
+	Path             string
+	Namespace        string
+	Kind             string
+	ResourceCount    int64
+	ConditionTrue    int64
+	ConditionFalse   int64
+	ConditionUnknown int64
+}
+
+func (cpm ClusterCustomResourceMetrics) GetPath() string {
+
+	return cpm.Path
+}
+
+func (cpm ClusterCustomResourceMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Namespace" || fieldName == "Path" || fieldName == "Kind" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterCustomResourceMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+
+	return &objMap
+}
+
+func NewClusterCustomResourceMetrics(bag *AppDBag, ns string, kind string) ClusterCustomResourceMetrics {
+	p := RootPath
+	if ns != "" && ns != ALL {
+		p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR)
+	}
+	p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_CUSTOM_RESOURCES, METRIC_SEPARATOR, kind, METRIC_SEPARATOR)
+	return ClusterCustomResourceMetrics{Namespace: ns, Kind: kind, ResourceCount: 0, ConditionTrue: 0, ConditionFalse: 0,
+		ConditionUnknown: 0, Path: p}
+}
@@ -52,6 +52,8 @@ type DeploySchemaDef struct {
 	MissScheduled          string `json:"missScheduled"`
 	UpdatedNumberScheduled string `json:"updatedNumberScheduled"`
 	DeploymentType         string `json:"deploymentType"`
+	PdbName                string `json:"pdbName"`
+	DrainRisk              string `json:"drainRisk"`
 }
 
 func NewDeploySchemaDefWrapper() DeploySchemaDefWrapper {
@@ -67,7 +69,7 @@ func NewDeploySchemaDef() DeploySchemaDef {
 		MaxSurge: "string", MaxUnavailable: "string", ReplicasAvailable: "integer", ReplicasUnAvailable: "integer",
 		ReplicasUpdated: "integer", CollisionCount: "integer", ReplicasReady: "integer", ReplicasLabeled: "integer",
 		NumberScheduled: "integer", DesiredNumber: "integer", MissScheduled: "integer", UpdatedNumberScheduled: "integer",
-		DeploymentType: "string"}
+		DeploymentType: "string", PdbName: "string", DrainRisk: "string"}
 	return pdsd
 }
 
@@ -103,6 +105,8 @@ type DeploySchema struct {
 	MissScheduled          int32     `json:"missScheduled"`
 	UpdatedNumberScheduled int32     `json:"updatedNumberScheduled"`
 	DeploymentType         string    `json:"deploymentType"`
+	PdbName                string    `json:"pdbName"`
+	DrainRisk              string    `json:"drainRisk"`
 }
 
 type DeployObjList struct {
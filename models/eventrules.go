@@ -0,0 +1,208 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	EVENT_CATEGORY_ERROR string = "error"
+	EVENT_CATEGORY_INFO  string = "info"
+
+	EVENT_SEVERITY_ERROR string = "ERROR"
+	EVENT_SEVERITY_INFO  string = "INFO"
+
+	EVENT_RULES_KEY string = "event-rules.json"
+)
+
+//metric names of the built-in subcategories. Kept stable for dashboards and searches
+var eventSubCategoryMetrics = map[string]string{
+	"pod":       "PodIssues",
+	"image":     "ImagePullErrors",
+	"reduction": "ScaleDowns",
+	"storage":   "StorageIssues",
+	"eviction":  "EvictionThreats",
+	"quota":     "QuotaViolations",
+}
+
+type EventRule struct {
+	Reasons          []string //event reasons. Empty matches any reason
+	Kinds            []string //kinds of the involved object, e.g. Pod, Node. Empty matches any kind
+	SourceComponents []string //reporting components, e.g. kubelet. Empty matches any component
+	MessagePattern   string   //regular expression applied to the event message
+	Category         string   //error or info. Default is info
+	SubCategory      string
+	Severity         string //default is ERROR for the error category and INFO otherwise
+	Metric           string //summary metric of the subcategory. Derived from the subcategory if empty
+	messageRegex     *regexp.Regexp
+}
+
+func (r *EventRule) Compile() error {
+	r.messageRegex = nil
+	if r.MessagePattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(r.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("Invalid message pattern %s. %v", r.MessagePattern, err)
+	}
+	r.messageRegex = re
+	return nil
+}
+
+func (r *EventRule) Matches(e *EventSchema) bool {
+	if len(r.Reasons) > 0 && !containsString(r.Reasons, e.Reason) {
+		return false
+	}
+	if len(r.Kinds) > 0 && !containsString(r.Kinds, e.ObjectKind) {
+		return false
+	}
+	if len(r.SourceComponents) > 0 && !containsString(r.SourceComponents, e.SourceComponent) {
+		return false
+	}
+	if r.messageRegex != nil && !r.messageRegex.MatchString(e.Message) {
+		return false
+	}
+	return true
+}
+
+func (r *EventRule) GetCategory() string {
+	if r.Category == "" {
+		return EVENT_CATEGORY_INFO
+	}
+	return strings.ToLower(r.Category)
+}
+
+func (r *EventRule) GetSeverity() string {
+	if r.Severity != "" {
+		return strings.ToUpper(r.Severity)
+	}
+	return GetDefaultEventSeverity(r.GetCategory())
+}
+
+func (r *EventRule) GetMetric() string {
+	if r.Metric != "" {
+		return r.Metric
+	}
+	return GetEventSubCategoryMetric(r.SubCategory)
+}
+
+func GetDefaultEventSeverity(category string) string {
+	if category == EVENT_CATEGORY_ERROR {
+		return EVENT_SEVERITY_ERROR
+	}
+	return EVENT_SEVERITY_INFO
+}
+
+func GetEventSubCategoryMetric(sub string) string {
+	if sub == "" {
+		return ""
+	}
+	if metric, ok := eventSubCategoryMetrics[sub]; ok {
+		return metric
+	}
+	name := ""
+	for _, part := range strings.FieldsFunc(sub, func(c rune) bool { return c == '-' || c == '_' || c == ' ' || c == '.' }) {
+		name += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return name + "Events"
+}
+
+/*
+ Ordered list of rules. The first matching rule assigns the category, subcategory and severity.
+ Configured rules are evaluated before the built-in defaults
+*/
+type EventRuleSet struct {
+	Rules   []EventRule
+	Metrics map[string]string //subcategory -> metric name
+}
+
+func NewEventRuleSet(configured []EventRule) (EventRuleSet, []error) {
+	errs := []error{}
+	set := EventRuleSet{Rules: []EventRule{}, Metrics: make(map[string]string)}
+	for sub, metric := range eventSubCategoryMetrics {
+		set.Metrics[sub] = metric
+	}
+	all := append(append([]EventRule{}, configured...), DefaultEventRules()...)
+	for _, r := range all {
+		if err := r.Compile(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		set.Rules = append(set.Rules, r)
+		if r.SubCategory != "" {
+			if _, ok := set.Metrics[r.SubCategory]; !ok || r.Metric != "" {
+				set.Metrics[r.SubCategory] = r.GetMetric()
+			}
+		}
+	}
+	return set, errs
+}
+
+func (rs *EventRuleSet) Categorize(e *EventSchema) (string, string, string) {
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Matches(e) {
+			return r.GetCategory(), r.SubCategory, r.GetSeverity()
+		}
+	}
+	return EVENT_CATEGORY_INFO, "", EVENT_SEVERITY_INFO
+}
+
+func (rs *EventRuleSet) GetMetric(sub string) string {
+	if metric, ok := rs.Metrics[sub]; ok {
+		return metric
+	}
+	return GetEventSubCategoryMetric(sub)
+}
+
+func (rs *EventRuleSet) MetricNames() []string {
+	unique := make(map[string]bool)
+	for _, metric := range rs.Metrics {
+		unique[metric] = true
+	}
+	names := []string{}
+	for metric, _ := range unique {
+		names = append(names, metric)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+//built-in rules matching the original categorization of kubernetes events
+func DefaultEventRules() []EventRule {
+	storage := []string{"FailedAttachVolume", "FailedDetachVolume", "FailedMount", "FailedBinding", "VolumeResizeFailed", "FileSystemResizeFailed",
+		"FailedUnMount", "FailedMapVolume", "FailedUnmapDevice", "UnsupportedMountOption", "InvalidDiskCapacity", "FreeDiskSpaceFailed"}
+	pod := []string{"FailedKillPod", "FailedCreatePodContainer", "ContainerGCFailed", "SandboxChanged", "FailedCreatePodSandBox", "FailedPodSandBoxStatus", "Unhealthy"}
+	image := []string{"ErrImageNeverPull", "ImageGCFailed"}
+	errors := []string{"NodeAllocatableEnforced", "Preempting", "InspectFailed", "KubeletSetupFailed", "NodeSelectorMismatching",
+		"FailedNodeAllocatableEnforcement", "UnfinishedPreStopHook", "ErrorReconciliationRetryTimeout"}
+
+	return []EventRule{
+		{Reasons: []string{"ScalingReplicaSet"}, MessagePattern: "Scaled down", Category: EVENT_CATEGORY_ERROR, SubCategory: "reduction"},
+		{Reasons: []string{"Killing"}, Category: EVENT_CATEGORY_ERROR, SubCategory: "reduction"},
+		{Reasons: []string{"Failed"}, MessagePattern: "ImagePullBackOff|image", Category: EVENT_CATEGORY_ERROR, SubCategory: "image"},
+		{Reasons: []string{"Failed"}, Category: EVENT_CATEGORY_ERROR, SubCategory: "pod"},
+		{Reasons: []string{"BackOff"}, MessagePattern: "container", Category: EVENT_CATEGORY_ERROR, SubCategory: "pod"},
+		{Reasons: []string{"BackOff"}, MessagePattern: "image", Category: EVENT_CATEGORY_ERROR, SubCategory: "image"},
+		{Reasons: []string{"BackOff"}, Category: EVENT_CATEGORY_ERROR},
+		{Reasons: []string{"FailedCreate"}, MessagePattern: "quota", Category: EVENT_CATEGORY_ERROR, SubCategory: "quota"},
+		{Reasons: []string{"FailedCreate"}, Category: EVENT_CATEGORY_ERROR, SubCategory: "pod"},
+		{Reasons: pod, Category: EVENT_CATEGORY_ERROR, SubCategory: "pod"},
+		{Reasons: image, Category: EVENT_CATEGORY_ERROR, SubCategory: "image"},
+		{Reasons: storage, Category: EVENT_CATEGORY_ERROR, SubCategory: "storage"},
+		{Reasons: errors, Category: EVENT_CATEGORY_ERROR},
+		{Reasons: []string{"Evicted"}, Category: EVENT_CATEGORY_INFO, SubCategory: "eviction"},
+	}
+}
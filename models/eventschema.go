@@ -33,11 +33,19 @@ type EventSchemaDef struct {
 	Name                  string `json:"name"`
 	Namespace             string `json:"namespace"`
 	OwnerReferences       string `json:"ownerReferences"`
+	OwnerKind             string `json:"ownerKind"`
+	OwnerName             string `json:"ownerName"`
 	ResourceVersion       string `json:"resourceVersion"`
 	SelfLink              string `json:"selfLink"`
 	Type                  string `json:"type"`
 	Category              string `json:"category"`
 	SubCategory           string `json:"subCategory"`
+	Severity              string `json:"severity"`
+	Fingerprint           string `json:"fingerprint"`
+	FirstSeen             string `json:"firstSeen"`
+	LastSeen              string `json:"lastSeen"`
+	Occurrences           string `json:"occurrences"`
+	Storm                 string `json:"storm"`
 	Count                 string `json:"count"`
 	SourceComponent       string `json:"sourceComponent"`
 	SourceHost            string `json:"sourceHost"`
@@ -53,7 +61,8 @@ func NewEventSchemaDefWrapper() EventSchemaDefWrapper {
 func NewEventSchemaDef() EventSchemaDef {
 	pdsd := EventSchemaDef{ObjectKind: "string", ObjectName: "string", ClusterName: "string", ObjectNamespace: "string", ObjectResourceVersion: "string",
 		ObjectUid: "string", LastTimestamp: "date", Message: "string", CreationTimestamp: "date", DeletionTimestamp: "date", GenerateName: "string", Generation: "integer",
-		Name: "string", Namespace: "string", OwnerReferences: "string", ResourceVersion: "string", Category: "string", SubCategory: "string",
+		Name: "string", Namespace: "string", OwnerReferences: "string", OwnerKind: "string", OwnerName: "string", ResourceVersion: "string", Category: "string", SubCategory: "string", Severity: "string",
+		Fingerprint: "string", FirstSeen: "date", LastSeen: "date", Occurrences: "integer", Storm: "boolean",
 		SelfLink: "string", Type: "string", Count: "integer", SourceComponent: "string", SourceHost: "string", Reason: "string"}
 	return pdsd
 }
@@ -79,11 +88,19 @@ type EventSchema struct {
 	Name                  string    `json:"name"`
 	Namespace             string    `json:"namespace"`
 	OwnerReferences       string    `json:"ownerReferences"`
+	OwnerKind             string    `json:"ownerKind"`
+	OwnerName             string    `json:"ownerName"`
 	ResourceVersion       string    `json:"resourceVersion"`
 	SelfLink              string    `json:"selfLink"`
 	Type                  string    `json:"type"`
 	Category              string    `json:"category"`
 	SubCategory           string    `json:"subCategory"`
+	Severity              string    `json:"severity"`
+	Fingerprint           string    `json:"fingerprint"`
+	FirstSeen             time.Time `json:"firstSeen"`
+	LastSeen              time.Time `json:"lastSeen"`
+	Occurrences           int32     `json:"occurrences"`
+	Storm                 bool      `json:"storm"`
 	Count                 int32     `json:"count"`
 	SourceComponent       string    `json:"sourceComponent"`
 	SourceHost            string    `json:"sourceHost"`
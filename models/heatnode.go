@@ -48,6 +48,7 @@ type HeatNode struct {
 	Restarts    int32
 	Events      []string
 	Containers  map[string]Utilization
+	IncidentID  string //last crash incident of the pod
 }
 
 func NewHeatNode(podSchema PodSchema) HeatNode {
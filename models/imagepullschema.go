@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+//outcome of the image pull
+const (
+	IMAGE_PULL_PULLED string = "pulled"
+	IMAGE_PULL_CACHED string = "cached" //image already present on the node
+	IMAGE_PULL_FAILED string = "failed"
+)
+
+//classes of image pull errors
+const (
+	IMAGE_PULL_ERROR_AUTH       string = "auth"
+	IMAGE_PULL_ERROR_NOT_FOUND  string = "notFound"
+	IMAGE_PULL_ERROR_TIMEOUT    string = "timeout"
+	IMAGE_PULL_ERROR_RATE_LIMIT string = "rateLimit"
+	IMAGE_PULL_ERROR_OTHER      string = "other"
+)
+
+//image references are pinned either by tag or by digest
+const (
+	IMAGE_REF_TAG    string = "tag"
+	IMAGE_REF_DIGEST string = "digest"
+)
+
+//components of an image reference, e.g. registry.example.com:5000/team/app:1.2@sha256:...
+type ImageReference struct {
+	Registry      string
+	Repository    string
+	Tag           string
+	Digest        string
+	ReferenceType string
+}
+
+type ImagePullSchemaDefWrapper struct {
+	Schema ImagePullSchemaDef `json:"schema"`
+}
+
+func (sd ImagePullSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type ImagePullSchemaDef struct {
+	ClusterName   string `json:"clusterName"`
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	PodOwner      string `json:"podOwner"`
+	ContainerName string `json:"containerName"`
+	NodeName      string `json:"nodeName"`
+	Image         string `json:"image"`
+	Registry      string `json:"registry"`
+	Repository    string `json:"repository"`
+	Tag           string `json:"tag"`
+	Digest        string `json:"digest"`
+	ReferenceType string `json:"referenceType"`
+	Status        string `json:"status"`
+	Duration      string `json:"duration"`
+	Bytes         string `json:"bytes"`
+	ErrorClass    string `json:"errorClass"`
+	ErrorMessage  string `json:"errorMessage"`
+	Timestamp     string `json:"timestamp"`
+}
+
+func NewImagePullSchemaDefWrapper() ImagePullSchemaDefWrapper {
+	schema := NewImagePullSchemaDef()
+	wrapper := ImagePullSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewImagePullSchemaDef() ImagePullSchemaDef {
+	pdsd := ImagePullSchemaDef{ClusterName: "string", Namespace: "string", PodName: "string", PodOwner: "string",
+		ContainerName: "string", NodeName: "string", Image: "string", Registry: "string", Repository: "string", Tag: "string",
+		Digest: "string", ReferenceType: "string", Status: "string", Duration: "integer", Bytes: "integer", ErrorClass: "string",
+		ErrorMessage: "string", Timestamp: "date"}
+	return pdsd
+}
+
+func (sd ImagePullSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type ImagePullSchema struct {
+	ClusterName   string    `json:"clusterName"`
+	Namespace     string    `json:"namespace"`
+	PodName       string    `json:"podName"`
+	PodOwner      string    `json:"podOwner"`
+	ContainerName string    `json:"containerName"`
+	NodeName      string    `json:"nodeName"`
+	Image         string    `json:"image"`
+	Registry      string    `json:"registry"`
+	Repository    string    `json:"repository"`
+	Tag           string    `json:"tag"`
+	Digest        string    `json:"digest"`
+	ReferenceType string    `json:"referenceType"`
+	Status        string    `json:"status"`
+	Duration      int64     `json:"duration"` //milliseconds
+	Bytes         int64     `json:"bytes"`    //0 if not reported by the kubelet
+	ErrorClass    string    `json:"errorClass"`
+	ErrorMessage  string    `json:"errorMessage"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+func NewImagePullSchema(eventObject *EventSchema, containerName string, image string, ref ImageReference) ImagePullSchema {
+	return ImagePullSchema{ClusterName: eventObject.ClusterName, Namespace: eventObject.ObjectNamespace, PodName: eventObject.ObjectName,
+		PodOwner: eventObject.OwnerName, ContainerName: containerName, NodeName: eventObject.SourceHost, Image: image,
+		Registry: ref.Registry, Repository: ref.Repository, Tag: ref.Tag, Digest: ref.Digest, ReferenceType: ref.ReferenceType,
+		Timestamp: eventObject.LastSeen}
+}
@@ -0,0 +1,301 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	LOG_FORMAT_JSON   string = "json"
+	LOG_FORMAT_LOGFMT string = "logfmt"
+	LOG_FORMAT_REGEX  string = "regex"
+)
+
+//keys recognized in structured logs, in order of precedence
+var (
+	logMessageKeys = []string{"msg", "message", "log", "text"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "loglevel", "log.level", "levelname"}
+	logLoggerKeys  = []string{"logger", "logger_name", "loggername", "log.logger", "name", "category"}
+	logTraceKeys   = []string{"trace_id", "traceid", "trace.id", "traceId", "dd.trace_id", "x-b3-traceid", "trace"}
+	logSpanKeys    = []string{"span_id", "spanid", "span.id", "spanId", "dd.span_id", "x-b3-spanid", "span"}
+)
+
+/*
+ Parser of the log lines of selected pods. A parser applies to the pods whose annotation names it
+ or, if no annotation is set, to the pods matching its selectors
+*/
+type LogParserConfig struct {
+	Name       string
+	Format     string   //json, logfmt or regex
+	Pattern    string   //regular expression with named groups, e.g. (?P<level>\w+) (?P<msg>.*). Required for the regex format
+	Namespaces []string //empty matches any namespace
+	Owners     []string //tiers. Empty matches any tier
+	Containers []string //empty matches any container
+}
+
+type LogParser struct {
+	Config LogParserConfig
+	regex  *regexp.Regexp
+}
+
+//result of parsing a log line
+type ParsedLog struct {
+	Message string
+	Level   string
+	Logger  string
+	TraceID string
+	SpanID  string
+	Fields  map[string]string
+}
+
+func NewLogParser(config LogParserConfig) (*LogParser, error) {
+	parser := LogParser{Config: config}
+	parser.Config.Format = strings.ToLower(config.Format)
+	switch parser.Config.Format {
+	case LOG_FORMAT_JSON, LOG_FORMAT_LOGFMT:
+	case LOG_FORMAT_REGEX:
+		re, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern of log parser %s. %v", config.Name, err)
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				named = true
+			}
+		}
+		if !named {
+			return nil, fmt.Errorf("Pattern of log parser %s has no named groups", config.Name)
+		}
+		parser.regex = re
+	default:
+		return nil, fmt.Errorf("Log parser %s has unsupported format %s", config.Name, config.Format)
+	}
+	return &parser, nil
+}
+
+func (lp *LogParser) Matches(namespace, owner, container string) bool {
+	if len(lp.Config.Namespaces) > 0 && !containsString(lp.Config.Namespaces, namespace) {
+		return false
+	}
+	if len(lp.Config.Owners) > 0 && !containsString(lp.Config.Owners, owner) {
+		return false
+	}
+	if len(lp.Config.Containers) > 0 && !containsString(lp.Config.Containers, container) {
+		return false
+	}
+	return true
+}
+
+//returns false if the line does not have the expected format
+func (lp *LogParser) Parse(line string) (ParsedLog, bool) {
+	var fields map[string]string
+	switch lp.Config.Format {
+	case LOG_FORMAT_JSON:
+		fields = parseJSONLog(line)
+	case LOG_FORMAT_LOGFMT:
+		fields = parseLogfmt(line)
+	case LOG_FORMAT_REGEX:
+		fields = lp.parseRegex(line)
+	}
+	if len(fields) == 0 {
+		return ParsedLog{Message: line}, false
+	}
+	parsed := ParsedLog{Fields: fields}
+	parsed.Message = takeLogField(fields, logMessageKeys)
+	if parsed.Message == "" {
+		parsed.Message = line
+	}
+	parsed.Level = normalizeLogLevel(takeLogField(fields, logLevelKeys))
+	parsed.Logger = takeLogField(fields, logLoggerKeys)
+	parsed.TraceID = takeLogField(fields, logTraceKeys)
+	parsed.SpanID = takeLogField(fields, logSpanKeys)
+	return parsed, true
+}
+
+func (lp *LogParser) parseRegex(line string) map[string]string {
+	match := lp.regex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	for i, name := range lp.regex.SubexpNames() {
+		if i > 0 && name != "" && match[i] != "" {
+			fields[name] = match[i]
+		}
+	}
+	return fields
+}
+
+//nested objects are flattened with dot separated keys
+func parseJSONLog(line string) map[string]string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	flattenLogFields("", obj, fields)
+	return fields
+}
+
+func flattenLogFields(prefix string, obj map[string]interface{}, fields map[string]string) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenLogFields(key, val, fields)
+		case string:
+			fields[key] = val
+		case nil:
+		default:
+			data, err := json.Marshal(val)
+			if err == nil {
+				fields[key] = string(data)
+			}
+		}
+	}
+}
+
+//key=value pairs separated by spaces. Values with spaces are quoted
+func parseLogfmt(line string) map[string]string {
+	fields := make(map[string]string)
+	i := 0
+	n := len(line)
+	for i < n {
+		for i < n && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < n && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" && i >= n {
+			break
+		}
+		if i >= n || line[i] != '=' || key == "" {
+			//bare words are not logfmt
+			return nil
+		}
+		i++
+		value := ""
+		if i < n && line[i] == '"' {
+			i++
+			var sb strings.Builder
+			for i < n && line[i] != '"' {
+				if line[i] == '\\' && i+1 < n {
+					i++
+				}
+				sb.WriteByte(line[i])
+				i++
+			}
+			i++
+			value = sb.String()
+		} else {
+			start = i
+			for i < n && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+//removes the first present key from the fields and returns its value
+func takeLogField(fields map[string]string, keys []string) string {
+	for _, k := range keys {
+		for fk, v := range fields {
+			if strings.EqualFold(fk, k) {
+				delete(fields, fk)
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeLogLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	switch level {
+	case "WARNING":
+		return "WARN"
+	case "ERR":
+		return "ERROR"
+	case "CRITICAL", "CRIT", "FATAL", "PANIC":
+		return "FATAL"
+	}
+	return level
+}
+
+//custom fields must be valid analytics field names
+func IsValidLogFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(unicode.IsLetter(c) || c == '_' || i > 0 && unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+//key of the parsed log promoted to a typed field of the log schema
+type LogCustomField struct {
+	Key   string //key in the parsed log, e.g. http.status
+	Field string //schema field. Derived from the key if empty
+	Type  string //string, integer, float or boolean. Default is string
+}
+
+func (cf *LogCustomField) GetField() string {
+	if cf.Field != "" {
+		return cf.Field
+	}
+	field := strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' {
+			return c
+		}
+		return '_'
+	}, cf.Key)
+	if field != "" && unicode.IsDigit(rune(field[0])) {
+		field = "_" + field
+	}
+	return field
+}
+
+func (cf *LogCustomField) GetType() string {
+	switch strings.ToLower(cf.Type) {
+	case "integer", "float", "boolean":
+		return strings.ToLower(cf.Type)
+	}
+	return "string"
+}
+
+//returns false if the value cannot be converted to the type of the field
+func (cf *LogCustomField) Convert(value string) (interface{}, bool) {
+	switch cf.GetType() {
+	case "integer":
+		v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		return v, err == nil
+	case "float":
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return v, err == nil
+	case "boolean":
+		v, err := strconv.ParseBool(strings.TrimSpace(value))
+		return v, err == nil
+	}
+	return value, true
+}
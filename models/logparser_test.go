@@ -0,0 +1,103 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLogParserParse(t *testing.T) {
+	regexPattern := `^(?P<time>\S+) (?P<level>\w+) \[(?P<logger>[^\]]+)\] (?P<msg>.*)$`
+	cases := []struct {
+		name     string
+		format   string
+		pattern  string
+		line     string
+		ok       bool
+		expected ParsedLog
+	}{
+		{name: "json", format: LOG_FORMAT_JSON,
+			line: `{"level":"warning","msg":"disk low","logger":"app.disk","trace_id":"t1","span_id":"s1","http":{"status":503,"method":"GET"},"user":"bob","ctx":null}`,
+			ok:   true, expected: ParsedLog{Message: "disk low", Level: "WARN", Logger: "app.disk", TraceID: "t1", SpanID: "s1",
+				Fields: map[string]string{"http.status": "503", "http.method": "GET", "user": "bob"}}},
+		{name: "json without a message", format: LOG_FORMAT_JSON, line: `{"severity":"CRIT","status":"down"}`,
+			ok: true, expected: ParsedLog{Message: `{"severity":"CRIT","status":"down"}`, Level: "FATAL", Fields: map[string]string{"status": "down"}}},
+		{name: "logfmt", format: LOG_FORMAT_LOGFMT,
+			line: `level=err msg="connection refused" logger=db traceId=t2 retries=3 path="/a \"b\""`,
+			ok:   true, expected: ParsedLog{Message: "connection refused", Level: "ERROR", Logger: "db", TraceID: "t2",
+				Fields: map[string]string{"retries": "3", "path": `/a "b"`}}},
+		{name: "logfmt empty value", format: LOG_FORMAT_LOGFMT, line: `msg=started user=`,
+			ok: true, expected: ParsedLog{Message: "started", Fields: map[string]string{"user": ""}}},
+		{name: "regex", format: LOG_FORMAT_REGEX, pattern: regexPattern, line: "2020-01-02T03:04:05Z info [main] server started",
+			ok: true, expected: ParsedLog{Message: "server started", Level: "INFO", Logger: "main", Fields: map[string]string{"time": "2020-01-02T03:04:05Z"}}},
+		{name: "malformed json", format: LOG_FORMAT_JSON, line: `{"level":"info","msg":`,
+			expected: ParsedLog{Message: `{"level":"info","msg":`}},
+		{name: "plain text as json", format: LOG_FORMAT_JSON, line: "server started",
+			expected: ParsedLog{Message: "server started"}},
+		{name: "plain text as logfmt", format: LOG_FORMAT_LOGFMT, line: "server started on port=8080",
+			expected: ParsedLog{Message: "server started on port=8080"}},
+		{name: "empty logfmt line", format: LOG_FORMAT_LOGFMT, line: "",
+			expected: ParsedLog{Message: ""}},
+		{name: "regex mismatch", format: LOG_FORMAT_REGEX, pattern: regexPattern, line: "server started",
+			expected: ParsedLog{Message: "server started"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parser, err := NewLogParser(LogParserConfig{Name: c.name, Format: c.format, Pattern: c.pattern})
+			if err != nil {
+				t.Fatalf("Unable to create the parser. %v", err)
+			}
+			parsed, ok := parser.Parse(c.line)
+			if ok != c.ok {
+				t.Errorf("Expected ok %v, got %v", c.ok, ok)
+			}
+			if !reflect.DeepEqual(parsed, c.expected) {
+				t.Errorf("Expected %+v, got %+v", c.expected, parsed)
+			}
+		})
+	}
+}
+
+func TestNewLogParserErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  string
+		pattern string
+	}{
+		{name: "unsupported format", format: "xml"},
+		{name: "invalid pattern", format: LOG_FORMAT_REGEX, pattern: `(?P<level>\w+`},
+		{name: "no named groups", format: LOG_FORMAT_REGEX, pattern: `(\w+) (.*)`},
+	}
+	for _, c := range cases {
+		if _, err := NewLogParser(LogParserConfig{Name: c.name, Format: c.format, Pattern: c.pattern}); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+	if _, err := NewLogParser(LogParserConfig{Name: "upper case format", Format: "JSON"}); err != nil {
+		t.Errorf("Expected the format to be case insensitive. %v", err)
+	}
+}
+
+func TestLogCustomFieldConvert(t *testing.T) {
+	cases := []struct {
+		field    LogCustomField
+		value    string
+		name     string
+		expected interface{}
+		ok       bool
+	}{
+		{field: LogCustomField{Key: "http.status", Type: "integer"}, value: " 503", name: "http_status", expected: int64(503), ok: true},
+		{field: LogCustomField{Key: "duration", Field: "durationMs", Type: "Float"}, value: "1.5", name: "durationMs", expected: 1.5, ok: true},
+		{field: LogCustomField{Key: "cached", Type: "boolean"}, value: "true", name: "cached", expected: true, ok: true},
+		{field: LogCustomField{Key: "2xx", Type: "integer"}, value: "many", name: "_2xx", expected: int64(0), ok: false},
+		{field: LogCustomField{Key: "user.name"}, value: "bob", name: "user_name", expected: "bob", ok: true},
+	}
+	for _, c := range cases {
+		if name := c.field.GetField(); name != c.name {
+			t.Errorf("Key %s: expected field %s, got %s", c.field.Key, c.name, name)
+		}
+		value, ok := c.field.Convert(c.value)
+		if ok != c.ok || value != c.expected {
+			t.Errorf("Key %s: expected %v (%v), got %v (%v)", c.field.Key, c.expected, c.ok, value, ok)
+		}
+	}
+}
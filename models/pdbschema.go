@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/fatih/structs"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DRAIN_RISK_NO_PDB_SINGLE_REPLICA string = "NoPdbSingleReplica"
+	DRAIN_RISK_ZERO_DISRUPTIONS      string = "ZeroDisruptionsAllowed"
+)
+
+type PdbSchemaDefWrapper struct {
+	Schema PdbSchemaDef `json:"schema"`
+}
+
+func (sd PdbSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type PdbSchemaDef struct {
+	ClusterName        string `json:"clusterName"`
+	Name               string `json:"name"`
+	Namespace          string `json:"namespace"`
+	ObjectUid          string `json:"objectUid"`
+	CreationTimestamp  string `json:"creationTimestamp"`
+	Selector           string `json:"selector"`
+	MinAvailable       string `json:"minAvailable"`
+	MaxUnavailable     string `json:"maxUnavailable"`
+	CurrentHealthy     string `json:"currentHealthy"`
+	DesiredHealthy     string `json:"desiredHealthy"`
+	ExpectedPods       string `json:"expectedPods"`
+	DisruptionsAllowed string `json:"disruptionsAllowed"`
+	BlocksDrain        string `json:"blocksDrain"`
+}
+
+func NewPdbSchemaDefWrapper() PdbSchemaDefWrapper {
+	schema := NewPdbSchemaDef()
+	wrapper := PdbSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewPdbSchemaDef() PdbSchemaDef {
+	pdsd := PdbSchemaDef{ClusterName: "string", Name: "string", Namespace: "string", ObjectUid: "string", CreationTimestamp: "date",
+		Selector: "string", MinAvailable: "string", MaxUnavailable: "string", CurrentHealthy: "integer", DesiredHealthy: "integer",
+		ExpectedPods: "integer", DisruptionsAllowed: "integer", BlocksDrain: "boolean"}
+	return pdsd
+}
+
+func (sd PdbSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type PdbSchema struct {
+	ClusterName        string    `json:"clusterName"`
+	Name               string    `json:"name"`
+	Namespace          string    `json:"namespace"`
+	ObjectUid          string    `json:"objectUid"`
+	CreationTimestamp  time.Time `json:"creationTimestamp"`
+	Selector           string    `json:"selector"`
+	MinAvailable       string    `json:"minAvailable"`
+	MaxUnavailable     string    `json:"maxUnavailable"`
+	CurrentHealthy     int32     `json:"currentHealthy"`
+	DesiredHealthy     int32     `json:"desiredHealthy"`
+	ExpectedPods       int32     `json:"expectedPods"`
+	DisruptionsAllowed int32     `json:"disruptionsAllowed"`
+	BlocksDrain        bool      `json:"blocksDrain"`
+}
+
+func NewPdbSchema(pdb *policyv1beta1.PodDisruptionBudget) PdbSchema {
+	schema := PdbSchema{Name: pdb.Name, Namespace: pdb.Namespace, ObjectUid: string(pdb.GetUID()),
+		CreationTimestamp: pdb.CreationTimestamp.Time, CurrentHealthy: pdb.Status.CurrentHealthy,
+		DesiredHealthy: pdb.Status.DesiredHealthy, ExpectedPods: pdb.Status.ExpectedPods,
+		DisruptionsAllowed: pdb.Status.PodDisruptionsAllowed}
+	if pdb.Spec.Selector != nil {
+		schema.Selector = metav1.FormatLabelSelector(pdb.Spec.Selector)
+	}
+	if pdb.Spec.MinAvailable != nil {
+		schema.MinAvailable = pdb.Spec.MinAvailable.String()
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		schema.MaxUnavailable = pdb.Spec.MaxUnavailable.String()
+	}
+	schema.BlocksDrain = PdbBlocksDrain(pdb)
+	return schema
+}
+
+//the budget protects running pods, but no voluntary eviction is allowed at the moment
+func PdbBlocksDrain(pdb *policyv1beta1.PodDisruptionBudget) bool {
+	return pdb.Status.ExpectedPods > 0 && pdb.Status.PodDisruptionsAllowed == 0
+}
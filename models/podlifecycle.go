@@ -0,0 +1,130 @@
+package models
+
+import (
+	"time"
+)
+
+/*
+ Timestamps of the startup stages of a pod. Each timestamp is recorded once, when first observed.
+ Zero values are unknown, e.g. when the image pull events were not received
+*/
+type PodLifecycle struct {
+	Created         time.Time
+	Scheduled       time.Time
+	Pulling         time.Time //first image pull started before the containers started
+	Pulled          time.Time //last image pulled before the containers started
+	Initialized     time.Time //init containers completed
+	Started         time.Time //all containers started
+	ContainersReady time.Time
+	Ready           time.Time
+}
+
+//stages of the pod startup
+const (
+	LATENCY_SCHEDULE        string = "Schedule"
+	LATENCY_IMAGE_PULL      string = "ImagePull"
+	LATENCY_CONTAINER_START string = "ContainerStart"
+	LATENCY_READY           string = "Ready"
+	LATENCY_STARTUP         string = "Startup"
+)
+
+func getLatency(from time.Time, to time.Time) (int64, bool) {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0, false
+	}
+	return to.Sub(from).Nanoseconds() / 1000000, true
+}
+
+//stage -> duration in milliseconds. Stages with unknown boundaries are omitted
+func (pl *PodLifecycle) GetLatencies() map[string]int64 {
+	latencies := make(map[string]int64)
+	if l, ok := getLatency(pl.Created, pl.Scheduled); ok {
+		latencies[LATENCY_SCHEDULE] = l
+	}
+	pullStart := pl.Pulling
+	if pullStart.IsZero() {
+		pullStart = pl.Scheduled
+	}
+	if l, ok := getLatency(pullStart, pl.Pulled); ok {
+		latencies[LATENCY_IMAGE_PULL] = l
+	}
+	//the images of the app containers are pulled after the init containers complete
+	startFrom := pl.Pulled
+	if pl.Initialized.After(startFrom) {
+		startFrom = pl.Initialized
+	}
+	if l, ok := getLatency(startFrom, pl.Started); ok {
+		latencies[LATENCY_CONTAINER_START] = l
+	}
+	//readiness gates may hold the Ready condition after the containers are ready
+	readyAt := pl.ContainersReady
+	if readyAt.IsZero() {
+		readyAt = pl.Ready
+	}
+	if l, ok := getLatency(pl.Started, readyAt); ok {
+		latencies[LATENCY_READY] = l
+	}
+	if l, ok := getLatency(pl.Created, pl.Ready); ok {
+		latencies[LATENCY_STARTUP] = l
+	}
+	return latencies
+}
+
+//fills the latency fields of the pod record
+func (ps *PodSchema) SetLifecycle(pl *PodLifecycle) {
+	ps.Latencies = pl.GetLatencies()
+	ps.ScheduleLatency = ps.Latencies[LATENCY_SCHEDULE]
+	ps.ImagePullLatency = ps.Latencies[LATENCY_IMAGE_PULL]
+	ps.ContainerStartLatency = ps.Latencies[LATENCY_CONTAINER_START]
+	ps.ReadyLatency = ps.Latencies[LATENCY_READY]
+	ps.StartupLatency = ps.Latencies[LATENCY_STARTUP]
+}
+
+type latencyStats struct {
+	Count int64
+	Total int64
+}
+
+//adds the latencies of the pod to the tier averages and max
+func (cpm *ClusterAppMetrics) AddLatencies(podObject *PodSchema) {
+	if cpm.latencies == nil {
+		cpm.latencies = make(map[string]*latencyStats)
+	}
+	for stage, l := range podObject.Latencies {
+		stats, ok := cpm.latencies[stage]
+		if !ok {
+			stats = &latencyStats{}
+			cpm.latencies[stage] = stats
+		}
+		stats.Count++
+		stats.Total += l
+		avg := stats.Total / stats.Count
+		switch stage {
+		case LATENCY_SCHEDULE:
+			cpm.ScheduleLatencyAvg = avg
+			if l > cpm.ScheduleLatencyMax {
+				cpm.ScheduleLatencyMax = l
+			}
+		case LATENCY_IMAGE_PULL:
+			cpm.ImagePullLatencyAvg = avg
+			if l > cpm.ImagePullLatencyMax {
+				cpm.ImagePullLatencyMax = l
+			}
+		case LATENCY_CONTAINER_START:
+			cpm.ContainerStartLatencyAvg = avg
+			if l > cpm.ContainerStartLatencyMax {
+				cpm.ContainerStartLatencyMax = l
+			}
+		case LATENCY_READY:
+			cpm.ReadyLatencyAvg = avg
+			if l > cpm.ReadyLatencyMax {
+				cpm.ReadyLatencyMax = l
+			}
+		case LATENCY_STARTUP:
+			cpm.StartupLatencyAvg = avg
+			if l > cpm.StartupLatencyMax {
+				cpm.StartupLatencyMax = l
+			}
+		}
+	}
+}
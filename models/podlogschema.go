@@ -1,13 +1,42 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"time"
+
+	"github.com/fatih/structs"
 )
 
 type LogSchemaDefWrapper struct {
-	Schema LogSchemaDef `json:"schema"`
+	Schema       LogSchemaDef      `json:"schema"`
+	CustomFields map[string]string `json:"-" structs:"-"` //field -> type of the configured custom fields
+}
+
+//the schema includes the configured custom fields
+func (sd LogSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	schema := structs.Map(sd.Schema)
+	for field, fieldType := range sd.CustomFields {
+		schema[field] = fieldType
+	}
+	objMap := map[string]interface{}{"Schema": schema}
+	return &objMap
+}
+
+func (sd LogSchemaDefWrapper) MarshalJSON() ([]byte, error) {
+	schema := map[string]interface{}{}
+	data, err := json.Marshal(sd.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	for field, fieldType := range sd.CustomFields {
+		schema[field] = fieldType
+	}
+	return json.Marshal(map[string]interface{}{"schema": schema})
 }
 
 type LogSchemaDef struct {
@@ -17,31 +46,92 @@ type LogSchemaDef struct {
 	PodName        string `json:"pod"`
 	ContainerName  string `json:"container"`
 	Message        string `json:"message"`
+	Level          string `json:"level"`
+	Logger         string `json:"logger"`
+	TraceID        string `json:"traceId"`
+	SpanID         string `json:"spanId"`
+	Format         string `json:"format"`
 	BatchTimestamp string `json:"batchTimestamp"`
 	Timestamp      string `json:"timestamp"`
 }
 
-func NewLogSchemaDefWrapper() LogSchemaDefWrapper {
+func NewLogSchemaDefWrapper(customFields []LogCustomField) LogSchemaDefWrapper {
 	schema := NewLogSchemaDef()
-	wrapper := LogSchemaDefWrapper{Schema: schema}
+	wrapper := LogSchemaDefWrapper{Schema: schema, CustomFields: make(map[string]string)}
+	for _, cf := range customFields {
+		if field := cf.GetField(); IsValidLogFieldName(field) {
+			wrapper.CustomFields[field] = cf.GetType()
+		}
+	}
 	return wrapper
 }
 
 func NewLogSchemaDef() LogSchemaDef {
 	pdsd := LogSchemaDef{ClusterName: "string", Namespace: "string", PodOwner: "string", PodName: "string", ContainerName: "string", Message: "string",
-		BatchTimestamp: "integer", Timestamp: "string"}
+		Level: "string", Logger: "string", TraceID: "string", SpanID: "string", Format: "string", BatchTimestamp: "integer", Timestamp: "string"}
 	return pdsd
 }
 
 type LogSchema struct {
-	ClusterName    string     `json:"clusterName"`
-	Namespace      string     `json:"namespace"`
-	PodOwner       string     `json:"podOwner"`
-	PodName        string     `json:"pod"`
-	ContainerName  string     `json:"container"`
-	Message        string     `json:"message"`
-	BatchTimestamp int64      `json:"batchTimestamp"`
-	Timestamp      *time.Time `json:"timestamp"`
+	ClusterName    string                 `json:"clusterName"`
+	Namespace      string                 `json:"namespace"`
+	PodOwner       string                 `json:"podOwner"`
+	PodName        string                 `json:"pod"`
+	ContainerName  string                 `json:"container"`
+	Message        string                 `json:"message"`
+	Level          string                 `json:"level"`
+	Logger         string                 `json:"logger"`
+	TraceID        string                 `json:"traceId"`
+	SpanID         string                 `json:"spanId"`
+	Format         string                 `json:"format"`
+	BatchTimestamp int64                  `json:"batchTimestamp"`
+	Timestamp      *time.Time             `json:"timestamp"`
+	Custom         map[string]interface{} `json:"-"` //values of the configured custom fields
+}
+
+//custom fields are serialized as top level fields of the record
+func (ls LogSchema) MarshalJSON() ([]byte, error) {
+	type logRecord LogSchema
+	data, err := json.Marshal(logRecord(ls))
+	if err != nil || len(ls.Custom) == 0 {
+		return data, err
+	}
+	record := map[string]interface{}{}
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	for field, val := range ls.Custom {
+		if _, exists := record[field]; !exists {
+			record[field] = val
+		}
+	}
+	return json.Marshal(record)
+}
+
+//fills the typed fields from the parsed log. Values that do not convert to the type of the field are skipped
+func (ls *LogSchema) ApplyParsed(parsed *ParsedLog, format string, customFields []LogCustomField) {
+	ls.Message = parsed.Message
+	ls.Level = parsed.Level
+	ls.Logger = parsed.Logger
+	ls.TraceID = parsed.TraceID
+	ls.SpanID = parsed.SpanID
+	ls.Format = format
+	for _, cf := range customFields {
+		field := cf.GetField()
+		if !IsValidLogFieldName(field) {
+			continue
+		}
+		raw, ok := parsed.Fields[cf.Key]
+		if !ok {
+			continue
+		}
+		if val, ok := cf.Convert(raw); ok {
+			if ls.Custom == nil {
+				ls.Custom = make(map[string]interface{})
+			}
+			ls.Custom[field] = val
+		}
+	}
 }
 
 type LogObjList struct {
@@ -25,6 +25,9 @@ type PodSchemaDef struct {
 	Priority                      string `json:"priority"`
 	RestartPolicy                 string `json:"restartPolicy"`
 	ServiceAccountName            string `json:"serviceAccountName"`
+	PdbName                       string `json:"pdbName"`
+	OwnerKind                     string `json:"ownerKind"`
+	OwnerName                     string `json:"ownerName"`
 	TerminationGracePeriodSeconds string `json:"terminationGracePeriodSeconds"`
 	Tolerations                   string `json:"tolerations"`
 	NodeAffinityPreferred         string `json:"nodeAffinityPreferred"`
@@ -63,6 +66,26 @@ type PodSchemaDef struct {
 	TermReasons                   string `json:"termReasons"`
 	RunningStartTime              string `json:"runningStartTime"`
 	TerminationTime               string `json:"terminationTime"`
+	SchedulingFailure             string `json:"schedulingFailure"`
+	SchedulingCause               string `json:"schedulingCause"`
+	SchedTotalNodes               string `json:"schedTotalNodes"`
+	SchedInsufficientCpu          string `json:"schedInsufficientCpu"`
+	SchedInsufficientMemory       string `json:"schedInsufficientMemory"`
+	SchedInsufficientResources    string `json:"schedInsufficientResources"`
+	SchedNodeUnschedulable        string `json:"schedNodeUnschedulable"`
+	SchedTaints                   string `json:"schedTaints"`
+	SchedNodeAffinity             string `json:"schedNodeAffinity"`
+	SchedPodAffinity              string `json:"schedPodAffinity"`
+	SchedVolumeAffinity           string `json:"schedVolumeAffinity"`
+	SchedVolumeBinding            string `json:"schedVolumeBinding"`
+	SchedHostPorts                string `json:"schedHostPorts"`
+	SchedTopologySpread           string `json:"schedTopologySpread"`
+	SchedOther                    string `json:"schedOther"`
+	ScheduleLatency               string `json:"scheduleLatency"`
+	ImagePullLatency              string `json:"imagePullLatency"`
+	ContainerStartLatency         string `json:"containerStartLatency"`
+	ReadyLatency                  string `json:"readyLatency"`
+	StartupLatency                string `json:"startupLatency"`
 }
 
 func NewPodSchemaDefWrapper() PodSchemaDefWrapper {
@@ -78,14 +101,18 @@ func (sd PodSchemaDefWrapper) Unwrap() *map[string]interface{} {
 
 func NewPodSchemaDef() PodSchemaDef {
 	pdsd := PodSchemaDef{Name: "string", Namespace: "string", ClusterName: "string", Labels: "string", Annotations: "string", ContainerCount: "integer",
-		InitContainerCount: "integer", NodeName: "string", Priority: "integer", RestartPolicy: "string", ServiceAccountName: "string", TerminationGracePeriodSeconds: "integer",
+		InitContainerCount: "integer", NodeName: "string", Priority: "integer", RestartPolicy: "string", ServiceAccountName: "string", PdbName: "string", OwnerKind: "string", OwnerName: "string", TerminationGracePeriodSeconds: "integer",
 		Tolerations: "string", NodeAffinityPreferred: "string", NodeAffinityRequired: "string", PodAffinityPreferred: "string", PodAffinityRequired: "string",
 		PodAntiAffinityPreferred: "string", PodAntiAffinityRequired: "string",
 		HostIP: "string", Phase: "string", PodIP: "string", Reason: "string", StartTime: "date", LastTransitionTimeCondition: "date", ReasonCondition: "string",
 		StatusCondition: "string", TypeCondition: "string", LimitsDefined: "boolean", LiveProbes: "integer", ReadyProbes: "integer", PodRestarts: "integer",
 		NumPrivileged: "integer", Ports: "string", MemRequest: "float", CpuRequest: "float", CpuLimit: "float", MemLimit: "float",
 		PodStorageRequest: "float", PodStorageLimit: "float", StorageRequest: "float", StorageCapacity: "float", CpuUse: "float", MemUse: "float",
-		Images: "string", WaitReasons: "string", TermReasons: "string", RunningStartTime: "date", TerminationTime: "date"}
+		Images: "string", WaitReasons: "string", TermReasons: "string", RunningStartTime: "date", TerminationTime: "date",
+		SchedulingFailure: "string", SchedulingCause: "string", SchedTotalNodes: "integer", SchedInsufficientCpu: "integer", SchedInsufficientMemory: "integer",
+		SchedInsufficientResources: "integer", SchedNodeUnschedulable: "integer", SchedTaints: "integer", SchedNodeAffinity: "integer", SchedPodAffinity: "integer",
+		SchedVolumeAffinity: "integer", SchedVolumeBinding: "integer", SchedHostPorts: "integer", SchedTopologySpread: "integer", SchedOther: "integer",
+		ScheduleLatency: "integer", ImagePullLatency: "integer", ContainerStartLatency: "integer", ReadyLatency: "integer", StartupLatency: "integer"}
 	return pdsd
 }
 
@@ -106,6 +133,9 @@ type PodSchema struct {
 	Priority                      int32                      `json:"priority"`
 	RestartPolicy                 string                     `json:"restartPolicy"`
 	ServiceAccountName            string                     `json:"serviceAccountName"`
+	PdbName                       string                     `json:"pdbName"`
+	OwnerKind                     string                     `json:"ownerKind"`
+	OwnerName                     string                     `json:"ownerName"`
 	TerminationGracePeriodSeconds int64                      `json:"terminationGracePeriodSeconds"`
 	Tolerations                   string                     `json:"tolerations"`
 	NodeAffinityPreferred         string                     `json:"nodeAffinityPreferred"`
@@ -144,6 +174,28 @@ type PodSchema struct {
 	TermReasons                   string                     `json:"termReasons"`
 	RunningStartTime              *time.Time                 `json:"runningStartTime"`
 	TerminationTime               *time.Time                 `json:"terminationTime"`
+	SchedulingFailure             string                     `json:"schedulingFailure"`
+	SchedulingCause               string                     `json:"schedulingCause"`
+	SchedTotalNodes               int                        `json:"schedTotalNodes"`
+	SchedInsufficientCpu          int                        `json:"schedInsufficientCpu"`
+	SchedInsufficientMemory       int                        `json:"schedInsufficientMemory"`
+	SchedInsufficientResources    int                        `json:"schedInsufficientResources"`
+	SchedNodeUnschedulable        int                        `json:"schedNodeUnschedulable"`
+	SchedTaints                   int                        `json:"schedTaints"`
+	SchedNodeAffinity             int                        `json:"schedNodeAffinity"`
+	SchedPodAffinity              int                        `json:"schedPodAffinity"`
+	SchedVolumeAffinity           int                        `json:"schedVolumeAffinity"`
+	SchedVolumeBinding            int                        `json:"schedVolumeBinding"`
+	SchedHostPorts                int                        `json:"schedHostPorts"`
+	SchedTopologySpread           int                        `json:"schedTopologySpread"`
+	SchedOther                    int                        `json:"schedOther"`
+	SchedulingFailures            map[string]int             `json:"-"` //predicate -> rejected nodes
+	ScheduleLatency               int64                      `json:"scheduleLatency"`
+	ImagePullLatency              int64                      `json:"imagePullLatency"`
+	ContainerStartLatency         int64                      `json:"containerStartLatency"`
+	ReadyLatency                  int64                      `json:"readyLatency"`
+	StartupLatency                int64                      `json:"startupLatency"`
+	Latencies                     map[string]int64           `json:"-"` //startup stage -> ms
 	PendingTime                   int64                      `json:"-"`
 	Containers                    map[string]ContainerSchema `json:"-"`
 	InitContainers                map[string]ContainerSchema `json:"-"`
@@ -163,6 +215,7 @@ type PodSchema struct {
 	UpTimeMillis                  int64                      `json:"-"`
 	BreakPointMillis              int64                      `json:"-"` //time when a container exited
 	MissingDependencies           bool                       `json:"-"`
+	StaleConfig                   bool                       `json:"-"`
 	NoConnectivity                bool                       `json:"-"`
 	ConsumptionCpu                float64                    `json:"-"`
 	ConsumptionMem                float64                    `json:"-"`
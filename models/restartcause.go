@@ -0,0 +1,27 @@
+package models
+
+const (
+	RESTART_CAUSE_OOM_KILLED     string = "OOMKilled"
+	RESTART_CAUSE_LIVENESS_PROBE string = "LivenessProbeFailure"
+	RESTART_CAUSE_NON_ZERO_EXIT  string = "NonZeroExit"
+	RESTART_CAUSE_EVICTED        string = "Evicted"
+	RESTART_CAUSE_NODE_SHUTDOWN  string = "NodeShutdown"
+	RESTART_CAUSE_COMPLETED      string = "Completed"
+)
+
+func (cpm *ClusterAppMetrics) AddRestarts(cause string, count int64) {
+	switch cause {
+	case RESTART_CAUSE_OOM_KILLED:
+		cpm.RestartsOOMKilled += count
+	case RESTART_CAUSE_LIVENESS_PROBE:
+		cpm.RestartsLivenessProbe += count
+	case RESTART_CAUSE_NON_ZERO_EXIT:
+		cpm.RestartsNonZeroExit += count
+	case RESTART_CAUSE_EVICTED:
+		cpm.RestartsEvicted += count
+	case RESTART_CAUSE_NODE_SHUTDOWN:
+		cpm.RestartsNodeShutdown += count
+	default:
+		cpm.RestartsOther += count
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	ROLLOUT_IN_PROGRESS string = "InProgress"
+	ROLLOUT_COMPLETE    string = "Complete"
+	ROLLOUT_STALLED     string = "Stalled"
+	ROLLOUT_SUPERSEDED  string = "Superseded"
+	ROLLOUT_DELETED     string = "Deleted"
+)
+
+type RolloutSchemaDefWrapper struct {
+	Schema RolloutSchemaDef `json:"schema"`
+}
+
+func (sd RolloutSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type RolloutSchemaDef struct {
+	Name           string `json:"name"`
+	ClusterName    string `json:"clusterName"`
+	Namespace      string `json:"namespace"`
+	ObjectUid      string `json:"objectUid"`
+	AppName        string `json:"appName"`
+	OldRevision    string `json:"oldRevision"`
+	NewRevision    string `json:"newRevision"`
+	OldImages      string `json:"oldImages"`
+	NewImages      string `json:"newImages"`
+	ChangedImages  string `json:"changedImages"`
+	StartTime      string `json:"startTime"`
+	EndTime        string `json:"endTime"`
+	Duration       string `json:"duration"`
+	Outcome        string `json:"outcome"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Replicas       string `json:"replicas"`
+	MaxSurged      string `json:"maxSurged"`
+	MaxUnavailable string `json:"maxUnavailable"`
+}
+
+func NewRolloutSchemaDefWrapper() RolloutSchemaDefWrapper {
+	schema := NewRolloutSchemaDef()
+	wrapper := RolloutSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewRolloutSchemaDef() RolloutSchemaDef {
+	pdsd := RolloutSchemaDef{Name: "string", ClusterName: "string", Namespace: "string", ObjectUid: "string", AppName: "string",
+		OldRevision: "integer", NewRevision: "integer", OldImages: "string", NewImages: "string", ChangedImages: "string",
+		StartTime: "date", EndTime: "date", Duration: "integer", Outcome: "string", Reason: "string", Message: "string",
+		Replicas: "integer", MaxSurged: "integer", MaxUnavailable: "integer"}
+	return pdsd
+}
+
+func (sd RolloutSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type RolloutSchema struct {
+	Name           string    `json:"name"`
+	ClusterName    string    `json:"clusterName"`
+	Namespace      string    `json:"namespace"`
+	ObjectUid      string    `json:"objectUid"`
+	AppName        string    `json:"appName"`
+	OldRevision    int64     `json:"oldRevision"`
+	NewRevision    int64     `json:"newRevision"`
+	OldImages      string    `json:"oldImages"`
+	NewImages      string    `json:"newImages"`
+	ChangedImages  string    `json:"changedImages"`
+	StartTime      time.Time `json:"startTime"`
+	EndTime        time.Time `json:"endTime"`
+	Duration       int64     `json:"duration"` //seconds
+	Outcome        string    `json:"outcome"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Replicas       int32     `json:"replicas"`
+	MaxSurged      int32     `json:"maxSurged"`
+	MaxUnavailable int32     `json:"maxUnavailable"`
+}
+
+func NewRolloutObj() RolloutSchema {
+	return RolloutSchema{OldRevision: 0, NewRevision: 0, Duration: 0, Replicas: 0, MaxSurged: 0, MaxUnavailable: 0,
+		Outcome: ROLLOUT_IN_PROGRESS}
+}
+
+func (rs *RolloutSchema) Finish(outcome string, reason string, message string) {
+	rs.Outcome = outcome
+	rs.Reason = reason
+	rs.Message = message
+	rs.EndTime = time.Now()
+	rs.Duration = int64(rs.EndTime.Sub(rs.StartTime).Seconds())
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//scheduling predicates that rejected the nodes of the cluster
+const (
+	SCHED_INSUFFICIENT_CPU       string = "InsufficientCpu"
+	SCHED_INSUFFICIENT_MEMORY    string = "InsufficientMemory"
+	SCHED_INSUFFICIENT_RESOURCES string = "InsufficientResources" //other resources, e.g. pods, ephemeral-storage, gpus
+	SCHED_NODE_UNSCHEDULABLE     string = "NodeUnschedulable"     //cordoned nodes
+	SCHED_TAINTS                 string = "Taints"
+	SCHED_NODE_AFFINITY          string = "NodeAffinity"
+	SCHED_POD_AFFINITY           string = "PodAffinity"
+	SCHED_VOLUME_AFFINITY        string = "VolumeNodeAffinity"
+	SCHED_VOLUME_BINDING         string = "VolumeBinding"
+	SCHED_HOST_PORTS             string = "HostPorts"
+	SCHED_TOPOLOGY_SPREAD        string = "TopologySpread"
+	SCHED_OTHER                  string = "Other"
+
+	SCHED_CAUSE_CAPACITY string = "capacity"
+	SCHED_CAUSE_SPEC     string = "spec"
+)
+
+//predicates that are resolved by adding capacity to the cluster. The rest point to the pod spec
+var schedulingCapacityPredicates = []string{SCHED_INSUFFICIENT_CPU, SCHED_INSUFFICIENT_MEMORY, SCHED_INSUFFICIENT_RESOURCES, SCHED_NODE_UNSCHEDULABLE}
+
+//rejected nodes per predicate, parsed from the message of the scheduler
+type SchedulingFailure struct {
+	TotalNodes int
+	Predicates map[string]int //predicate -> number of nodes
+}
+
+func NewSchedulingFailure() SchedulingFailure {
+	return SchedulingFailure{Predicates: make(map[string]int)}
+}
+
+func IsCapacityPredicate(predicate string) bool {
+	return containsString(schedulingCapacityPredicates, predicate)
+}
+
+//capacity if any of the predicates can be resolved by adding capacity, spec otherwise
+func (sf *SchedulingFailure) GetCause() string {
+	if len(sf.Predicates) == 0 {
+		return ""
+	}
+	for p := range sf.Predicates {
+		if IsCapacityPredicate(p) {
+			return SCHED_CAUSE_CAPACITY
+		}
+	}
+	for p := range sf.Predicates {
+		if p != SCHED_OTHER {
+			return SCHED_CAUSE_SPEC
+		}
+	}
+	return ""
+}
+
+func (sf *SchedulingFailure) Format() string {
+	list := []string{}
+	for p, count := range sf.Predicates {
+		list = append(list, fmt.Sprintf("%s: %d", p, count))
+	}
+	sort.Strings(list)
+	return strings.Join(list, "; ")
+}
+
+//fills the scheduling fields of the pod record
+func (ps *PodSchema) SetSchedulingFailure(sf *SchedulingFailure) {
+	ps.SchedulingFailures = sf.Predicates
+	ps.SchedulingFailure = sf.Format()
+	ps.SchedulingCause = sf.GetCause()
+	ps.SchedTotalNodes = sf.TotalNodes
+	ps.SchedInsufficientCpu = sf.Predicates[SCHED_INSUFFICIENT_CPU]
+	ps.SchedInsufficientMemory = sf.Predicates[SCHED_INSUFFICIENT_MEMORY]
+	ps.SchedInsufficientResources = sf.Predicates[SCHED_INSUFFICIENT_RESOURCES]
+	ps.SchedNodeUnschedulable = sf.Predicates[SCHED_NODE_UNSCHEDULABLE]
+	ps.SchedTaints = sf.Predicates[SCHED_TAINTS]
+	ps.SchedNodeAffinity = sf.Predicates[SCHED_NODE_AFFINITY]
+	ps.SchedPodAffinity = sf.Predicates[SCHED_POD_AFFINITY]
+	ps.SchedVolumeAffinity = sf.Predicates[SCHED_VOLUME_AFFINITY]
+	ps.SchedVolumeBinding = sf.Predicates[SCHED_VOLUME_BINDING]
+	ps.SchedHostPorts = sf.Predicates[SCHED_HOST_PORTS]
+	ps.SchedTopologySpread = sf.Predicates[SCHED_TOPOLOGY_SPREAD]
+	ps.SchedOther = sf.Predicates[SCHED_OTHER]
+}
+
+//counts the pending pod under every predicate that rejected nodes for it
+func (cpm *ClusterPodMetrics) AddSchedulingFailure(podObject *PodSchema) {
+	if len(podObject.SchedulingFailures) == 0 {
+		return
+	}
+	for p := range podObject.SchedulingFailures {
+		switch p {
+		case SCHED_INSUFFICIENT_CPU:
+			cpm.UnschedulableInsufficientCpu++
+		case SCHED_INSUFFICIENT_MEMORY:
+			cpm.UnschedulableInsufficientMemory++
+		case SCHED_INSUFFICIENT_RESOURCES:
+			cpm.UnschedulableInsufficientResources++
+		case SCHED_NODE_UNSCHEDULABLE:
+			cpm.UnschedulableNodeUnschedulable++
+		case SCHED_TAINTS:
+			cpm.UnschedulableTaints++
+		case SCHED_NODE_AFFINITY:
+			cpm.UnschedulableNodeAffinity++
+		case SCHED_POD_AFFINITY:
+			cpm.UnschedulablePodAffinity++
+		case SCHED_VOLUME_AFFINITY:
+			cpm.UnschedulableVolumeAffinity++
+		case SCHED_VOLUME_BINDING:
+			cpm.UnschedulableVolumeBinding++
+		case SCHED_HOST_PORTS:
+			cpm.UnschedulableHostPorts++
+		case SCHED_TOPOLOGY_SPREAD:
+			cpm.UnschedulableTopologySpread++
+		default:
+			cpm.UnschedulableOther++
+		}
+	}
+	switch podObject.SchedulingCause {
+	case SCHED_CAUSE_CAPACITY:
+		cpm.UnschedulableCapacity++
+	case SCHED_CAUSE_SPEC:
+		cpm.UnschedulableSpec++
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+const defaultRegistry string = "docker.io"
+
+var imagePullImageRegex = regexp.MustCompile(`[Ii]mage "([^"]+)"`)
+var imagePullDurationRegex = regexp.MustCompile(`\bin ((?:[0-9.]+(?:ns|us|µs|ms|s|m|h))+)`)
+var imagePullSizeRegex = regexp.MustCompile(`Image size: (\d+) bytes`)
+
+/*
+ Phrases of the registry and runtime errors, in order of evaluation. The patterns match the lower-cased message.
+ Status codes are only matched where the registry client reports them, e.g. "unexpected status: 401" or "429 too many requests",
+ so that digests, sizes or ports that contain the digits are not classified
+*/
+var imagePullErrorMessages = []struct {
+	Pattern    *regexp.Regexp
+	ErrorClass string
+}{
+	{regexp.MustCompile(`toomanyrequests|too many requests|rate limit`), m.IMAGE_PULL_ERROR_RATE_LIMIT},
+	{regexp.MustCompile(`\bstatus(?: code)?:? 429\b`), m.IMAGE_PULL_ERROR_RATE_LIMIT},
+	{regexp.MustCompile(`manifest unknown|name unknown|repository does not exist|not found`), m.IMAGE_PULL_ERROR_NOT_FOUND},
+	{regexp.MustCompile(`\bstatus(?: code)?:? 404\b`), m.IMAGE_PULL_ERROR_NOT_FOUND},
+	{regexp.MustCompile(`unauthorized|authentication required|no basic auth credentials|access denied|forbidden`), m.IMAGE_PULL_ERROR_AUTH},
+	{regexp.MustCompile(`\bstatus(?: code)?:? 40[13]\b`), m.IMAGE_PULL_ERROR_AUTH},
+	{regexp.MustCompile(`timeout|timed out|deadline exceeded`), m.IMAGE_PULL_ERROR_TIMEOUT},
+}
+
+/*
+ Splits an image reference into registry, repository, tag and digest.
+ Images without a registry host are pulled from Docker Hub, e.g. "nginx" is docker.io/library/nginx:latest
+*/
+func ParseImageReference(image string) m.ImageReference {
+	ref := m.ImageReference{Registry: defaultRegistry, ReferenceType: m.IMAGE_REF_TAG}
+	name := strings.TrimSpace(image)
+	if idx := strings.Index(name, "@"); idx >= 0 {
+		ref.Digest = name[idx+1:]
+		ref.ReferenceType = m.IMAGE_REF_DIGEST
+		name = name[:idx]
+	}
+	//the tag follows the last colon after the last slash. Colons before it separate the port of the registry
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		ref.Tag = name[idx+1:]
+		name = name[:idx]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		name = parts[1]
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	return ref
+}
+
+//image of the Pulling, Pulled and Failed events of the kubelet. Empty if the message does not name one
+func GetImageFromPullMessage(message string) string {
+	match := imagePullImageRegex.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+/*
+ Pull duration in milliseconds and image size in bytes reported in the Pulled event, e.g.
+ Successfully pulled image "nginx:1.25" in 2.345s (2.345s including waiting). Image size: 70520801 bytes.
+ The values are 0 if the kubelet does not report them
+*/
+func ParseImagePullMessage(message string) (int64, int64) {
+	var duration int64 = 0
+	var size int64 = 0
+	if match := imagePullDurationRegex.FindStringSubmatch(message); match != nil {
+		if d, err := time.ParseDuration(match[1]); err == nil {
+			duration = d.Nanoseconds() / 1000000
+		}
+	}
+	if match := imagePullSizeRegex.FindStringSubmatch(message); match != nil {
+		size, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	return duration, size
+}
+
+func ClassifyImagePullError(message string) string {
+	message = strings.ToLower(message)
+	for _, e := range imagePullErrorMessages {
+		if e.Pattern.MatchString(message) {
+			return e.ErrorClass
+		}
+	}
+	return m.IMAGE_PULL_ERROR_OTHER
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+func TestParseImageReference(t *testing.T) {
+	digest := "sha256:4c0f3b243397e4b7a1fd9e4ea2c8e1d3f1b0f6cfb7a83ab1c1d0f3b0e2a1c4d5"
+	cases := []struct {
+		image    string
+		expected m.ImageReference
+	}{
+		{image: "nginx", expected: m.ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "nginx:1.25", expected: m.ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "bitnami/redis:7.2", expected: m.ImageReference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "gcr.io/google-containers/pause:3.9", expected: m.ImageReference{Registry: "gcr.io", Repository: "google-containers/pause", Tag: "3.9", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "registry.example.com:5000/team/app:v1", expected: m.ImageReference{Registry: "registry.example.com:5000", Repository: "team/app", Tag: "v1", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "localhost/app", expected: m.ImageReference{Registry: "localhost", Repository: "app", Tag: "latest", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "localhost:5000/app", expected: m.ImageReference{Registry: "localhost:5000", Repository: "app", Tag: "latest", ReferenceType: m.IMAGE_REF_TAG}},
+		{image: "quay.io/prometheus/node-exporter@" + digest, expected: m.ImageReference{Registry: "quay.io", Repository: "prometheus/node-exporter", Digest: digest, ReferenceType: m.IMAGE_REF_DIGEST}},
+		{image: "nginx:1.25@" + digest, expected: m.ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Digest: digest, ReferenceType: m.IMAGE_REF_DIGEST}},
+	}
+	for _, c := range cases {
+		if ref := ParseImageReference(c.image); ref != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.image, c.expected, ref)
+		}
+	}
+}
+
+func TestParseImagePullMessage(t *testing.T) {
+	cases := []struct {
+		message  string
+		image    string
+		duration int64
+		size     int64
+	}{
+		{message: `Successfully pulled image "nginx:1.25" in 2.345s (2.345s including waiting). Image size: 70520801 bytes.`, image: "nginx:1.25", duration: 2345, size: 70520801},
+		{message: `Successfully pulled image "registry.example.com:5000/team/app:v1" in 1m2.5s`, image: "registry.example.com:5000/team/app:v1", duration: 62500},
+		{message: `Successfully pulled image "busybox" in 850ms (1.2s including waiting)`, image: "busybox", duration: 850},
+		{message: `Pulling image "nginx:1.25"`, image: "nginx:1.25"},
+		{message: `Container image "nginx:1.25" already present on machine`, image: "nginx:1.25"},
+		{message: `Back-off pulling image`, image: ""},
+	}
+	for _, c := range cases {
+		if image := GetImageFromPullMessage(c.message); image != c.image {
+			t.Errorf("%s: expected image %q, got %q", c.message, c.image, image)
+		}
+		duration, size := ParseImagePullMessage(c.message)
+		if duration != c.duration || size != c.size {
+			t.Errorf("%s: expected %dms and %d bytes, got %dms and %d bytes", c.message, c.duration, c.size, duration, size)
+		}
+	}
+}
+
+func TestClassifyImagePullError(t *testing.T) {
+	cases := []struct {
+		message  string
+		expected string
+	}{
+		{message: `Failed to pull image "nginx:1.25": rpc error: code = Unknown desc = toomanyrequests: You have reached your pull rate limit.`, expected: m.IMAGE_PULL_ERROR_RATE_LIMIT},
+		{message: `Failed to pull image "app:v1": unexpected status code: 429 Too Many Requests`, expected: m.IMAGE_PULL_ERROR_RATE_LIMIT},
+		{message: `Failed to pull image "app:v2": rpc error: code = NotFound desc = failed to resolve reference "docker.io/library/app:v2": not found`, expected: m.IMAGE_PULL_ERROR_NOT_FOUND},
+		{message: `Failed to pull image "team/app": manifest unknown: manifest unknown`, expected: m.IMAGE_PULL_ERROR_NOT_FOUND},
+		{message: `Failed to pull image "private/app": pull access denied, repository does not exist or may require 'docker login'`, expected: m.IMAGE_PULL_ERROR_NOT_FOUND},
+		{message: `Failed to pull image "registry.example.com/app": failed to authorize: failed to fetch oauth token: unexpected status: 401 Unauthorized`, expected: m.IMAGE_PULL_ERROR_AUTH},
+		{message: `Failed to pull image "ecr/app": no basic auth credentials`, expected: m.IMAGE_PULL_ERROR_AUTH},
+		{message: `Failed to pull image "gcr.io/app": status code: 403`, expected: m.IMAGE_PULL_ERROR_AUTH},
+		{message: `Failed to pull image "quay.io/app": dial tcp 10.0.0.1:443: i/o timeout`, expected: m.IMAGE_PULL_ERROR_TIMEOUT},
+		{message: `Failed to pull image "quay.io/app": context deadline exceeded`, expected: m.IMAGE_PULL_ERROR_TIMEOUT},
+		{message: `Failed to pull image "app@sha256:4290a4041c1a": failed to copy: unexpected EOF`, expected: m.IMAGE_PULL_ERROR_OTHER},
+		{message: `Failed to pull image "registry.example.com:4040/app": layer of 4291 bytes is corrupted`, expected: m.IMAGE_PULL_ERROR_OTHER},
+	}
+	for _, c := range cases {
+		if errorClass := ClassifyImagePullError(c.message); errorClass != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.message, c.expected, errorClass)
+		}
+	}
+}
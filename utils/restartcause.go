@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"strings"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	OOM_MEMORY_THRESHOLD     float64       = 0.9 //share of the memory limit
+	LIVENESS_PROBE_WINDOW    time.Duration = 5 * time.Minute
+	EXIT_CODE_SIGKILL        int32         = 137
+	EXIT_CODE_SIGTERM        int32         = 143
+	NODE_SHUTDOWN_MESSAGE    string        = "node shutdown"
+	POD_REASON_EVICTED       string        = "Evicted"
+	POD_REASON_TERMINATED    string        = "Terminated"
+	POD_REASON_NODE_SHUTDOWN string        = "NodeShutdown"
+)
+
+/*
+ Determines why a container stopped from its termination state, the state of the pod,
+ the last liveness probe failure and the memory usage before the termination.
+ Containers stopped because their pod is being deleted, e.g. on rollout or scale down, are completed
+*/
+func ClassifyRestart(term *v1.ContainerStateTerminated, pod *v1.Pod, lastProbeFailure time.Time, memUse int64, memLimit int64) string {
+	if term == nil {
+		return ""
+	}
+	if term.Reason == m.RESTART_CAUSE_OOM_KILLED {
+		return m.RESTART_CAUSE_OOM_KILLED
+	}
+	if pod != nil {
+		if pod.DeletionTimestamp != nil && (term.ExitCode == 0 || term.ExitCode == EXIT_CODE_SIGTERM || term.ExitCode == EXIT_CODE_SIGKILL) {
+			return m.RESTART_CAUSE_COMPLETED
+		}
+		if pod.Status.Reason == POD_REASON_EVICTED {
+			return m.RESTART_CAUSE_EVICTED
+		}
+		if pod.Status.Reason == POD_REASON_NODE_SHUTDOWN ||
+			pod.Status.Reason == POD_REASON_TERMINATED && strings.Contains(strings.ToLower(pod.Status.Message), NODE_SHUTDOWN_MESSAGE) {
+			return m.RESTART_CAUSE_NODE_SHUTDOWN
+		}
+	}
+	//the kernel OOM killer does not always surface as OOMKilled
+	if term.ExitCode == EXIT_CODE_SIGKILL && memLimit > 0 && float64(memUse) >= OOM_MEMORY_THRESHOLD*float64(memLimit) {
+		return m.RESTART_CAUSE_OOM_KILLED
+	}
+	if !lastProbeFailure.IsZero() {
+		finished := term.FinishedAt.Time
+		if lastProbeFailure.After(finished.Add(-LIVENESS_PROBE_WINDOW)) && lastProbeFailure.Before(finished.Add(time.Minute)) {
+			return m.RESTART_CAUSE_LIVENESS_PROBE
+		}
+	}
+	if term.ExitCode != 0 || term.Signal != 0 {
+		return m.RESTART_CAUSE_NON_ZERO_EXIT
+	}
+	return m.RESTART_CAUSE_COMPLETED
+}
+
+//container name from the field path of an event, e.g. spec.containers{app}
+func GetContainerFromFieldPath(fieldPath string) string {
+	start := strings.Index(fieldPath, "{")
+	end := strings.LastIndex(fieldPath, "}")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return fieldPath[start+1 : end]
+}
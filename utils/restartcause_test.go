@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClassifyRestart(t *testing.T) {
+	finished := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	deleted := metav1.NewTime(finished)
+	running := &v1.Pod{}
+	deleting := &v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}}
+	evicted := &v1.Pod{Status: v1.PodStatus{Reason: POD_REASON_EVICTED}}
+	shutdown := &v1.Pod{Status: v1.PodStatus{Reason: POD_REASON_TERMINATED, Message: "Pod was terminated in response to imminent node shutdown."}}
+
+	cases := []struct {
+		name         string
+		reason       string
+		exitCode     int32
+		pod          *v1.Pod
+		probeFailure time.Time
+		memUse       int64
+		memLimit     int64
+		expected     string
+	}{
+		{name: "exit code", exitCode: 1, pod: running, expected: m.RESTART_CAUSE_NON_ZERO_EXIT},
+		{name: "completed", exitCode: 0, pod: running, expected: m.RESTART_CAUSE_COMPLETED},
+		{name: "no pod", exitCode: 2, expected: m.RESTART_CAUSE_NON_ZERO_EXIT},
+		{name: "oom killed", reason: m.RESTART_CAUSE_OOM_KILLED, exitCode: EXIT_CODE_SIGKILL, pod: running, expected: m.RESTART_CAUSE_OOM_KILLED},
+		{name: "sigkill near the memory limit", exitCode: EXIT_CODE_SIGKILL, pod: running, memUse: 95, memLimit: 100, expected: m.RESTART_CAUSE_OOM_KILLED},
+		{name: "sigkill below the memory limit", exitCode: EXIT_CODE_SIGKILL, pod: running, memUse: 50, memLimit: 100, expected: m.RESTART_CAUSE_NON_ZERO_EXIT},
+		{name: "liveness probe", exitCode: EXIT_CODE_SIGTERM, pod: running, probeFailure: finished.Add(-time.Minute), expected: m.RESTART_CAUSE_LIVENESS_PROBE},
+		{name: "stale liveness probe", exitCode: EXIT_CODE_SIGTERM, pod: running, probeFailure: finished.Add(-LIVENESS_PROBE_WINDOW - time.Minute), expected: m.RESTART_CAUSE_NON_ZERO_EXIT},
+		{name: "evicted", exitCode: EXIT_CODE_SIGTERM, pod: evicted, expected: m.RESTART_CAUSE_EVICTED},
+		{name: "node shutdown", exitCode: EXIT_CODE_SIGTERM, pod: shutdown, expected: m.RESTART_CAUSE_NODE_SHUTDOWN},
+		{name: "deleting pod sigterm", exitCode: EXIT_CODE_SIGTERM, pod: deleting, expected: m.RESTART_CAUSE_COMPLETED},
+		{name: "deleting pod sigkill after the grace period", exitCode: EXIT_CODE_SIGKILL, pod: deleting, memUse: 95, memLimit: 100, expected: m.RESTART_CAUSE_COMPLETED},
+		{name: "deleting pod exit code", exitCode: 1, pod: deleting, expected: m.RESTART_CAUSE_NON_ZERO_EXIT},
+		{name: "deleting pod oom killed", reason: m.RESTART_CAUSE_OOM_KILLED, exitCode: EXIT_CODE_SIGKILL, pod: deleting, expected: m.RESTART_CAUSE_OOM_KILLED},
+	}
+	for _, c := range cases {
+		term := &v1.ContainerStateTerminated{Reason: c.reason, ExitCode: c.exitCode, FinishedAt: metav1.NewTime(finished)}
+		if cause := ClassifyRestart(term, c.pod, c.probeFailure, c.memUse, c.memLimit); cause != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, cause)
+		}
+	}
+	if cause := ClassifyRestart(nil, running, time.Time{}, 0, 0); cause != "" {
+		t.Errorf("Expected no cause without a termination, got %s", cause)
+	}
+}
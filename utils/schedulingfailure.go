@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var schedulingHeaderRegex = regexp.MustCompile(`^\s*(\d+)/(\d+) nodes are available:?\s*(.*)$`)
+var schedulingCountRegex = regexp.MustCompile(`^(\d+) (.+)$`)
+
+//message fragments of the scheduler predicates, in order of evaluation
+var schedulingPredicateMessages = []struct {
+	Fragment  string
+	Predicate string
+}{
+	{"insufficient cpu", m.SCHED_INSUFFICIENT_CPU},
+	{"insufficient memory", m.SCHED_INSUFFICIENT_MEMORY},
+	{"insufficient", m.SCHED_INSUFFICIENT_RESOURCES},
+	{"too many pods", m.SCHED_INSUFFICIENT_RESOURCES},
+	{"volume node affinity conflict", m.SCHED_VOLUME_AFFINITY},
+	{"pod affinity", m.SCHED_POD_AFFINITY},
+	{"pod anti-affinity", m.SCHED_POD_AFFINITY},
+	{"existing pods anti-affinity", m.SCHED_POD_AFFINITY},
+	{"node affinity", m.SCHED_NODE_AFFINITY},
+	{"node selector", m.SCHED_NODE_AFFINITY},
+	{"taint", m.SCHED_TAINTS},
+	{"were unschedulable", m.SCHED_NODE_UNSCHEDULABLE},
+	{"unschedulable", m.SCHED_NODE_UNSCHEDULABLE},
+	{"persistentvolumeclaim", m.SCHED_VOLUME_BINDING},
+	{"persistent volumes to bind", m.SCHED_VOLUME_BINDING},
+	{"max volume count", m.SCHED_VOLUME_BINDING},
+	{"free ports", m.SCHED_HOST_PORTS},
+	{"topology spread", m.SCHED_TOPOLOGY_SPREAD},
+}
+
+func GetSchedulingPredicate(text string) string {
+	text = strings.ToLower(text)
+	for _, p := range schedulingPredicateMessages {
+		if strings.Contains(text, p.Fragment) {
+			return p.Predicate
+		}
+	}
+	return m.SCHED_OTHER
+}
+
+/*
+ Parses the message of FailedScheduling events and Unschedulable pod conditions, e.g.
+ "0/12 nodes are available: 3 Insufficient cpu, 9 node(s) had taints that the pod didn't tolerate."
+ into the number of rejected nodes per predicate. The preemption details are ignored
+*/
+func ParseSchedulingFailure(message string) m.SchedulingFailure {
+	sf := m.NewSchedulingFailure()
+	if idx := strings.Index(message, "preemption:"); idx >= 0 {
+		message = message[:idx]
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return sf
+	}
+	header := schedulingHeaderRegex.FindStringSubmatch(message)
+	if header == nil {
+		//no node breakdown, e.g. pod has unbound immediate PersistentVolumeClaims
+		sf.Predicates[GetSchedulingPredicate(message)] = 0
+		return sf
+	}
+	sf.TotalNodes, _ = strconv.Atoi(header[2])
+	for _, part := range splitSchedulingReasons(header[3]) {
+		part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "."))
+		if part == "" {
+			continue
+		}
+		count := sf.TotalNodes
+		text := part
+		if match := schedulingCountRegex.FindStringSubmatch(part); match != nil {
+			count, _ = strconv.Atoi(match[1])
+			text = match[2]
+		}
+		sf.Predicates[GetSchedulingPredicate(text)] += count
+	}
+	return sf
+}
+
+//splits on commas outside of braces and brackets. Taints are quoted as {key: value}
+func splitSchedulingReasons(s string) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+func TestParseSchedulingFailure(t *testing.T) {
+	cases := []struct {
+		name       string
+		message    string
+		totalNodes int
+		predicates map[string]int
+		cause      string
+	}{
+		{name: "resources and taints", message: "0/12 nodes are available: 3 Insufficient cpu, 9 node(s) had taints that the pod didn't tolerate.",
+			totalNodes: 12, predicates: map[string]int{m.SCHED_INSUFFICIENT_CPU: 3, m.SCHED_TAINTS: 9}, cause: m.SCHED_CAUSE_CAPACITY},
+		{name: "quoted taint", message: "0/5 nodes are available: 2 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }, 3 Insufficient memory.",
+			totalNodes: 5, predicates: map[string]int{m.SCHED_TAINTS: 2, m.SCHED_INSUFFICIENT_MEMORY: 3}, cause: m.SCHED_CAUSE_CAPACITY},
+		{name: "other resources summed", message: "0/4 nodes are available: 1 Insufficient cpu, 1 Insufficient nvidia.com/gpu, 2 Too many pods.",
+			totalNodes: 4, predicates: map[string]int{m.SCHED_INSUFFICIENT_CPU: 1, m.SCHED_INSUFFICIENT_RESOURCES: 3}, cause: m.SCHED_CAUSE_CAPACITY},
+		{name: "affinity", message: "0/6 nodes are available: 2 node(s) didn't match pod anti-affinity rules, 4 node(s) didn't match Pod's node affinity/selector.",
+			totalNodes: 6, predicates: map[string]int{m.SCHED_POD_AFFINITY: 2, m.SCHED_NODE_AFFINITY: 4}, cause: m.SCHED_CAUSE_SPEC},
+		{name: "preemption suffix", message: "0/3 nodes are available: 1 node(s) were unschedulable, 2 Insufficient cpu. preemption: 0/3 nodes are available: 1 Preemption is not helpful for scheduling, 2 No preemption victims found for incoming pod.",
+			totalNodes: 3, predicates: map[string]int{m.SCHED_NODE_UNSCHEDULABLE: 1, m.SCHED_INSUFFICIENT_CPU: 2}, cause: m.SCHED_CAUSE_CAPACITY},
+		{name: "preemption suffix without details", message: "0/3 nodes are available: 3 node(s) didn't have free ports for the requested pod ports. preemption: not eligible due to preemptionPolicy=Never.",
+			totalNodes: 3, predicates: map[string]int{m.SCHED_HOST_PORTS: 3}, cause: m.SCHED_CAUSE_SPEC},
+		{name: "reason without a count", message: "0/1 nodes are available: node(s) had volume node affinity conflict.",
+			totalNodes: 1, predicates: map[string]int{m.SCHED_VOLUME_AFFINITY: 1}, cause: m.SCHED_CAUSE_SPEC},
+		{name: "unknown reason", message: "0/2 nodes are available: 2 node(s) were out of disk space.",
+			totalNodes: 2, predicates: map[string]int{m.SCHED_OTHER: 2}, cause: ""},
+		{name: "no node breakdown", message: "pod has unbound immediate PersistentVolumeClaims",
+			predicates: map[string]int{m.SCHED_VOLUME_BINDING: 0}, cause: m.SCHED_CAUSE_SPEC},
+		{name: "preemption only", message: "preemption: 0/3 nodes are available: 3 No preemption victims found for incoming pod.",
+			predicates: map[string]int{}, cause: ""},
+		{name: "empty", message: "", predicates: map[string]int{}, cause: ""},
+	}
+	for _, c := range cases {
+		sf := ParseSchedulingFailure(c.message)
+		if sf.TotalNodes != c.totalNodes {
+			t.Errorf("%s: expected %d nodes, got %d", c.name, c.totalNodes, sf.TotalNodes)
+		}
+		if !reflect.DeepEqual(sf.Predicates, c.predicates) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.predicates, sf.Predicates)
+		}
+		if cause := sf.GetCause(); cause != c.cause {
+			t.Errorf("%s: expected cause %q, got %q", c.name, c.cause, cause)
+		}
+	}
+}
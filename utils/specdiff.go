@@ -0,0 +1,348 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const MASKED_VALUE string = "***"
+
+//field-level differences between two pod templates
+func DiffPodTemplates(oldSpec *v1.PodTemplateSpec, newSpec *v1.PodTemplateSpec) []m.FieldChange {
+	changes := []m.FieldChange{}
+	oldContainers := containerMap(oldSpec.Spec.InitContainers, oldSpec.Spec.Containers)
+	newContainers := containerMap(newSpec.Spec.InitContainers, newSpec.Spec.Containers)
+
+	for name, nc := range newContainers {
+		oc, ok := oldContainers[name]
+		if !ok {
+			changes = append(changes, m.FieldChange{Field: fmt.Sprintf("containers[%s]", name), OldValue: "", NewValue: nc.Image})
+			continue
+		}
+		prefix := fmt.Sprintf("containers[%s]", name)
+		changes = appendChange(changes, prefix+".image", oc.Image, nc.Image)
+		changes = append(changes, diffEnv(prefix, oc.Env, nc.Env)...)
+		changes = appendChange(changes, prefix+".envFrom", formatEnvFrom(oc.EnvFrom), formatEnvFrom(nc.EnvFrom))
+		changes = appendChange(changes, prefix+".resources.requests", formatResourceList(oc.Resources.Requests), formatResourceList(nc.Resources.Requests))
+		changes = appendChange(changes, prefix+".resources.limits", formatResourceList(oc.Resources.Limits), formatResourceList(nc.Resources.Limits))
+		changes = appendChange(changes, prefix+".livenessProbe", formatProbe(oc.LivenessProbe), formatProbe(nc.LivenessProbe))
+		changes = appendChange(changes, prefix+".readinessProbe", formatProbe(oc.ReadinessProbe), formatProbe(nc.ReadinessProbe))
+	}
+	for name, oc := range oldContainers {
+		if _, ok := newContainers[name]; !ok {
+			changes = append(changes, m.FieldChange{Field: fmt.Sprintf("containers[%s]", name), OldValue: oc.Image, NewValue: ""})
+		}
+	}
+	changes = appendChange(changes, "volumes.configMaps", strings.Join(GetPodConfigRefs(&oldSpec.Spec, true), ";"), strings.Join(GetPodConfigRefs(&newSpec.Spec, true), ";"))
+	changes = appendChange(changes, "volumes.secrets", strings.Join(GetPodConfigRefs(&oldSpec.Spec, false), ";"), strings.Join(GetPodConfigRefs(&newSpec.Spec, false), ";"))
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func DiffReplicas(oldReplicas *int32, newReplicas *int32) []m.FieldChange {
+	return appendChange([]m.FieldChange{}, "replicas", formatInt32Ptr(oldReplicas), formatInt32Ptr(newReplicas))
+}
+
+//differences between data keys of configmaps or secrets. Values of secrets are masked, the records only show which keys changed
+func DiffConfigData(oldData map[string]string, newData map[string]string, mask bool) []m.FieldChange {
+	changes := []m.FieldChange{}
+	for k, nv := range newData {
+		ov, ok := oldData[k]
+		if ok && ov == nv {
+			continue
+		}
+		change := m.FieldChange{Field: fmt.Sprintf("data[%s]", k), OldValue: ov, NewValue: nv}
+		if mask {
+			change.OldValue = maskValue(ov, ok)
+			change.NewValue = maskValue(nv, true)
+		}
+		changes = append(changes, change)
+	}
+	for k, ov := range oldData {
+		if _, ok := newData[k]; !ok {
+			change := m.FieldChange{Field: fmt.Sprintf("data[%s]", k), OldValue: ov, NewValue: ""}
+			if mask {
+				change.OldValue = maskValue(ov, true)
+			}
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func SecretDataToStrings(data map[string][]byte) map[string]string {
+	result := make(map[string]string)
+	for k, v := range data {
+		result[k] = string(v)
+	}
+	return result
+}
+
+//returns names of configmaps (or secrets) referenced by volumes, env and envFrom of the pod
+func GetPodConfigRefs(spec *v1.PodSpec, configMaps bool) []string {
+	refs := []string{}
+	for _, vol := range spec.Volumes {
+		addVolumeConfigRefs(&refs, &vol, configMaps)
+	}
+	containers := append([]v1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, c := range containers {
+		addEnvConfigRefs(&refs, &c, configMaps)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+//returns names of configmaps (or secrets) referenced by env, envFrom and volumes mounted by the container
+func GetContainerConfigRefs(spec *v1.PodSpec, c *v1.Container, configMaps bool) []string {
+	refs := []string{}
+	addEnvConfigRefs(&refs, c, configMaps)
+	for _, vm := range c.VolumeMounts {
+		for _, vol := range spec.Volumes {
+			if vol.Name == vm.Name {
+				addVolumeConfigRefs(&refs, &vol, configMaps)
+			}
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func addConfigRef(refs *[]string, name string) {
+	if name != "" && !StringInSlice(name, *refs) {
+		*refs = append(*refs, name)
+	}
+}
+
+func addVolumeConfigRefs(refs *[]string, vol *v1.Volume, configMaps bool) {
+	if configMaps && vol.ConfigMap != nil {
+		addConfigRef(refs, vol.ConfigMap.Name)
+	}
+	if !configMaps && vol.Secret != nil {
+		addConfigRef(refs, vol.Secret.SecretName)
+	}
+	if vol.Projected != nil {
+		for _, s := range vol.Projected.Sources {
+			if configMaps && s.ConfigMap != nil {
+				addConfigRef(refs, s.ConfigMap.Name)
+			}
+			if !configMaps && s.Secret != nil {
+				addConfigRef(refs, s.Secret.Name)
+			}
+		}
+	}
+}
+
+func addEnvConfigRefs(refs *[]string, c *v1.Container, configMaps bool) {
+	for _, ef := range c.EnvFrom {
+		if configMaps && ef.ConfigMapRef != nil {
+			addConfigRef(refs, ef.ConfigMapRef.Name)
+		}
+		if !configMaps && ef.SecretRef != nil {
+			addConfigRef(refs, ef.SecretRef.Name)
+		}
+	}
+	for _, ev := range c.Env {
+		if ev.ValueFrom == nil {
+			continue
+		}
+		if configMaps && ev.ValueFrom.ConfigMapKeyRef != nil {
+			addConfigRef(refs, ev.ValueFrom.ConfigMapKeyRef.Name)
+		}
+		if !configMaps && ev.ValueFrom.SecretKeyRef != nil {
+			addConfigRef(refs, ev.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+}
+
+//hash of the data of a configmap or secret. Order of the keys does not matter
+func HashConfigData(data map[string]string, binaryData map[string][]byte) string {
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	for k := range binaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		if v, ok := data[k]; ok {
+			h.Write([]byte(v))
+		} else {
+			h.Write(binaryData[k])
+		}
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+//time of the most recent update recorded in managedFields, creation time if none
+func GetLastUpdateTime(meta *metav1.ObjectMeta) time.Time {
+	last := meta.CreationTimestamp.Time
+	for _, mf := range meta.ManagedFields {
+		if mf.Time != nil && mf.Time.Time.After(last) {
+			last = mf.Time.Time
+		}
+	}
+	return last
+}
+
+//the manager of the most recent update recorded in managedFields
+func GetChangeActor(meta *metav1.ObjectMeta) string {
+	actor := ""
+	var latest *metav1.Time
+	for _, mf := range meta.ManagedFields {
+		if mf.Operation != metav1.ManagedFieldsOperationUpdate && mf.Operation != metav1.ManagedFieldsOperationApply {
+			continue
+		}
+		if mf.Time == nil {
+			if actor == "" {
+				actor = mf.Manager
+			}
+			continue
+		}
+		if latest == nil || latest.Before(mf.Time) {
+			latest = mf.Time
+			actor = mf.Manager
+		}
+	}
+	return actor
+}
+
+func appendChange(changes []m.FieldChange, field string, oldVal string, newVal string) []m.FieldChange {
+	if oldVal != newVal {
+		changes = append(changes, m.FieldChange{Field: field, OldValue: oldVal, NewValue: newVal})
+	}
+	return changes
+}
+
+func containerMap(initContainers []v1.Container, containers []v1.Container) map[string]v1.Container {
+	cm := make(map[string]v1.Container)
+	for _, c := range initContainers {
+		cm["init:"+c.Name] = c
+	}
+	for _, c := range containers {
+		cm[c.Name] = c
+	}
+	return cm
+}
+
+//per variable, so that a change of a masked value is still recorded
+func diffEnv(prefix string, oldEnv []v1.EnvVar, newEnv []v1.EnvVar) []m.FieldChange {
+	changes := []m.FieldChange{}
+	oldVars := make(map[string]v1.EnvVar)
+	for _, ev := range oldEnv {
+		oldVars[ev.Name] = ev
+	}
+	newVars := make(map[string]v1.EnvVar)
+	for _, ev := range newEnv {
+		newVars[ev.Name] = ev
+	}
+	for name, nv := range newVars {
+		field := fmt.Sprintf("%s.env[%s]", prefix, name)
+		ov, ok := oldVars[name]
+		if !ok {
+			changes = append(changes, m.FieldChange{Field: field, OldValue: "", NewValue: formatEnvVar(nv)})
+		} else if getEnvSource(ov) != getEnvSource(nv) {
+			changes = append(changes, m.FieldChange{Field: field, OldValue: formatEnvVar(ov), NewValue: formatEnvVar(nv)})
+		}
+	}
+	for name, ov := range oldVars {
+		if _, ok := newVars[name]; !ok {
+			changes = append(changes, m.FieldChange{Field: fmt.Sprintf("%s.env[%s]", prefix, name), OldValue: formatEnvVar(ov), NewValue: ""})
+		}
+	}
+	return changes
+}
+
+//the value or reference of the variable, compared between the revisions. Not published
+func getEnvSource(ev v1.EnvVar) string {
+	if ev.ValueFrom != nil && ev.ValueFrom.SecretKeyRef != nil {
+		return fmt.Sprintf("secret:%s/%s", ev.ValueFrom.SecretKeyRef.Name, ev.ValueFrom.SecretKeyRef.Key)
+	}
+	if ev.ValueFrom == nil {
+		return "value:" + ev.Value
+	}
+	return formatEnvVar(ev)
+}
+
+//the variable as published. Literal values and secret references are masked, they may carry credentials
+func formatEnvVar(ev v1.EnvVar) string {
+	if ev.ValueFrom == nil || ev.ValueFrom.SecretKeyRef != nil {
+		return MASKED_VALUE
+	}
+	switch {
+	case ev.ValueFrom.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configMap:%s/%s", ev.ValueFrom.ConfigMapKeyRef.Name, ev.ValueFrom.ConfigMapKeyRef.Key)
+	case ev.ValueFrom.FieldRef != nil:
+		return fmt.Sprintf("field:%s", ev.ValueFrom.FieldRef.FieldPath)
+	case ev.ValueFrom.ResourceFieldRef != nil:
+		return fmt.Sprintf("resource:%s", ev.ValueFrom.ResourceFieldRef.Resource)
+	}
+	return MASKED_VALUE
+}
+
+func formatEnvFrom(envFrom []v1.EnvFromSource) string {
+	var list []string
+	for _, ef := range envFrom {
+		if ef.ConfigMapRef != nil {
+			list = append(list, fmt.Sprintf("configMap:%s", ef.ConfigMapRef.Name))
+		}
+		if ef.SecretRef != nil {
+			list = append(list, fmt.Sprintf("secret:%s", ef.SecretRef.Name))
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, ";")
+}
+
+func formatResourceList(rl v1.ResourceList) string {
+	var list []string
+	for name, q := range rl {
+		list = append(list, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	sort.Strings(list)
+	return strings.Join(list, ";")
+}
+
+func formatProbe(p *v1.Probe) string {
+	if p == nil {
+		return ""
+	}
+	handler := ""
+	switch {
+	case p.HTTPGet != nil:
+		handler = fmt.Sprintf("http:%s:%s", p.HTTPGet.Path, p.HTTPGet.Port.String())
+	case p.TCPSocket != nil:
+		handler = fmt.Sprintf("tcp:%s", p.TCPSocket.Port.String())
+	case p.Exec != nil:
+		handler = fmt.Sprintf("exec:%s", strings.Join(p.Exec.Command, " "))
+	}
+	return fmt.Sprintf("%s delay=%d timeout=%d period=%d failure=%d", handler, p.InitialDelaySeconds, p.TimeoutSeconds, p.PeriodSeconds, p.FailureThreshold)
+}
+
+func formatInt32Ptr(val *int32) string {
+	if val == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *val)
+}
+
+func maskValue(val string, exists bool) string {
+	if !exists {
+		return ""
+	}
+	return MASKED_VALUE
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+)
+
+var testSecretValues = []string{"hunter2", "s3cr3t-n3w", "token-old", "token-new"}
+
+func newTestTemplate(image string, env []v1.EnvVar) *v1.PodTemplateSpec {
+	template := v1.PodTemplateSpec{}
+	template.Spec.Containers = []v1.Container{{Name: "app", Image: image, Env: env}}
+	return &template
+}
+
+func secretEnv(name string, secret string, key string) v1.EnvVar {
+	return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: secret}, Key: key}}}
+}
+
+func configMapEnv(name string, configMap string, key string) v1.EnvVar {
+	return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: configMap}, Key: key}}}
+}
+
+func checkNoSecretValues(t *testing.T, changes []m.FieldChange) {
+	for _, c := range changes {
+		for _, secret := range testSecretValues {
+			if strings.Contains(c.OldValue, secret) || strings.Contains(c.NewValue, secret) {
+				t.Errorf("Secret value %s leaked in the change of %s: %s -> %s", secret, c.Field, c.OldValue, c.NewValue)
+			}
+		}
+	}
+}
+
+func TestDiffPodTemplatesMasksEnv(t *testing.T) {
+	oldSpec := newTestTemplate("shop/web:1.0", []v1.EnvVar{
+		{Name: "DB_PASSWORD", Value: "hunter2"},
+		{Name: "API_TOKEN", Value: "token-old"},
+		{Name: "UNCHANGED", Value: "hunter2"},
+		secretEnv("DB_URL", "db-credentials", "url"),
+		configMapEnv("LOG_LEVEL", "web-config", "level"),
+		{Name: "REMOVED", Value: "token-old"},
+	})
+	newSpec := newTestTemplate("shop/web:1.1", []v1.EnvVar{
+		{Name: "DB_PASSWORD", Value: "s3cr3t-n3w"},
+		secretEnv("API_TOKEN", "api-token", "token"),
+		{Name: "UNCHANGED", Value: "hunter2"},
+		secretEnv("DB_URL", "db-credentials-v2", "url"),
+		configMapEnv("LOG_LEVEL", "web-config-v2", "level"),
+		{Name: "ADDED", Value: "token-new"},
+	})
+
+	changes := DiffPodTemplates(oldSpec, newSpec)
+	checkNoSecretValues(t, changes)
+
+	expected := []m.FieldChange{
+		{Field: "containers[app].env[ADDED]", OldValue: "", NewValue: MASKED_VALUE},
+		{Field: "containers[app].env[API_TOKEN]", OldValue: MASKED_VALUE, NewValue: MASKED_VALUE},
+		{Field: "containers[app].env[DB_PASSWORD]", OldValue: MASKED_VALUE, NewValue: MASKED_VALUE},
+		{Field: "containers[app].env[DB_URL]", OldValue: MASKED_VALUE, NewValue: MASKED_VALUE},
+		{Field: "containers[app].env[LOG_LEVEL]", OldValue: "configMap:web-config/level", NewValue: "configMap:web-config-v2/level"},
+		{Field: "containers[app].env[REMOVED]", OldValue: MASKED_VALUE, NewValue: ""},
+		{Field: "containers[app].image", OldValue: "shop/web:1.0", NewValue: "shop/web:1.1"},
+		{Field: "volumes.configMaps", OldValue: "web-config", NewValue: "web-config-v2"},
+		{Field: "volumes.secrets", OldValue: "db-credentials", NewValue: "api-token;db-credentials-v2"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffPodTemplatesSecretRefs(t *testing.T) {
+	oldSpec := newTestTemplate("shop/web:1.0", nil)
+	oldSpec.Spec.Containers[0].EnvFrom = []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "web-secrets"}}}}
+	oldSpec.Spec.Volumes = []v1.Volume{{Name: "certs", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "web-certs"}}}}
+	newSpec := newTestTemplate("shop/web:1.0", nil)
+	newSpec.Spec.Containers[0].EnvFrom = []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "web-secrets-v2"}}}}
+	newSpec.Spec.Volumes = []v1.Volume{{Name: "certs", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "web-certs-v2"}}}}
+
+	//only the names of the secrets are recorded, their data is never part of the pod template
+	expected := []m.FieldChange{
+		{Field: "containers[app].envFrom", OldValue: "secret:web-secrets", NewValue: "secret:web-secrets-v2"},
+		{Field: "volumes.secrets", OldValue: "web-certs;web-secrets", NewValue: "web-certs-v2;web-secrets-v2"},
+	}
+	if changes := DiffPodTemplates(oldSpec, newSpec); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffConfigData(t *testing.T) {
+	oldData := SecretDataToStrings(map[string][]byte{"password": []byte("hunter2"), "token": []byte("token-old"), "user": []byte("admin"), "removed": []byte("hunter2")})
+	newData := SecretDataToStrings(map[string][]byte{"password": []byte("s3cr3t-n3w"), "token": []byte("token-old"), "user": []byte("admin"), "added": []byte("token-new")})
+
+	changes := DiffConfigData(oldData, newData, true)
+	checkNoSecretValues(t, changes)
+	expected := []m.FieldChange{
+		{Field: "data[added]", OldValue: "", NewValue: MASKED_VALUE},
+		{Field: "data[password]", OldValue: MASKED_VALUE, NewValue: MASKED_VALUE},
+		{Field: "data[removed]", OldValue: MASKED_VALUE, NewValue: ""},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Secrets: expected %v, got %v", expected, changes)
+	}
+
+	//values of configmaps are shown
+	changes = DiffConfigData(map[string]string{"level": "info"}, map[string]string{"level": "debug"}, false)
+	expected = []m.FieldChange{{Field: "data[level]", OldValue: "info", NewValue: "debug"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("ConfigMaps: expected %v, got %v", expected, changes)
+	}
+}
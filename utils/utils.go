@@ -3,6 +3,8 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -176,3 +178,13 @@ func TruncateString(str string, maxLength int) string {
 	}
 	return result
 }
+
+var volatileTokens = regexp.MustCompile(`[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|0x[0-9a-fA-F]+|[0-9]+`)
+
+//hash of the message with ids, addresses and numbers masked, so repeats of the same event share a fingerprint
+func GetMessageFingerprint(msg string) string {
+	normalized := volatileTokens.ReplaceAllString(strings.TrimSpace(msg), "#")
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return fmt.Sprintf("%x", h.Sum64())
+}
@@ -0,0 +1,173 @@
+package watchers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+)
+
+var lockChanges = sync.RWMutex{}
+var lockPodRefs = sync.RWMutex{}
+
+//tier of a pod and the objects it depends on, as kind/namespace/name keys
+type podChangeRefs struct {
+	TierName string
+	Objects  []string
+}
+
+type ChangeRecorder struct {
+	ConfManager  *config.MutexConfigManager
+	Sinks        *app.SinkSet
+	UpdatedCache []m.ChangeSchema
+	PodRefs      map[string]podChangeRefs
+	Logger       *log.Logger
+}
+
+func NewChangeRecorder(cm *config.MutexConfigManager, sinks *app.SinkSet, l *log.Logger) *ChangeRecorder {
+	cr := ChangeRecorder{ConfManager: cm, Sinks: sinks, UpdatedCache: []m.ChangeSchema{}, PodRefs: make(map[string]podChangeRefs), Logger: l}
+	return &cr
+}
+
+func getChangeObjectKey(kind, namespace, name string) string {
+	return kind + "/" + utils.GetKey(namespace, name)
+}
+
+/*
+ Remembers the tier of the pod along with its owner and the config maps and secrets it references,
+ so that changes of these objects are attributed to the tier. Safe to call on a nil recorder
+*/
+func (cr *ChangeRecorder) TrackPod(p *v1.Pod, tierName string, ownerKind string, ownerName string) {
+	if cr == nil || tierName == "" {
+		return
+	}
+	objects := []string{}
+	if ownerKind != "" {
+		objects = append(objects, getChangeObjectKey(ownerKind, p.Namespace, ownerName))
+	}
+	for _, name := range utils.GetPodConfigRefs(&p.Spec, true) {
+		objects = append(objects, getChangeObjectKey(m.CHANGE_KIND_CONFIGMAP, p.Namespace, name))
+	}
+	for _, name := range utils.GetPodConfigRefs(&p.Spec, false) {
+		objects = append(objects, getChangeObjectKey(m.CHANGE_KIND_SECRET, p.Namespace, name))
+	}
+	lockPodRefs.Lock()
+	defer lockPodRefs.Unlock()
+	cr.PodRefs[utils.GetPodKey(p)] = podChangeRefs{TierName: tierName, Objects: objects}
+}
+
+func (cr *ChangeRecorder) UntrackPod(p *v1.Pod) {
+	if cr == nil {
+		return
+	}
+	lockPodRefs.Lock()
+	defer lockPodRefs.Unlock()
+	delete(cr.PodRefs, utils.GetPodKey(p))
+}
+
+//tiers of the pods that depend on the object. Workloads without tracked pods are their own tier
+func (cr *ChangeRecorder) getObjectTiers(kind string, meta *metav1.ObjectMeta) []string {
+	key := getChangeObjectKey(kind, meta.Namespace, meta.Name)
+	tiers := []string{}
+	lockPodRefs.RLock()
+	for _, refs := range cr.PodRefs {
+		if utils.StringInSlice(key, refs.Objects) && !utils.StringInSlice(refs.TierName, tiers) {
+			tiers = append(tiers, refs.TierName)
+		}
+	}
+	lockPodRefs.RUnlock()
+	if len(tiers) == 0 {
+		if kind == m.CHANGE_KIND_CONFIGMAP || kind == m.CHANGE_KIND_SECRET {
+			return []string{""}
+		}
+		return []string{meta.Name}
+	}
+	sort.Strings(tiers)
+	return tiers
+}
+
+func (cr *ChangeRecorder) Start(stopCh <-chan struct{}) {
+	bag := (*cr.ConfManager).Get()
+	go cr.eventQueueTicker(stopCh, time.NewTicker(time.Duration(bag.SnapshotSyncInterval)*time.Second))
+}
+
+func (cr *ChangeRecorder) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker) {
+	for {
+		select {
+		case <-ticker.C:
+			cr.postChangeRecords()
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+//queues field changes of the object for publishing, one record per field and tier that depends on the object. Safe to call on a nil recorder
+func (cr *ChangeRecorder) RecordChanges(kind string, meta *metav1.ObjectMeta, changes []m.FieldChange) {
+	if cr == nil || len(changes) == 0 {
+		return
+	}
+	bag := (*cr.ConfManager).Get()
+	clusterName := meta.ClusterName
+	if clusterName == "" {
+		clusterName = bag.AppName
+	}
+	actor := utils.GetChangeActor(meta)
+	tiers := cr.getObjectTiers(kind, meta)
+	now := time.Now()
+
+	lockChanges.Lock()
+	defer lockChanges.Unlock()
+	for _, tierName := range tiers {
+		for _, c := range changes {
+			record := m.ChangeSchema{ClusterName: clusterName, Namespace: meta.Namespace, ObjectKind: kind, ObjectName: meta.Name,
+				ObjectUid: string(meta.UID), ResourceVersion: meta.ResourceVersion, Field: c.Field,
+				OldValue: utils.TruncateString(c.OldValue, app.MAX_FIELD_LENGTH), NewValue: utils.TruncateString(c.NewValue, app.MAX_FIELD_LENGTH),
+				Actor: actor, ChangeTimestamp: now, TierName: tierName}
+			cr.UpdatedCache = append(cr.UpdatedCache, record)
+		}
+	}
+	cr.Logger.WithFields(log.Fields{"kind": kind, "namespace": meta.Namespace, "name": meta.Name, "changes": len(changes), "tiers": tiers, "actor": actor}).Debug("Spec changes recorded")
+}
+
+func (cr *ChangeRecorder) postChangeRecords() {
+	lockChanges.Lock()
+	objList := cr.UpdatedCache
+	cr.UpdatedCache = []m.ChangeSchema{}
+	lockChanges.Unlock()
+
+	if len(objList) == 0 {
+		return
+	}
+
+	cr.Logger.WithField("count", len(objList)).Info("About to send change records")
+	bag := (*cr.ConfManager).Get()
+
+	for start := 0; start < len(objList); start += bag.EventAPILimit {
+		end := start + bag.EventAPILimit
+		if end > len(objList) {
+			end = len(objList)
+		}
+		batch := objList[start:end]
+		cr.postChangeBatchRecords(&batch)
+	}
+}
+
+func (cr *ChangeRecorder) postChangeBatchRecords(objList *[]m.ChangeSchema) {
+	bag := (*cr.ConfManager).Get()
+	schemaDefObj := m.NewChangeSchemaDefWrapper()
+	err := cr.Sinks.PostEvents(bag.ChangeSchemaName, &schemaDefObj, objList)
+	if err != nil {
+		cr.Logger.WithFields(log.Fields{"name": bag.ChangeSchemaName, "error": err}).Error("Issues when publishing records")
+	}
+}
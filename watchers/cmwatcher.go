@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
@@ -23,13 +24,15 @@ type ConfigWatcher struct {
 	ConfManager *config.MutexConfigManager
 	Listener    *WatchListener
 	UpdateDelay bool
+	Recorder    *ChangeRecorder
+	Versions    map[string]m.ConfigVersion
 	Logger      *log.Logger
 }
 
 var lockConfigs = sync.RWMutex{}
 
-func NewConfigWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.ConfigMap, listener WatchListener, l *log.Logger) *ConfigWatcher {
-	sw := ConfigWatcher{Client: client, CMCache: *cache, ConfManager: cm, Listener: &listener, Logger: l}
+func NewConfigWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.ConfigMap, listener WatchListener, recorder *ChangeRecorder, l *log.Logger) *ConfigWatcher {
+	sw := ConfigWatcher{Client: client, CMCache: *cache, ConfManager: cm, Listener: &listener, Recorder: recorder, Versions: make(map[string]m.ConfigVersion), Logger: l}
 	sw.UpdateDelay = true
 	return &sw
 }
@@ -105,8 +108,9 @@ func (pw ConfigWatcher) onDeleteConfig(cm *v1.ConfigMap) {
 	_, ok := pw.CMCache[utils.GetConfigMapKey(cm)]
 	if ok {
 		lockConfigs.Lock()
-		defer lockConfigs.Unlock()
 		delete(pw.CMCache, utils.GetConfigMapKey(cm))
+		delete(pw.Versions, utils.GetConfigMapKey(cm))
+		lockConfigs.Unlock()
 		pw.notifyListener(cm.Namespace)
 	}
 }
@@ -115,13 +119,50 @@ func (pw ConfigWatcher) onUpdateConfig(cm *v1.ConfigMap) {
 	if !pw.qualifies(cm) {
 		return
 	}
-	pw.updateMap(cm)
+	pw.recordChanges(cm)
+	if pw.updateMap(cm) {
+		//pods started before the change are running stale config
+		pw.notifyListener(cm.Namespace)
+	}
 }
 
-func (pw ConfigWatcher) updateMap(cm *v1.ConfigMap) {
+func (pw ConfigWatcher) recordChanges(cm *v1.ConfigMap) {
+	lockConfigs.RLock()
+	old, ok := pw.CMCache[utils.GetConfigMapKey(cm)]
+	lockConfigs.RUnlock()
+	if !ok || old.ResourceVersion == cm.ResourceVersion {
+		return
+	}
+	changes := utils.DiffConfigData(old.Data, cm.Data, false)
+	pw.Recorder.RecordChanges(m.CHANGE_KIND_CONFIGMAP, &cm.ObjectMeta, changes)
+}
+
+//returns true if the data of the known config map changed
+func (pw ConfigWatcher) updateMap(cm *v1.ConfigMap) bool {
 	lockConfigs.Lock()
 	defer lockConfigs.Unlock()
-	pw.CMCache[utils.GetConfigMapKey(cm)] = *cm
+	key := utils.GetConfigMapKey(cm)
+	pw.CMCache[key] = *cm
+
+	hash := utils.HashConfigData(cm.Data, cm.BinaryData)
+	version, known := pw.Versions[key]
+	changed := known && version.Hash != hash
+	if !known {
+		version.Updated = utils.GetLastUpdateTime(&cm.ObjectMeta)
+	} else if changed {
+		version.Updated = time.Now()
+	}
+	version.ResourceVersion = cm.ResourceVersion
+	version.Hash = hash
+	pw.Versions[key] = version
+	return changed
+}
+
+func (pw ConfigWatcher) GetVersion(namespace string, name string) (m.ConfigVersion, bool) {
+	lockConfigs.RLock()
+	defer lockConfigs.RUnlock()
+	version, ok := pw.Versions[utils.GetKey(namespace, name)]
+	return version, ok
 }
 
 func (pw ConfigWatcher) CloneMap() map[string]v1.ConfigMap {
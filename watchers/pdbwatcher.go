@@ -0,0 +1,204 @@
+package watchers
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/appdynamics/cluster-agent/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/client-go/kubernetes"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+)
+
+var lockPDB = sync.RWMutex{}
+
+type PDBWatcher struct {
+	Client       *kubernetes.Clientset
+	PDBCache     map[string]policyv1beta1.PodDisruptionBudget
+	ConfManager  *config.MutexConfigManager
+	Sinks        *app.SinkSet
+	UpdatedCache map[string]m.PdbSchema
+	Logger       *log.Logger
+}
+
+func NewPDBWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, sinks *app.SinkSet, cache *map[string]policyv1beta1.PodDisruptionBudget, l *log.Logger) *PDBWatcher {
+	pw := PDBWatcher{Client: client, PDBCache: *cache, ConfManager: cm, Sinks: sinks, UpdatedCache: make(map[string]m.PdbSchema), Logger: l}
+	return &pw
+}
+
+func (pw *PDBWatcher) qualifies(pdb *policyv1beta1.PodDisruptionBudget) bool {
+	bag := pw.ConfManager.Get()
+	return utils.NSQualifiesForMonitoring(pdb.Namespace, bag)
+}
+
+func (pw *PDBWatcher) startEventQueueWorker(stopCh <-chan struct{}) {
+	bag := (*pw.ConfManager).Get()
+	pw.eventQueueTicker(stopCh, time.NewTicker(time.Duration(bag.SnapshotSyncInterval)*time.Second))
+}
+
+func (pw *PDBWatcher) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker) {
+	for {
+		select {
+		case <-ticker.C:
+			pw.postPDBRecords()
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+//pod disruption budgets
+func (pw PDBWatcher) WatchPDBs() {
+	api := pw.Client.PolicyV1beta1()
+	listOptions := metav1.ListOptions{}
+	pw.Logger.Info("Starting PDB Watcher...")
+
+	stop := make(chan struct{})
+	go pw.startEventQueueWorker(stop)
+
+	watcher, err := api.PodDisruptionBudgets(metav1.NamespaceAll).Watch(listOptions)
+	if err != nil {
+		pw.Logger.WithField("error", err).Error("Issues when setting up PDB watcher. Aborting...")
+	} else {
+
+		ch := watcher.ResultChan()
+
+		for ev := range ch {
+			pdb, ok := ev.Object.(*policyv1beta1.PodDisruptionBudget)
+			if !ok {
+				pw.Logger.Warn("Expected PodDisruptionBudget, but received an object of an unknown type. ")
+				continue
+			}
+			switch ev.Type {
+			case watch.Added:
+				pw.onNewPDB(pdb)
+				break
+
+			case watch.Deleted:
+				pw.onDeletePDB(pdb)
+				break
+
+			case watch.Modified:
+				pw.onUpdatePDB(pdb)
+				break
+			}
+
+		}
+	}
+	close(stop)
+	pw.Logger.Info("Exiting PDB watcher.")
+}
+
+func (pw PDBWatcher) onNewPDB(pdb *policyv1beta1.PodDisruptionBudget) {
+	if !pw.qualifies(pdb) {
+		return
+	}
+	pw.updateMap(pdb)
+}
+
+func (pw PDBWatcher) onDeletePDB(pdb *policyv1beta1.PodDisruptionBudget) {
+	if !pw.qualifies(pdb) {
+		return
+	}
+	key := utils.GetKey(pdb.Namespace, pdb.Name)
+	lockPDB.Lock()
+	defer lockPDB.Unlock()
+	delete(pw.PDBCache, key)
+	delete(pw.UpdatedCache, key)
+}
+
+func (pw PDBWatcher) onUpdatePDB(pdb *policyv1beta1.PodDisruptionBudget) {
+	if !pw.qualifies(pdb) {
+		return
+	}
+	pw.updateMap(pdb)
+}
+
+func (pw PDBWatcher) updateMap(pdb *policyv1beta1.PodDisruptionBudget) {
+	bag := (*pw.ConfManager).Get()
+	lockPDB.Lock()
+	defer lockPDB.Unlock()
+	key := utils.GetKey(pdb.Namespace, pdb.Name)
+	pw.PDBCache[key] = *pdb
+	schema := m.NewPdbSchema(pdb)
+	schema.ClusterName = bag.AppName
+	pw.UpdatedCache[key] = schema
+}
+
+func (pw PDBWatcher) CloneMap() map[string]policyv1beta1.PodDisruptionBudget {
+	lockPDB.RLock()
+	defer lockPDB.RUnlock()
+	m := make(map[string]policyv1beta1.PodDisruptionBudget)
+	for key, val := range pw.PDBCache {
+		m[key] = val
+	}
+
+	return m
+}
+
+//finds the budget that selects pods with the given labels
+func (pw PDBWatcher) GetMatchingPDB(namespace string, podLabels map[string]string) (*policyv1beta1.PodDisruptionBudget, bool) {
+	lockPDB.RLock()
+	defer lockPDB.RUnlock()
+	for _, pdb := range pw.PDBCache {
+		if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			pw.Logger.Debugf("Invalid selector of PDB %s/%s. %v\n", pdb.Namespace, pdb.Name, err)
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(podLabels)) {
+			found := pdb
+			return &found, true
+		}
+	}
+	return nil, false
+}
+
+func (pw *PDBWatcher) postPDBRecords() {
+	lockPDB.Lock()
+	updated := []m.PdbSchema{}
+	for key, schema := range pw.UpdatedCache {
+		updated = append(updated, schema)
+		delete(pw.UpdatedCache, key)
+	}
+	lockPDB.Unlock()
+
+	if len(updated) == 0 {
+		return
+	}
+
+	pw.Logger.WithField("count", len(updated)).Info("About to send PDB records")
+	bag := (*pw.ConfManager).Get()
+
+	for start := 0; start < len(updated); start += bag.EventAPILimit {
+		end := start + bag.EventAPILimit
+		if end > len(updated) {
+			end = len(updated)
+		}
+		batch := updated[start:end]
+		pw.postPDBBatchRecords(&batch)
+	}
+}
+
+func (pw *PDBWatcher) postPDBBatchRecords(objList *[]m.PdbSchema) {
+	bag := (*pw.ConfManager).Get()
+	schemaDefObj := m.NewPdbSchemaDefWrapper()
+	err := pw.Sinks.PostEvents(bag.PdbSchemaName, &schemaDefObj, objList)
+	if err != nil {
+		pw.Logger.WithFields(log.Fields{"name": bag.PdbSchemaName, "error": err}).Error("Issues when publishing records")
+	}
+}
@@ -1,7 +1,6 @@
 package watchers
 
 import (
-	"encoding/json"
 	"sync"
 	"time"
 
@@ -26,12 +25,13 @@ type RQWatcher struct {
 	Client       *kubernetes.Clientset
 	RQCache      map[string]v1.ResourceQuota
 	ConfManager  *config.MutexConfigManager
+	Sinks        *app.SinkSet
 	UpdatedCache map[string]m.RqSchema
 	Logger       *log.Logger
 }
 
-func NewRQWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.ResourceQuota, l *log.Logger) *RQWatcher {
-	epw := RQWatcher{Client: client, RQCache: *cache, ConfManager: cm, UpdatedCache: make(map[string]m.RqSchema), Logger: l}
+func NewRQWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, sinks *app.SinkSet, cache *map[string]v1.ResourceQuota, l *log.Logger) *RQWatcher {
+	epw := RQWatcher{Client: client, RQCache: *cache, ConfManager: cm, Sinks: sinks, UpdatedCache: make(map[string]m.RqSchema), Logger: l}
 	return &epw
 }
 
@@ -174,19 +174,11 @@ func (pw *RQWatcher) postRQRecords() {
 
 func (pw *RQWatcher) postRQBatchRecords(objList *[]m.RqSchema) {
 	bag := (*pw.ConfManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewRqSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.RqSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.RqSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.WithFields(log.Fields{"name": bag.RqSchemaName, "error": err}).Error("Issues when ensuring %s schema.")
+		pw.Logger.WithFields(log.Fields{"name": bag.RqSchemaName, "error": err}).Error("Issues when publishing records")
 	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.WithFields(log.Fields{"error": err}).Error("Problems when serializing array of resource quota schemas")
-		}
-		rc.PostAppDEvents(bag.RqSchemaName, data)
 		pw.UpdatedCache = make(map[string]m.RqSchema)
 	}
 
@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
@@ -23,13 +24,15 @@ type SecretWathcer struct {
 	ConfManager *config.MutexConfigManager
 	Listener    *WatchListener
 	UpdateDelay bool
+	Recorder    *ChangeRecorder
+	Versions    map[string]m.ConfigVersion
 	Logger      *log.Logger
 }
 
 var lockSecrets = sync.RWMutex{}
 
-func NewSecretWathcer(client *kubernetes.Clientset, secret *config.MutexConfigManager, cache *map[string]v1.Secret, listener WatchListener, l *log.Logger) *SecretWathcer {
-	sw := SecretWathcer{Client: client, SecretCache: *cache, ConfManager: secret, Listener: &listener, Logger: l}
+func NewSecretWathcer(client *kubernetes.Clientset, secret *config.MutexConfigManager, cache *map[string]v1.Secret, listener WatchListener, recorder *ChangeRecorder, l *log.Logger) *SecretWathcer {
+	sw := SecretWathcer{Client: client, SecretCache: *cache, ConfManager: secret, Listener: &listener, Recorder: recorder, Versions: make(map[string]m.ConfigVersion), Logger: l}
 	sw.UpdateDelay = true
 	return &sw
 }
@@ -98,8 +101,9 @@ func (pw SecretWathcer) onDeleteConfig(secret *v1.Secret) {
 	_, ok := pw.SecretCache[utils.GetSecretKey(secret)]
 	if ok {
 		lockSecrets.Lock()
-		defer lockSecrets.Unlock()
 		delete(pw.SecretCache, utils.GetSecretKey(secret))
+		delete(pw.Versions, utils.GetSecretKey(secret))
+		lockSecrets.Unlock()
 		pw.notifyListener(secret.Namespace)
 	}
 }
@@ -108,9 +112,24 @@ func (pw SecretWathcer) onUpdateConfig(secret *v1.Secret) {
 	if !pw.qualifies(secret) {
 		return
 	}
+	pw.recordChanges(secret)
 	pw.updateMap(secret)
 }
 
+func (pw SecretWathcer) recordChanges(secret *v1.Secret) {
+	if secret.Type == v1.SecretTypeServiceAccountToken {
+		return
+	}
+	lockSecrets.RLock()
+	old, ok := pw.SecretCache[utils.GetSecretKey(secret)]
+	lockSecrets.RUnlock()
+	if !ok || old.ResourceVersion == secret.ResourceVersion {
+		return
+	}
+	changes := utils.DiffConfigData(utils.SecretDataToStrings(old.Data), utils.SecretDataToStrings(secret.Data), true)
+	pw.Recorder.RecordChanges(m.CHANGE_KIND_SECRET, &secret.ObjectMeta, changes)
+}
+
 func (pw SecretWathcer) notifyListener(namespace string) {
 	if pw.Listener != nil && !pw.UpdateDelay {
 		(*pw.Listener).CacheUpdated(namespace)
@@ -119,11 +138,31 @@ func (pw SecretWathcer) notifyListener(namespace string) {
 
 func (pw SecretWathcer) updateMap(secret *v1.Secret) {
 	lockSecrets.Lock()
-	defer lockSecrets.Unlock()
-	pw.SecretCache[utils.GetSecretKey(secret)] = *secret
+	key := utils.GetSecretKey(secret)
+	pw.SecretCache[key] = *secret
+
+	hash := utils.HashConfigData(nil, secret.Data)
+	version, known := pw.Versions[key]
+	if !known {
+		version.Updated = utils.GetLastUpdateTime(&secret.ObjectMeta)
+	} else if version.Hash != hash {
+		version.Updated = time.Now()
+	}
+	version.ResourceVersion = secret.ResourceVersion
+	version.Hash = hash
+	pw.Versions[key] = version
+	lockSecrets.Unlock()
+
 	pw.notifyListener(secret.Namespace)
 }
 
+func (pw SecretWathcer) GetVersion(namespace string, name string) (m.ConfigVersion, bool) {
+	lockSecrets.RLock()
+	defer lockSecrets.RUnlock()
+	version, ok := pw.Versions[utils.GetKey(namespace, name)]
+	return version, ok
+}
+
 func (pw SecretWathcer) CloneMap() map[string]v1.Secret {
 	lockSecrets.RLock()
 	defer lockSecrets.RUnlock()
@@ -0,0 +1,123 @@
+package watchers
+
+import (
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/watch"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+)
+
+var lockStatefulSets = sync.RWMutex{}
+
+type StatefulSetWatcher struct {
+	Client      *kubernetes.Clientset
+	STSCache    map[string]appsv1.StatefulSet
+	ConfManager *config.MutexConfigManager
+	Recorder    *ChangeRecorder
+	Logger      *log.Logger
+}
+
+func NewStatefulSetWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]appsv1.StatefulSet, recorder *ChangeRecorder, l *log.Logger) *StatefulSetWatcher {
+	sw := StatefulSetWatcher{Client: client, STSCache: *cache, ConfManager: cm, Recorder: recorder, Logger: l}
+	return &sw
+}
+
+func (pw StatefulSetWatcher) WatchStatefulSets() {
+	api := pw.Client.AppsV1()
+	listOptions := metav1.ListOptions{}
+	pw.Logger.Info("Starting StatefulSet Watcher...")
+
+	watcher, err := api.StatefulSets(metav1.NamespaceAll).Watch(listOptions)
+	if err != nil {
+		pw.Logger.WithField("error", err).Error("Issues when setting up StatefulSet watcher. Aborting...")
+	} else {
+
+		ch := watcher.ResultChan()
+
+		for ev := range ch {
+			sts, ok := ev.Object.(*appsv1.StatefulSet)
+			if !ok {
+				pw.Logger.Warn("Expected StatefulSet, but received an object of an unknown type.")
+				continue
+			}
+			switch ev.Type {
+			case watch.Added:
+				pw.onNewStatefulSet(sts)
+				break
+
+			case watch.Deleted:
+				pw.onDeleteStatefulSet(sts)
+				break
+
+			case watch.Modified:
+				pw.onUpdateStatefulSet(sts)
+				break
+			}
+
+		}
+	}
+	pw.Logger.Info("Exiting StatefulSet watcher.")
+}
+
+func (pw *StatefulSetWatcher) qualifies(sts *appsv1.StatefulSet) bool {
+	bag := pw.ConfManager.Get()
+	return utils.NSQualifiesForMonitoring(sts.Namespace, bag)
+}
+
+func (pw StatefulSetWatcher) onNewStatefulSet(sts *appsv1.StatefulSet) {
+	if !pw.qualifies(sts) {
+		return
+	}
+	pw.updateMap(sts)
+}
+
+func (pw StatefulSetWatcher) onDeleteStatefulSet(sts *appsv1.StatefulSet) {
+	pw.Logger.WithFields(log.Fields{"name": sts.Name, "namespace": sts.Namespace}).Debug("StatefulSet deleted")
+	if !pw.qualifies(sts) {
+		return
+	}
+	lockStatefulSets.Lock()
+	defer lockStatefulSets.Unlock()
+	delete(pw.STSCache, utils.GetKey(sts.Namespace, sts.Name))
+}
+
+func (pw StatefulSetWatcher) onUpdateStatefulSet(sts *appsv1.StatefulSet) {
+	if !pw.qualifies(sts) {
+		return
+	}
+	lockStatefulSets.RLock()
+	old, ok := pw.STSCache[utils.GetKey(sts.Namespace, sts.Name)]
+	lockStatefulSets.RUnlock()
+	if ok && old.Generation != sts.Generation {
+		changes := utils.DiffReplicas(old.Spec.Replicas, sts.Spec.Replicas)
+		changes = append(changes, utils.DiffPodTemplates(&old.Spec.Template, &sts.Spec.Template)...)
+		pw.Recorder.RecordChanges(m.CHANGE_KIND_STATEFULSET, &sts.ObjectMeta, changes)
+	}
+	pw.updateMap(sts)
+}
+
+func (pw StatefulSetWatcher) updateMap(sts *appsv1.StatefulSet) {
+	lockStatefulSets.Lock()
+	defer lockStatefulSets.Unlock()
+	pw.STSCache[utils.GetKey(sts.Namespace, sts.Name)] = *sts
+}
+
+func (pw StatefulSetWatcher) CloneMap() map[string]appsv1.StatefulSet {
+	lockStatefulSets.RLock()
+	defer lockStatefulSets.RUnlock()
+	m := make(map[string]appsv1.StatefulSet)
+	for key, val := range pw.STSCache {
+		m[key] = val
+	}
+	return m
+}
@@ -19,8 +19,12 @@ import (
 )
 
 type AgentWebServer struct {
-	ConfigManager *config.MutexConfigManager
-	Logger        *log.Logger
+	ConfigManager  *config.MutexConfigManager
+	MetricsHandler http.Handler            //Prometheus exposition of the metrics. Nil if not enabled
+	SpoolStatus    func() *m.SpoolStatus   //state of the Events API spool. Nil if not enabled
+	Schemas        func() []m.SchemaStatus //live vs expected analytics schemas. Nil if not enabled
+	CircuitStates  func() map[string]string
+	Logger         *log.Logger
 }
 
 func NewAgentWebServer(c *config.MutexConfigManager, l *log.Logger) *AgentWebServer {
@@ -33,6 +37,12 @@ func (ws *AgentWebServer) RunServer() {
 	r := mux.NewRouter()
 	r.HandleFunc("/version", ws.getVersion)
 	r.HandleFunc("/status", ws.getStatus)
+	if ws.MetricsHandler != nil {
+		r.Handle("/metrics", ws.MetricsHandler)
+	}
+	if ws.Schemas != nil {
+		r.HandleFunc("/schemas", ws.getSchemas)
+	}
 	addr := fmt.Sprintf(":%d", bag.AgentServerPort)
 	server := &http.Server{Addr: addr, Handler: r}
 
@@ -91,6 +101,12 @@ func (ws *AgentWebServer) getStatus(w http.ResponseWriter, req *http.Request) {
 		statusObj.LogLines = bag.LogLines
 		statusObj.MetricsSyncInterval = bag.MetricsSyncInterval
 		statusObj.SnapshotSyncInterval = bag.SnapshotSyncInterval
+		if ws.SpoolStatus != nil {
+			statusObj.Spool = ws.SpoolStatus()
+		}
+		if ws.CircuitStates != nil {
+			statusObj.Endpoints = ws.CircuitStates()
+		}
 
 		result, _ := json.Marshal(statusObj)
 		io.WriteString(w, string(result))
@@ -98,3 +114,13 @@ func (ws *AgentWebServer) getStatus(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Only GET is supported", 404)
 	}
 }
+
+func (ws *AgentWebServer) getSchemas(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "GET" {
+		w.Header().Set("Content-Type", "application/json")
+		result, _ := json.Marshal(ws.Schemas())
+		io.WriteString(w, string(result))
+	} else {
+		http.Error(w, "Only GET is supported", 404)
+	}
+}
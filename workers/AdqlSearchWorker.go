@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,14 +20,19 @@ const (
 	BASE_PATH               string = "Application Infrastructure Performance|%s|Custom Metrics|Cluster Stats|"
 )
 
+//incident linked by the crash search of each pod. Kept across dashboard updates
+var incidentSearches = make(map[string]string)
+var lockIncidentSearches = sync.Mutex{}
+
 type AdqlSearchWorker struct {
-	Bag         *m.AppDBag
-	SearchCache map[string]m.AdqlSearch
-	Logger      *log.Logger
+	Bag             *m.AppDBag
+	SearchCache     map[string]m.AdqlSearch
+	LinkedIncidents map[string]bool //crash searches linked by the dashboard being built
+	Logger          *log.Logger
 }
 
 func NewAdqlSearchWorker(bag *m.AppDBag, l *log.Logger) AdqlSearchWorker {
-	aw := AdqlSearchWorker{Bag: bag, SearchCache: make(map[string]m.AdqlSearch), Logger: l}
+	aw := AdqlSearchWorker{Bag: bag, SearchCache: make(map[string]m.AdqlSearch), LinkedIncidents: make(map[string]bool), Logger: l}
 	aw.CacheSearches()
 	return aw
 }
@@ -60,6 +66,80 @@ func (aw *AdqlSearchWorker) GetSearch(metricPath string) string {
 	return search
 }
 
+//search of spec changes of the objects of the tier
+func (aw *AdqlSearchWorker) GetChangesSearch(namespace string, tierName string) string {
+	searchName := aw.buildFullMetricName(fmt.Sprintf("Changes %s/%s", namespace, tierName))
+	if searchObj, ok := aw.SearchCache[searchName]; ok {
+		return fmt.Sprintf(DRILL_DOWN_URL_TEMPLATE, aw.Bag.RestAPIUrl, searchObj.ID)
+	}
+	sObj := m.AdqlSearch{SchemaDef: m.ChangeSchemaDef{}, SearchName: searchName, SchemaName: aw.Bag.ChangeSchemaName,
+		Query: fmt.Sprintf("select * from %s where clusterName = '%s' and namespace = '%s' and tierName = '%s' ORDER BY changeTimestamp DESC", aw.Bag.ChangeSchemaName, aw.Bag.AppName, namespace, tierName)}
+	obj, err := aw.CreateSearch(&sObj)
+	if err != nil {
+		aw.Logger.Printf("Unable to save search object. %v\n", err)
+		return ""
+	}
+	aw.SearchCache[searchName] = *obj
+	search := fmt.Sprintf(DRILL_DOWN_URL_TEMPLATE, aw.Bag.RestAPIUrl, obj.ID)
+	aw.Logger.Printf("Search object created and cached: %s\n", search)
+	return search
+}
+
+/*
+ Search of the forensics record of the last crash incident of the pod.
+ One search is kept per pod. It is replaced when the pod crashes again
+*/
+func (aw *AdqlSearchWorker) GetIncidentSearch(namespace string, podName string, incidentID string) string {
+	searchName := aw.buildFullMetricName(fmt.Sprintf("Crash %s/%s", namespace, podName))
+	aw.LinkedIncidents[searchName] = true
+
+	lockIncidentSearches.Lock()
+	defer lockIncidentSearches.Unlock()
+	if searchObj, ok := aw.SearchCache[searchName]; ok {
+		if incidentSearches[searchName] == incidentID {
+			return fmt.Sprintf(DRILL_DOWN_URL_TEMPLATE, aw.Bag.RestAPIUrl, searchObj.ID)
+		}
+		//the search links an earlier incident of the pod
+		if err := aw.DeleteSearch(int(searchObj.ID)); err != nil {
+			aw.Logger.Warnf("Unable to replace search %s. %v\n", searchName, err)
+			return ""
+		}
+		delete(aw.SearchCache, searchName)
+		delete(incidentSearches, searchName)
+	}
+	sObj := m.AdqlSearch{SchemaDef: m.CrashSchemaDef{}, SearchName: searchName, SchemaName: aw.Bag.CrashSchemaName,
+		Query: fmt.Sprintf("select * from %s where clusterName = '%s' and incidentId = '%s'", aw.Bag.CrashSchemaName, aw.Bag.AppName, incidentID)}
+	obj, err := aw.CreateSearch(&sObj)
+	if err != nil {
+		aw.Logger.Printf("Unable to save search object. %v\n", err)
+		return ""
+	}
+	aw.SearchCache[searchName] = *obj
+	incidentSearches[searchName] = incidentID
+	search := fmt.Sprintf(DRILL_DOWN_URL_TEMPLATE, aw.Bag.RestAPIUrl, obj.ID)
+	aw.Logger.Printf("Search object created and cached: %s\n", search)
+	return search
+}
+
+//deletes the crash searches created by the agent for the pods that are no longer linked from the dashboard
+func (aw *AdqlSearchWorker) PruneIncidentSearches() {
+	lockIncidentSearches.Lock()
+	defer lockIncidentSearches.Unlock()
+	for searchName := range incidentSearches {
+		if aw.LinkedIncidents[searchName] {
+			continue
+		}
+		if searchObj, ok := aw.SearchCache[searchName]; ok {
+			if err := aw.DeleteSearch(int(searchObj.ID)); err != nil {
+				aw.Logger.Warnf("Unable to delete search %s. %v\n", searchName, err)
+				continue
+			}
+			delete(aw.SearchCache, searchName)
+		}
+		delete(incidentSearches, searchName)
+	}
+}
+
 func (aw *AdqlSearchWorker) CacheSearches() error {
 	rc := app.NewRestClient(aw.Bag, aw.Logger)
 	data, err := rc.CallAppDController("restui/analyticsSavedSearches/getAllAnalyticsSavedSearches", "GET", nil)
@@ -206,6 +286,10 @@ func (aw *AdqlSearchWorker) getQueryMap() map[string]m.AdqlSearch {
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' ORDER by name", aw.Bag.NsSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "OrphanEndpoint": m.AdqlSearch{SchemaDef: m.EpSchemaDef{}, SearchName: fmt.Sprintf("%s. OrphanEndpoint", aw.Bag.AppName), SchemaName: aw.Bag.EpSchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and isOrphan = true", aw.Bag.EpSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "SingleReplicaNoPdb": m.AdqlSearch{SchemaDef: m.DeploySchemaDef{}, SearchName: fmt.Sprintf("%s. SingleReplicaNoPdb", aw.Bag.AppName), SchemaName: aw.Bag.DeploySchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and drainRisk = '%s' ORDER by namespace, name", aw.Bag.DeploySchemaName, aw.Bag.AppName, m.DRAIN_RISK_NO_PDB_SINGLE_REPLICA)},
+		BASE_PATH + "PdbZeroDisruptions": m.AdqlSearch{SchemaDef: m.PdbSchemaDef{}, SearchName: fmt.Sprintf("%s. PdbZeroDisruptions", aw.Bag.AppName), SchemaName: aw.Bag.PdbSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and blocksDrain = true ORDER by namespace, name", aw.Bag.PdbSchemaName, aw.Bag.AppName)},
 	}
 
 	return queryMap
@@ -3,6 +3,7 @@ package workers
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,14 +14,18 @@ import (
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	"github.com/appdynamics/cluster-agent/web"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 
 	app "github.com/appdynamics/cluster-agent/appd"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
 )
 
 const (
@@ -36,10 +41,17 @@ type MainController struct {
 	PodsWorker     *PodWorker
 	NodesWorker    *NodesWorker
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
+	ChangeRecorder *w.ChangeRecorder
+	STSCache       map[string]appsv1.StatefulSet
+	PDBWatcher     *w.PDBWatcher
+	PDBCache       map[string]policyv1beta1.PodDisruptionBudget
+	OwnerResolver  *OwnerResolver
 }
 
 func NewController(cm *config.MutexConfigManager, client *kubernetes.Clientset, l *log.Logger, config *rest.Config) MainController {
-	return MainController{ConfManager: cm, K8sClient: client, Logger: l, K8sConfig: config}
+	return MainController{ConfManager: cm, K8sClient: client, Logger: l, K8sConfig: config, STSCache: make(map[string]appsv1.StatefulSet),
+		PDBCache: make(map[string]policyv1beta1.PodDisruptionBudget), OwnerResolver: NewOwnerResolver("ReplicaSet", "Job")}
 }
 
 func (c *MainController) ValidateParameters() error {
@@ -99,8 +111,8 @@ func (c *MainController) ValidateParameters() error {
 	}
 	c.Logger.Infof("Controller URL: %s, Controller port: %d, Event URL: %s", bag.ControllerUrl, bag.ControllerPort, bag.EventServiceUrl)
 	//validate keys
-	if bag.RestAPICred == "" {
-		return fmt.Errorf("Rest API user account is required. Create a user account in AppD and add it to the cluster-agent-secret (key api-user) in this form <user>@<account>:<pass>")
+	if bag.RestAPICred == "" && bag.RestAPIClient == "" {
+		return fmt.Errorf("Rest API user account or API client is required. Create a user account in AppD and add it to the cluster-agent-secret (key api-user) in this form <user>@<account>:<pass>, or an API client (key api-client) in this form <clientName>@<account>:<secret>")
 	}
 	if bag.AccessKey == "" || bag.Account == "" || bag.GlobalAccount == "" {
 		app.ValidateAccount(bag, c.Logger)
@@ -133,9 +145,14 @@ func (c *MainController) ValidateParameters() error {
 	}
 	c.ConfManager.Set(bag)
 	c.Logger.WithFields(log.Fields{"accessKey": bag.AccessKey, "global account": bag.GlobalAccount}).Debug("Account info")
-	appdC, errInitSdk := app.NewControllerClient(c.ConfManager, c.Logger)
-	if errInitSdk != nil {
-		return fmt.Errorf("Unable to initialize AppDynamics Golang SDK. %v. Metrics collection will not be possible", errInitSdk)
+	sinks, errSinks := app.NewSinkSet(c.ConfManager, c.Logger)
+	if errSinks != nil {
+		return fmt.Errorf("Unable to initialize telemetry sinks. %v. Metrics collection will not be possible", errSinks)
+	}
+	c.Sinks = sinks
+	appdC, errController := app.NewControllerClient(c.ConfManager, c.Logger)
+	if errController != nil {
+		return fmt.Errorf("Unable to initialize the controller client. %v", errController)
 	}
 	c.AppdController = appdC
 	return nil
@@ -144,6 +161,17 @@ func (c *MainController) ValidateParameters() error {
 func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 
 	ws := web.NewAgentWebServer(c.ConfManager, c.Logger)
+	if exporter, ok := c.Sinks.MetricSinks[m.SINK_PROMETHEUS].(http.Handler); ok {
+		ws.MetricsHandler = exporter
+	}
+	if eventSink, ok := c.Sinks.EventSinks[m.SINK_APPD].(*app.AppDEventSink); ok {
+		ws.Schemas = app.GetSchemaStates
+		if eventSink.Spool != nil {
+			ws.SpoolStatus = eventSink.Spool.Status
+			eventSink.Spool.Start(stopCh)
+		}
+	}
+	ws.CircuitStates = app.GetCircuitStates
 	wg.Add(1)
 	go ws.RunServer()
 
@@ -153,6 +181,15 @@ func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 		go c.startAppIDUpdater(stopCh)
 	}
 
+	c.ChangeRecorder = w.NewChangeRecorder(c.ConfManager, c.Sinks, c.Logger)
+	c.ChangeRecorder.Start(stopCh)
+
+	stsWatcher := w.NewStatefulSetWatcher(c.K8sClient, c.ConfManager, &c.STSCache, c.ChangeRecorder, c.Logger)
+	go stsWatcher.WatchStatefulSets()
+
+	c.PDBWatcher = w.NewPDBWatcher(c.K8sClient, c.ConfManager, c.Sinks, &c.PDBCache, c.Logger)
+	go c.PDBWatcher.WatchPDBs()
+
 	wg.Add(3)
 	go c.startNodeWorker(stopCh, c.K8sClient, wg, c.AppdController)
 
@@ -168,6 +205,11 @@ func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	wg.Add(1)
 	go c.startJobsWorker(stopCh, c.K8sClient, wg, c.AppdController)
 
+	if len(bag.CustomResources) > 0 {
+		wg.Add(1)
+		go c.startCustomResourceWorker(stopCh, wg, c.AppdController)
+	}
+
 }
 
 func (c *MainController) startAppIDUpdater(stopCh <-chan struct{}) {
@@ -202,7 +244,7 @@ func (c *MainController) appAppIDTicker(stop <-chan struct{}, ticker *time.Ticke
 func (c *MainController) startNodeWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Nodes worker...")
 	defer wg.Done()
-	nw := NewNodesWorker(client, c.ConfManager, appdController, c.Logger)
+	nw := NewNodesWorker(client, c.ConfManager, appdController, c.Sinks, c.Logger)
 	c.NodesWorker = &nw
 	go c.startPodsWorker(stopCh, client, wg, appdController)
 	nw.Observe(stopCh, wg)
@@ -213,7 +255,7 @@ func (c *MainController) startNodeWorker(stopCh <-chan struct{}, client *kuberne
 func (c *MainController) startDeployWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Deployment worker...")
 	defer wg.Done()
-	pw := NewDeployWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewDeployWorker(client, c.ConfManager, appdController, c.Sinks, c.ChangeRecorder, c.PDBWatcher, c.Logger)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -221,7 +263,7 @@ func (c *MainController) startDeployWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startDaemonWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Daemon worker...")
 	defer wg.Done()
-	pw := NewDaemonWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewDaemonWorker(client, c.ConfManager, appdController, c.Sinks, c.ChangeRecorder, c.Logger)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -229,7 +271,7 @@ func (c *MainController) startDaemonWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startRsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting ReplicaSet worker...")
 	defer wg.Done()
-	pw := NewRsWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewRsWorker(client, c.ConfManager, appdController, c.Sinks, c.OwnerResolver, c.Logger)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -237,7 +279,7 @@ func (c *MainController) startRsWorker(stopCh <-chan struct{}, client *kubernete
 func (c *MainController) startEventsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Events worker...")
 	defer wg.Done()
-	ew := NewEventWorker(client, c.ConfManager, appdController, c.PodsWorker, c.Logger)
+	ew := NewEventWorker(client, c.ConfManager, appdController, c.Sinks, c.PodsWorker, c.OwnerResolver, c.Logger)
 	ew.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -245,15 +287,28 @@ func (c *MainController) startEventsWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startJobsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Jobs worker...")
 	defer wg.Done()
-	ew := NewJobsWorker(client, c.ConfManager, appdController, c.K8sConfig, c.Logger)
+	ew := NewJobsWorker(client, c.ConfManager, appdController, c.Sinks, c.K8sConfig, c.OwnerResolver, c.Logger)
 	ew.Observe(stopCh, wg)
 	<-stopCh
 }
 
+func (c *MainController) startCustomResourceWorker(stopCh <-chan struct{}, wg *sync.WaitGroup, appdController *app.ControllerClient) {
+	c.Logger.Info("Starting Custom Resource worker...")
+	defer wg.Done()
+	cw, err := NewCustomResourceWorker(c.ConfManager, appdController, c.Sinks, c.K8sConfig, c.Logger)
+	if err != nil {
+		c.Logger.Errorf("Unable to start Custom Resource worker. %v", err)
+		return
+	}
+	wg.Add(1)
+	cw.Observe(stopCh, wg)
+	<-stopCh
+}
+
 func (c *MainController) startPodsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Pods worker...")
 	defer wg.Done()
-	pw := NewPodWorker(client, c.ConfManager, appdController, c.K8sConfig, c.Logger, c.NodesWorker)
+	pw := NewPodWorker(client, c.ConfManager, appdController, c.Sinks, c.K8sConfig, c.Logger, c.NodesWorker, c.ChangeRecorder, c.PDBWatcher, c.OwnerResolver)
 	c.PodsWorker = &pw
 	go c.startEventsWorker(stopCh, c.K8sClient, wg, appdController)
 	c.PodsWorker.Observe(stopCh, wg)
@@ -370,3 +425,20 @@ func (c *MainController) EnsureEventAPIKey(bag *m.AppDBag) (string, error) {
 	}
 	return key, nil
 }
+
+/*
+ Drops the queued records while the snapshots are paused, so that the queues do not grow until the Events API recovers.
+ The objects are published again with their next change
+*/
+func discardQueue(wq workqueue.RateLimitingInterface) int {
+	count := wq.Len()
+	for i := 0; i < count; i++ {
+		item, quit := wq.Get()
+		if quit {
+			return i
+		}
+		wq.Forget(item)
+		wq.Done(item)
+	}
+	return count
+}
@@ -0,0 +1,184 @@
+package workers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+var lockHealthyRevisions = sync.RWMutex{}
+var lockIncidents = sync.RWMutex{}
+
+//labels carrying the hash of the pod template the pod was created from
+var revisionLabels = []string{"pod-template-hash", "controller-revision-hash"}
+
+//the pod template of the last revision of a tier whose pods were running and ready
+type healthyRevision struct {
+	Revision string
+	Template v1.PodTemplateSpec
+}
+
+func getPodRevision(p *v1.Pod) string {
+	for _, l := range revisionLabels {
+		if rev, ok := p.Labels[l]; ok {
+			return rev
+		}
+	}
+	return ""
+}
+
+func getPodTemplate(p *v1.Pod) v1.PodTemplateSpec {
+	template := v1.PodTemplateSpec{}
+	template.Labels = p.Labels
+	template.Annotations = p.Annotations
+	template.Spec = p.Spec
+	return template
+}
+
+func isPodHealthy(p *v1.Pod) bool {
+	if p.DeletionTimestamp != nil || p.Status.Phase != v1.PodRunning || len(p.Status.ContainerStatuses) == 0 {
+		return false
+	}
+	for _, st := range p.Status.ContainerStatuses {
+		if !st.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+//remembers the revision of the tier that was last seen healthy
+func (pw PodWorker) trackHealthyRevision(p *v1.Pod, podObject *m.PodSchema) {
+	if !isPodHealthy(p) {
+		return
+	}
+	revision := getPodRevision(p)
+	if revision == "" {
+		return
+	}
+	key := utils.GetKey(podObject.Namespace, podObject.Owner)
+	lockHealthyRevisions.Lock()
+	defer lockHealthyRevisions.Unlock()
+	if last, ok := pw.HealthyRevisions[key]; ok && last.Revision == revision {
+		return
+	}
+	pw.HealthyRevisions[key] = healthyRevision{Revision: revision, Template: getPodTemplate(p)}
+}
+
+func (pw PodWorker) getHealthyRevision(podObject *m.PodSchema) (healthyRevision, bool) {
+	lockHealthyRevisions.RLock()
+	defer lockHealthyRevisions.RUnlock()
+	rev, ok := pw.HealthyRevisions[utils.GetKey(podObject.Namespace, podObject.Owner)]
+	return rev, ok
+}
+
+//returns the id of the last crash incident of the pod
+func (pw *PodWorker) GetLastIncident(namespace, podName string) string {
+	lockIncidents.RLock()
+	defer lockIncidents.RUnlock()
+	return pw.Incidents[utils.GetKey(namespace, podName)]
+}
+
+//returns false if the incident has already been registered
+func (pw PodWorker) registerIncident(podObject *m.PodSchema, incidentID string) bool {
+	lockIncidents.Lock()
+	defer lockIncidents.Unlock()
+	key := utils.GetKey(podObject.Namespace, podObject.Name)
+	if pw.Incidents[key] == incidentID {
+		return false
+	}
+	pw.Incidents[key] = incidentID
+	return true
+}
+
+func (pw *PodWorker) clearIncidents(podObject *m.PodSchema) {
+	lockIncidents.Lock()
+	defer lockIncidents.Unlock()
+	delete(pw.Incidents, utils.GetKey(podObject.Namespace, podObject.Name))
+}
+
+//forgets the healthy revisions of the owners and the incidents of the pods that no longer exist
+func (pw *PodWorker) pruneCrashForensics(owners map[string]bool, pods map[string]bool) {
+	lockHealthyRevisions.Lock()
+	for key := range pw.HealthyRevisions {
+		if !owners[key] {
+			delete(pw.HealthyRevisions, key)
+		}
+	}
+	lockHealthyRevisions.Unlock()
+
+	lockIncidents.Lock()
+	defer lockIncidents.Unlock()
+	for key := range pw.Incidents {
+		if !pods[key] {
+			delete(pw.Incidents, key)
+		}
+	}
+}
+
+func formatSpecChanges(changes []m.FieldChange) string {
+	list := []string{}
+	for _, c := range changes {
+		list = append(list, fmt.Sprintf("%s: %s -> %s", c.Field, c.OldValue, c.NewValue))
+	}
+	return strings.Join(list, "; ")
+}
+
+/*
+ Collects the forensics of a failed container: termination state, resource usage before the failure,
+ recent pod events, the spec changes since the last healthy revision and the logs of the failed instance.
+ The logs are read and the record is posted asynchronously
+*/
+func (pw PodWorker) collectCrashForensics(p *v1.Pod, podObject *m.PodSchema, containerObj *m.ContainerSchema, lastSnapshot *m.ContainerSchema, term *v1.ContainerStateTerminated, previous bool) {
+	bag := (*pw.ConfManager).Get()
+	record := m.NewCrashSchema(podObject, containerObj, term)
+	if !pw.registerIncident(podObject, record.IncidentID) {
+		return
+	}
+
+	//usage is sampled periodically. The last snapshot reflects the state before the failure
+	record.CpuUse = lastSnapshot.CpuUse
+	record.MemUse = lastSnapshot.MemUse
+	record.ConsumptionCpu = lastSnapshot.ConsumptionCpu
+	record.ConsumptionMem = lastSnapshot.ConsumptionMem
+	record.CpuLimit = containerObj.CpuLimit
+	record.MemLimit = containerObj.MemLimit
+	record.Events = utils.TruncateString(strings.Join(pw.GetPodEvents(podObject), "; "), app.MAX_FIELD_LENGTH)
+
+	record.Revision = getPodRevision(p)
+	if healthy, ok := pw.getHealthyRevision(podObject); ok {
+		record.HealthyRevision = healthy.Revision
+		if healthy.Revision != record.Revision {
+			current := getPodTemplate(p)
+			record.SpecChanges = utils.TruncateString(formatSpecChanges(utils.DiffPodTemplates(&healthy.Template, &current)), app.MAX_FIELD_LENGTH)
+		}
+	}
+
+	pw.Logger.WithFields(log.Fields{"pod": podObject.Name, "container": containerObj.Name, "incident": record.IncidentID, "cause": record.RestartCause}).Info("Container failure detected")
+
+	var lines int64 = int64(bag.CrashLogLines)
+	po := v1.PodLogOptions{Container: containerObj.Name, Previous: previous, Timestamps: true, TailLines: &lines}
+	go pw.postCrashRecord(record, &po)
+}
+
+func (pw PodWorker) postCrashRecord(record m.CrashSchema, logOptions *v1.PodLogOptions) {
+	if *logOptions.TailLines > 0 {
+		logs, err := pw.readLogs(record.Namespace, record.PodName, logOptions)
+		if err == nil {
+			record.PreviousLogs = utils.TruncateString(strings.TrimSpace(strings.Join(logs, "\n")), app.MAX_FIELD_LENGTH)
+		}
+	}
+
+	bag := (*pw.ConfManager).Get()
+	schemaDefObj := m.NewCrashSchemaDefWrapper()
+	err := pw.Sinks.PostEvents(bag.CrashSchemaName, &schemaDefObj, []m.CrashSchema{record})
+	if err != nil {
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.CrashSchemaName, err)
+	}
+}
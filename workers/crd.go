@@ -0,0 +1,488 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type customResourceInformer struct {
+	informer cache.SharedIndexInformer
+	Config   m.CustomResourceConfig
+}
+
+type CustomResourceWorker struct {
+	informers      []customResourceInformer
+	Client         dynamic.Interface
+	ConfigManager  *config.MutexConfigManager
+	SummaryMap     map[string]m.ClusterCustomResourceMetrics
+	WQ             workqueue.RateLimitingInterface
+	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
+	K8sConfig      *rest.Config
+	Logger         *log.Logger
+}
+
+func NewCustomResourceWorker(cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, config *rest.Config, l *log.Logger) (CustomResourceWorker, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	cw := CustomResourceWorker{ConfigManager: cm, SummaryMap: make(map[string]m.ClusterCustomResourceMetrics), WQ: queue,
+		AppdController: controller, Sinks: sinks, K8sConfig: config, Logger: l}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return cw, fmt.Errorf("Issues when initializing dynamic API client. %v", err)
+	}
+	cw.Client = client
+	cw.initInformers()
+	return cw, nil
+}
+
+func (cw *CustomResourceWorker) initInformers() {
+	bag := (*cw.ConfigManager).Get()
+	for _, crc := range bag.CustomResources {
+		if !crc.IsValid() {
+			cw.Logger.WithFields(log.Fields{"group": crc.Group, "version": crc.Version, "resource": crc.Resource}).
+				Error("Custom resource definition is invalid. Version and Resource are required")
+			continue
+		}
+		cw.informers = append(cw.informers, cw.initCustomResourceInformer(crc))
+	}
+}
+
+func (cw *CustomResourceWorker) initCustomResourceInformer(crc m.CustomResourceConfig) customResourceInformer {
+	gvr := schema.GroupVersionResource{Group: crc.Group, Version: crc.Version, Resource: crc.Resource}
+	client := cw.Client
+	i := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.Resource(gvr).Namespace(metav1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Resource(gvr).Namespace(metav1.NamespaceAll).Watch(options)
+			},
+		},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			cw.onCustomResourceChange(&crc, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			cw.onCustomResourceChange(&crc, obj)
+		},
+		UpdateFunc: func(objOld interface{}, objNew interface{}) {
+			cw.onCustomResourceChange(&crc, objNew)
+		},
+	})
+
+	return customResourceInformer{informer: i, Config: crc}
+}
+
+func (cw *CustomResourceWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer cw.WQ.ShutDown()
+	for _, cri := range cw.informers {
+		wg.Add(1)
+		go cri.informer.Run(stopCh)
+	}
+
+	wg.Add(1)
+	go cw.startMetricsWorker(stopCh)
+
+	wg.Add(1)
+	go cw.startEventQueueWorker(stopCh)
+
+	<-stopCh
+}
+
+func (cw *CustomResourceWorker) qualifies(obj *unstructured.Unstructured) bool {
+	bag := (*cw.ConfigManager).Get()
+	ns := obj.GetNamespace()
+	return ns == "" || utils.NSQualifiesForMonitoring(ns, bag)
+}
+
+func (cw *CustomResourceWorker) onCustomResourceChange(crc *m.CustomResourceConfig, obj interface{}) {
+	crObj, ok := obj.(*unstructured.Unstructured)
+	if !ok || !cw.qualifies(crObj) {
+		return
+	}
+	cw.Logger.Debugf("Custom resource %s %s/%s changed\n", crc.GetKind(), crObj.GetNamespace(), crObj.GetName())
+	record := cw.processObject(crc, crObj)
+	cw.WQ.Add(&record)
+}
+
+func (cw *CustomResourceWorker) startMetricsWorker(stopCh <-chan struct{}) {
+	bag := (*cw.ConfigManager).Get()
+	cw.appMetricTicker(stopCh, time.NewTicker(time.Duration(bag.MetricsSyncInterval)*time.Second))
+}
+
+func (cw *CustomResourceWorker) appMetricTicker(stop <-chan struct{}, ticker *time.Ticker) {
+	for {
+		select {
+		case <-ticker.C:
+			cw.buildAppDMetrics()
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (cw *CustomResourceWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker) {
+	for {
+		select {
+		case <-ticker.C:
+			if cw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(cw.WQ); n > 0 {
+					cw.Logger.Warnf("Events API is unavailable. Dropped %d custom resource records\n", n)
+				}
+				continue
+			}
+			cw.flushQueue()
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (cw *CustomResourceWorker) startEventQueueWorker(stopCh <-chan struct{}) {
+	bag := (*cw.ConfigManager).Get()
+	cw.eventQueueTicker(stopCh, time.NewTicker(time.Duration(bag.SnapshotSyncInterval)*time.Second))
+}
+
+func (cw *CustomResourceWorker) flushQueue() {
+	bag := (*cw.ConfigManager).Get()
+	bth := cw.Sinks.StartBT("FlushCustomResourceDataQueue")
+	count := cw.WQ.Len()
+	if count > 0 {
+		cw.Logger.Infof("Flushing the queue of %d custom resource records\n", count)
+	}
+	if count == 0 {
+		cw.Sinks.StopBT(bth)
+		return
+	}
+
+	objMap := make(map[string][]map[string]interface{})
+
+	for count > 0 {
+		record, ok := cw.getNextQueueItem()
+		count = count - 1
+		if !ok {
+			cw.Logger.Info("Custom resource queue shut down")
+			break
+		}
+		objMap[record.SchemaName] = append(objMap[record.SchemaName], record.Data)
+		if len(objMap[record.SchemaName]) >= bag.EventAPILimit {
+			list := objMap[record.SchemaName]
+			cw.postCustomResourceRecords(record.SchemaName, &list)
+			delete(objMap, record.SchemaName)
+		}
+	}
+
+	for schemaName, list := range objMap {
+		cw.Logger.Debugf("Sending %d %s records to AppD events API\n", len(list), schemaName)
+		cw.postCustomResourceRecords(schemaName, &list)
+	}
+	cw.Sinks.StopBT(bth)
+}
+
+func (cw *CustomResourceWorker) postCustomResourceRecords(schemaName string, objList *[]map[string]interface{}) {
+	crc := cw.getConfigBySchema(schemaName)
+	if crc == nil {
+		cw.Logger.Warnf("Custom resource definition for schema %s no longer exists. Dropping %d records\n", schemaName, len(*objList))
+		return
+	}
+	schemaDefObj := m.NewCustomResourceSchemaDefWrapper(crc)
+	err := cw.Sinks.PostEvents(schemaName, &schemaDefObj, objList)
+	if err != nil {
+		cw.Logger.Errorf("Issues when publishing %s records. %v\n", schemaName, err)
+	}
+}
+
+func (cw *CustomResourceWorker) getConfigBySchema(schemaName string) *m.CustomResourceConfig {
+	for _, cri := range cw.informers {
+		if cri.Config.GetSchemaName() == schemaName {
+			crc := cri.Config
+			return &crc
+		}
+	}
+	return nil
+}
+
+func (cw *CustomResourceWorker) getNextQueueItem() (*m.CustomResourceRecord, bool) {
+	record, quit := cw.WQ.Get()
+
+	if quit {
+		return nil, false
+	}
+	defer cw.WQ.Done(record)
+	cw.WQ.Forget(record)
+
+	return record.(*m.CustomResourceRecord), true
+}
+
+func (cw *CustomResourceWorker) processObject(crc *m.CustomResourceConfig, obj *unstructured.Unstructured) m.CustomResourceRecord {
+	bag := (*cw.ConfigManager).Get()
+	data := make(map[string]interface{})
+
+	data["name"] = obj.GetName()
+	data["namespace"] = obj.GetNamespace()
+	data["kind"] = crc.GetKind()
+	if obj.GetClusterName() != "" {
+		data["clusterName"] = obj.GetClusterName()
+	} else {
+		data["clusterName"] = bag.AppName
+	}
+	data["objectUid"] = string(obj.GetUID())
+	data["creationTimestamp"] = obj.GetCreationTimestamp().Time
+	if obj.GetDeletionTimestamp() != nil {
+		data["deletionTimestamp"] = obj.GetDeletionTimestamp().Time
+	}
+
+	var sb strings.Builder
+	for k, l := range obj.GetLabels() {
+		fmt.Fprintf(&sb, "%s:%s;", k, l)
+	}
+	data["labels"] = utils.TruncateString(sb.String(), app.MAX_FIELD_LENGTH)
+	sb.Reset()
+
+	for k, l := range obj.GetAnnotations() {
+		fmt.Fprintf(&sb, "%s:%s;", k, l)
+	}
+	data["annotations"] = utils.TruncateString(sb.String(), app.MAX_FIELD_LENGTH)
+
+	status, reason := getCondition(obj, crc.GetConditionType())
+	data["conditionStatus"] = status
+	data["conditionReason"] = reason
+
+	for _, f := range crc.Fields {
+		val, err := evalJSONPath(obj, f.Path)
+		if err != nil {
+			cw.Logger.WithFields(log.Fields{"field": f.Name, "path": f.Path, "error": err}).Debug("Unable to evaluate custom resource field")
+			continue
+		}
+		if val != nil {
+			data[f.Name] = convertFieldValue(val, f.Type)
+		}
+	}
+
+	return m.CustomResourceRecord{SchemaName: crc.GetSchemaName(), Data: data}
+}
+
+func (cw *CustomResourceWorker) buildAppDMetrics() {
+	bth := cw.Sinks.StartBT("PostCustomResourceMetrics")
+	bag := (*cw.ConfigManager).Get()
+	cw.SummaryMap = make(map[string]m.ClusterCustomResourceMetrics)
+
+	for _, cri := range cw.informers {
+		kind := cri.Config.GetKind()
+		//always report the cluster level count, even if zero
+		allKey := buildCustomResourceKey(m.ALL, kind)
+		cw.SummaryMap[allKey] = m.NewClusterCustomResourceMetrics(bag, m.ALL, kind)
+		for _, obj := range cri.informer.GetStore().List() {
+			crObj, ok := obj.(*unstructured.Unstructured)
+			if !ok || !cw.qualifies(crObj) {
+				continue
+			}
+			status, _ := getCondition(crObj, cri.Config.GetConditionType())
+			cw.summarize(kind, crObj.GetNamespace(), status)
+		}
+	}
+
+	ml := cw.builAppDMetricsList()
+
+	cw.Logger.Infof("Ready to push %d custom resource metrics\n", len(ml.Items))
+
+	if err := cw.Sinks.PostMetrics(ml); err != nil {
+		cw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	cw.Sinks.StopBT(bth)
+}
+
+func (cw *CustomResourceWorker) summarize(kind string, ns string, status string) {
+	bag := (*cw.ConfigManager).Get()
+	allKey := buildCustomResourceKey(m.ALL, kind)
+	summary, okSum := cw.SummaryMap[allKey]
+	if !okSum {
+		summary = m.NewClusterCustomResourceMetrics(bag, m.ALL, kind)
+	}
+	updateCustomResourceMetrics(&summary, status)
+	cw.SummaryMap[allKey] = summary
+
+	if ns == "" {
+		return
+	}
+	nsKey := buildCustomResourceKey(ns, kind)
+	summaryNS, okNS := cw.SummaryMap[nsKey]
+	if !okNS {
+		summaryNS = m.NewClusterCustomResourceMetrics(bag, ns, kind)
+	}
+	updateCustomResourceMetrics(&summaryNS, status)
+	cw.SummaryMap[nsKey] = summaryNS
+}
+
+func updateCustomResourceMetrics(metrics *m.ClusterCustomResourceMetrics, status string) {
+	metrics.ResourceCount++
+	switch status {
+	case "True":
+		metrics.ConditionTrue++
+	case "False":
+		metrics.ConditionFalse++
+	case "Unknown":
+		metrics.ConditionUnknown++
+	}
+}
+
+func buildCustomResourceKey(ns string, kind string) string {
+	return fmt.Sprintf("%s_%s", ns, kind)
+}
+
+func (cw CustomResourceWorker) builAppDMetricsList() m.AppDMetricList {
+	ml := m.NewAppDMetricList()
+	var list []m.AppDMetric
+	for _, metricNode := range cw.SummaryMap {
+		objMap := metricNode.Unwrap()
+		cw.addMetricToList(*objMap, metricNode, &list)
+	}
+
+	ml.Items = list
+	return ml
+}
+
+func (cw CustomResourceWorker) addMetricToList(objMap map[string]interface{}, metric m.AppDMetricInterface, list *[]m.AppDMetric) {
+
+	for fieldName, fieldValue := range objMap {
+		if !metric.ShouldExcludeField(fieldName) {
+			appdMetric := m.NewAppDMetric(fieldName, fieldValue.(int64), metric.GetPath())
+			*list = append(*list, appdMetric)
+		}
+	}
+}
+
+//returns status and reason of the condition of the given type in status.conditions
+func getCondition(obj *unstructured.Unstructured, conditionType string) (string, string) {
+	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil || !found {
+		return "", ""
+	}
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _ := cond["type"].(string); t == conditionType {
+			status, _ := cond["status"].(string)
+			reason, _ := cond["reason"].(string)
+			return status, reason
+		}
+	}
+	return "", ""
+}
+
+func evalJSONPath(obj *unstructured.Unstructured, path string) (interface{}, error) {
+	p := strings.TrimSpace(path)
+	if !strings.HasPrefix(p, "{") {
+		p = fmt.Sprintf("{%s}", p)
+	}
+	jp := jsonpath.New("field")
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(p); err != nil {
+		return nil, fmt.Errorf("Invalid JSONPath %s. %v", path, err)
+	}
+	results, err := jp.FindResults(obj.UnstructuredContent())
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, nil
+	}
+	if len(results[0]) == 1 {
+		return results[0][0].Interface(), nil
+	}
+	var vals []interface{}
+	for _, r := range results[0] {
+		vals = append(vals, r.Interface())
+	}
+	return vals, nil
+}
+
+func convertFieldValue(val interface{}, fieldType string) interface{} {
+	switch fieldType {
+	case "integer":
+		switch v := val.(type) {
+		case int64:
+			return v
+		case float64:
+			return int64(v)
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i
+			}
+		}
+		return nil
+	case "float":
+		switch v := val.(type) {
+		case int64:
+			return float64(v)
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+		return nil
+	case "boolean":
+		switch v := val.(type) {
+		case bool:
+			return v
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+		return nil
+	case "date":
+		if s, ok := val.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t
+			}
+		}
+		return nil
+	default:
+		switch v := val.(type) {
+		case string:
+			return utils.TruncateString(v, app.MAX_FIELD_LENGTH)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Sprintf("%v", v)
+			}
+			return utils.TruncateString(string(data), app.MAX_FIELD_LENGTH)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package workers
 
 import (
-	"encoding/json"
 	"sync"
 	"time"
 
@@ -12,6 +11,7 @@ import (
 
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	appsv1 "k8s.io/api/apps/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,15 +30,17 @@ type DaemonWorker struct {
 	SummaryMap     map[string]m.ClusterDaemonMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
+	Recorder       *w.ChangeRecorder
 	Logger         *log.Logger
 }
 
-func NewDaemonWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) DaemonWorker {
+func NewDaemonWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, recorder *w.ChangeRecorder, l *log.Logger) DaemonWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := DaemonWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterDaemonMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
+		AppdController: controller, Sinks: sinks, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Recorder: recorder, Logger: l}
 	dw.initDaemonInformer(client)
 	return dw
 }
@@ -118,6 +120,11 @@ func (dw *DaemonWorker) onUpdateDaemonSet(objOld interface{}, objNew interface{}
 	DaemonRecord, _ := dw.processObject(DaemonObj, nil)
 	dw.WQ.Add(&DaemonRecord)
 
+	oldObj := objOld.(*appsv1.DaemonSet)
+	if oldObj.Generation != DaemonObj.Generation {
+		changes := utils.DiffPodTemplates(&oldObj.Spec.Template, &DaemonObj.Spec.Template)
+		dw.Recorder.RecordChanges(m.CHANGE_KIND_DAEMONSET, &DaemonObj.ObjectMeta, changes)
+	}
 }
 
 func (pw *DaemonWorker) startMetricsWorker(stopCh <-chan struct{}) {
@@ -142,6 +149,12 @@ func (pw *DaemonWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Tick
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d daemon set records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
 		case <-stop:
 			ticker.Stop()
@@ -157,13 +170,13 @@ func (pw *DaemonWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (pw *DaemonWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
-	bth := pw.AppdController.StartBT("FlushDaemonSetDataQueue")
+	bth := pw.Sinks.StartBT("FlushDaemonSetDataQueue")
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d DaemonSet records\n", count)
 	}
 	if count == 0 {
-		pw.AppdController.StopBT(bth)
+		pw.Sinks.StopBT(bth)
 		return
 	}
 
@@ -183,28 +196,19 @@ func (pw *DaemonWorker) flushQueue() {
 		if count == 0 || len(objList) >= bag.EventAPILimit {
 			pw.Logger.Debugf("Sending %d DaemonSet records to AppD events API\n", len(objList))
 			pw.postDaemonRecords(&objList)
-			pw.AppdController.StopBT(bth)
+			pw.Sinks.StopBT(bth)
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *DaemonWorker) postDaemonRecords(objList *[]m.DeploySchema) {
 	bag := (*pw.ConfigManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewDeploySchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.DeploySchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.DeploySchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.DeploySchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of daemon schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.DeploySchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.DeploySchemaName, err)
 	}
 }
 
@@ -221,7 +225,7 @@ func (pw *DaemonWorker) getNextQueueItem() (*m.DeploySchema, bool) {
 }
 
 func (pw *DaemonWorker) buildAppDMetrics() {
-	bth := pw.AppdController.StartBT("PostDaemonSetMetrics")
+	bth := pw.Sinks.StartBT("PostDaemonSetMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterDaemonMetrics)
 
 	var count int = 0
@@ -241,8 +245,10 @@ func (pw *DaemonWorker) buildAppDMetrics() {
 
 	pw.Logger.Infof("Ready to push %d Daemonset metrics\n", len(ml.Items))
 
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *DaemonWorker) summarize(DaemonObject *m.DeploySchema) {
@@ -26,6 +26,7 @@ const (
 	CLUSTER_WIDGETS string  = "/cluster-widgets.json"
 	DEPLOY_BASE     string  = "/base_deploy_template.json"
 	TIER_SUMMARY    string  = "/tier_stats_widget.json"
+	TIER_CHANGES    string  = "/tier_changes_widget.json"
 	BACKGROUND      string  = "/background.json"
 	HEAT_MAP        string  = "/heatnodetemplate.json"
 	HEAT_WIDGET     string  = "/healthwidget.json"
@@ -675,8 +676,15 @@ func (dw *DashboardWorker) addPodHeatMap(dashboard *m.Dashboard, bag *m.Dashboar
 			//			dot["drillDownUrl"] = fmt.Sprintf("%s#/location=%s&timeRange=last_1_hour.BEFORE_NOW.-1.-1.60&application=%d&%s=%d&dashboardMode=force", dw.Bag.RestAPIUrl, linkLocation, hn.AppID, linkComponent, apmID)
 			hwdotArray = append(hwdotArray, healthDot)
 		}
-		//link to pod list by state
-		if searchPath != "" {
+		//link to the forensics of the last crash of the pod, otherwise to pod list by state
+		incidentUrl := ""
+		if hn.IncidentID != "" {
+			incidentUrl = dw.AdqlWorker.GetIncidentSearch(hn.Namespace, hn.Podname, hn.IncidentID)
+		}
+		if incidentUrl != "" {
+			dot["drillDownUrl"] = incidentUrl
+			dot["useMetricBrowserAsDrillDown"] = false
+		} else if searchPath != "" {
 			searchUrl := dw.AdqlWorker.GetSearch(searchPath)
 			if searchUrl != "" {
 				dot["drillDownUrl"] = searchUrl
@@ -697,6 +705,7 @@ func (dw *DashboardWorker) addPodHeatMap(dashboard *m.Dashboard, bag *m.Dashboar
 	for _, hwd := range hwdotArray {
 		dashboard.Widgets = append(dashboard.Widgets, hwd)
 	}
+	dw.AdqlWorker.PruneIncidentSearches()
 
 	return dashboard, nil
 }
@@ -809,6 +818,11 @@ func (dw *DashboardWorker) generateDeploymentDashboard(dashboard *m.Dashboard, b
 		fmt.Printf("Error when adding tier summary to dash %s. %v\n", dashboard.Name, err)
 		return nil, err
 	}
+	d, err = dw.addTierChangesWidget(dashboard, bag)
+	if err != nil {
+		fmt.Printf("Error when adding tier changes to dash %s. %v\n", dashboard.Name, err)
+		return nil, err
+	}
 	fmt.Printf("Added %d widgets to dash %.0f, %s\n", len(d.Widgets), d.ID, d.Name)
 	return d, err
 }
@@ -897,6 +911,33 @@ func (dw *DashboardWorker) addDeploymentSummaryWidget(dashboard *m.Dashboard, ba
 	return dashboard, nil
 }
 
+//link to the history of spec changes of the tier
+func (dw *DashboardWorker) addTierChangesWidget(dashboard *m.Dashboard, bag *m.DashboardBag) (*m.Dashboard, error) {
+	widgetList, err, exists := dw.loadWidgetTemplate(TIER_CHANGES)
+	if err != nil && exists {
+		return nil, fmt.Errorf("Tier changes template exists, but cannot be loaded. %v\n", err)
+	}
+	if !exists {
+		fmt.Printf("Tier changes template does not exist, skipping the widget for deployment %s/%s\n", bag.Namespace, bag.TierName)
+		return dashboard, nil
+	}
+	searchUrl := dw.AdqlWorker.GetChangesSearch(bag.Namespace, bag.TierName)
+	if searchUrl == "" {
+		return dashboard, nil
+	}
+	widget := (*widgetList)[0]
+	widget["id"] = 0
+	widget["dashboardId"] = dashboard.ID
+	widget["y"] = dashboard.Height
+	widget["drillDownUrl"] = searchUrl
+	dashboard.Widgets = append(dashboard.Widgets, widget)
+
+	dashboard.Height = dashboard.Height + widget["height"].(float64) + WIDGET_GAP
+	dashboard.Widgets[0]["height"] = dashboard.Height
+	fmt.Printf("Added tier changes to dash %s\n", dashboard.Name)
+	return dashboard, nil
+}
+
 func (dw *DashboardWorker) updateMetricDefinition(expTemplate map[string]interface{}, bag *m.DashboardBag, parentWidget *map[string]interface{}) error {
 	ok, definition := dw.nodeHasDefinition(expTemplate)
 	if ok {
@@ -1,7 +1,6 @@
 package workers
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -17,6 +16,7 @@ import (
 	instr "github.com/appdynamics/cluster-agent/instrumentation"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -30,6 +30,8 @@ import (
 	"k8s.io/client-go/util/workqueue"
 )
 
+var lockDrainRisks = sync.RWMutex{}
+
 type DeployWorker struct {
 	informer       cache.SharedIndexInformer
 	Client         *kubernetes.Clientset
@@ -37,15 +39,22 @@ type DeployWorker struct {
 	SummaryMap     map[string]m.ClusterDeployMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
+	RolloutCache   map[string]m.RolloutSchema
+	RolloutWQ      workqueue.RateLimitingInterface
+	Recorder       *w.ChangeRecorder
+	PDBWatcher     *w.PDBWatcher
+	DrainRisks     map[string]string
 	Logger         *log.Logger
 }
 
-func NewDeployWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) DeployWorker {
+func NewDeployWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, recorder *w.ChangeRecorder, pdbWatcher *w.PDBWatcher, l *log.Logger) DeployWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := DeployWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterDeployMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
+		AppdController: controller, Sinks: sinks, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus),
+		RolloutCache: make(map[string]m.RolloutSchema), RolloutWQ: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), Recorder: recorder, PDBWatcher: pdbWatcher, DrainRisks: make(map[string]string), Logger: l}
 	dw.initDeployInformer(client)
 	return dw
 }
@@ -78,6 +87,7 @@ func (nw *DeployWorker) initDeployInformer(client *kubernetes.Clientset) cache.S
 func (dw *DeployWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer dw.WQ.ShutDown()
+	defer dw.RolloutWQ.ShutDown()
 	wg.Add(1)
 	go dw.informer.Run(stopCh)
 
@@ -106,6 +116,8 @@ func (dw *DeployWorker) onNewDeployment(obj interface{}) {
 	deployRecord, _ := dw.processObject(deployObj, nil)
 	dw.WQ.Add(&deployRecord)
 
+	dw.trackRollout(deployObj, nil)
+
 	init, biq, agentRequests := dw.shouldUpdate(deployObj)
 	if init || biq {
 		dw.updateDeployment(deployObj, init, biq, agentRequests)
@@ -118,9 +130,13 @@ func (dw *DeployWorker) onDeleteDeployment(obj interface{}) {
 		return
 	}
 	dw.Logger.Debugf("Deleted Deployment: %s\n", deployObj.Name)
+	dw.onDeleteRollout(deployObj)
 	//clean caches
 	utils.RemoveFromSlice(utils.GetDeployKey(deployObj), dw.PendingCache)
 	delete(dw.FailedCache, utils.GetDeployKey(deployObj))
+	lockDrainRisks.Lock()
+	delete(dw.DrainRisks, utils.GetKey(deployObj.Namespace, deployObj.Name))
+	lockDrainRisks.Unlock()
 }
 
 func (dw *DeployWorker) onUpdateDeployment(objOld interface{}, objNew interface{}) {
@@ -133,6 +149,10 @@ func (dw *DeployWorker) onUpdateDeployment(objOld interface{}, objNew interface{
 	deployRecord, _ := dw.processObject(deployObj, nil)
 	dw.WQ.Add(&deployRecord)
 
+	oldObj := objOld.(*appsv1.Deployment)
+	dw.trackRollout(deployObj, oldObj)
+	dw.recordChanges(deployObj, oldObj)
+
 	init, biq, agentRequests := dw.shouldUpdate(deployObj)
 	if init || biq {
 		dw.Logger.Debugf("Deployment update is required. Init: %t. BiQ: %t\n", init, biq)
@@ -140,6 +160,16 @@ func (dw *DeployWorker) onUpdateDeployment(objOld interface{}, objNew interface{
 	}
 }
 
+//records field-level changes of the deployment spec
+func (dw *DeployWorker) recordChanges(deployObj *appsv1.Deployment, old *appsv1.Deployment) {
+	if old.Generation == deployObj.Generation {
+		return
+	}
+	changes := utils.DiffReplicas(old.Spec.Replicas, deployObj.Spec.Replicas)
+	changes = append(changes, utils.DiffPodTemplates(&old.Spec.Template, &deployObj.Spec.Template)...)
+	dw.Recorder.RecordChanges(m.CHANGE_KIND_DEPLOYMENT, &deployObj.ObjectMeta, changes)
+}
+
 func (pw *DeployWorker) startMetricsWorker(stopCh <-chan struct{}) {
 	bag := (*pw.ConfigManager).Get()
 	pw.appMetricTicker(stopCh, time.NewTicker(time.Duration(bag.MetricsSyncInterval)*time.Second))
@@ -162,7 +192,14 @@ func (pw *DeployWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Tick
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ) + discardQueue(pw.RolloutWQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d deployment records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
+			pw.flushRolloutQueue()
 		case <-stop:
 			ticker.Stop()
 			return
@@ -177,13 +214,13 @@ func (pw *DeployWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (pw *DeployWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
-	bth := pw.AppdController.StartBT("FlushDeploymentDataQueue")
+	bth := pw.Sinks.StartBT("FlushDeploymentDataQueue")
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d deployment records\n", count)
 	}
 	if count == 0 {
-		pw.AppdController.StopBT(bth)
+		pw.Sinks.StopBT(bth)
 		return
 	}
 
@@ -203,28 +240,19 @@ func (pw *DeployWorker) flushQueue() {
 		if count == 0 || len(objList) >= bag.EventAPILimit {
 			pw.Logger.Debugf("Sending %d deployment records to AppD events API\n", len(objList))
 			pw.postDeployRecords(&objList)
-			pw.AppdController.StopBT(bth)
+			pw.Sinks.StopBT(bth)
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *DeployWorker) postDeployRecords(objList *[]m.DeploySchema) {
 	bag := (*pw.ConfigManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewDeploySchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.DeploySchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.DeploySchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.DeploySchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of deployment schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.DeploySchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.DeploySchemaName, err)
 	}
 }
 
@@ -241,26 +269,33 @@ func (pw *DeployWorker) getNextQueueItem() (*m.DeploySchema, bool) {
 }
 
 func (pw *DeployWorker) buildAppDMetrics() {
-	bth := pw.AppdController.StartBT("PostDeploymentMetrics")
+	bth := pw.Sinks.StartBT("PostDeploymentMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterDeployMetrics)
 
 	var count int = 0
 	for _, obj := range pw.informer.GetStore().List() {
 		deployObject := obj.(*appsv1.Deployment)
 		deploySchema, _ := pw.processObject(deployObject, nil)
-		pw.summarize(&deploySchema)
+		rolloutStatus, _, _ := getRolloutStatus(deployObject)
+		pw.summarize(&deploySchema, rolloutStatus)
+		if pw.drainRiskChanged(&deploySchema) {
+			pw.WQ.Add(&deploySchema)
+		}
 		count++
 	}
+	pw.summarizePDBs()
 
 	ml := pw.builAppDMetricsList()
 
 	pw.Logger.Infof("Ready to push %d Deployment metrics\n", len(ml.Items))
 
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 }
 
-func (pw *DeployWorker) summarize(deployObject *m.DeploySchema) {
+func (pw *DeployWorker) summarize(deployObject *m.DeploySchema, rolloutStatus string) {
 	bag := (*pw.ConfigManager).Get()
 	//global metrics
 	summary, okSum := pw.SummaryMap[m.ALL]
@@ -288,6 +323,20 @@ func (pw *DeployWorker) summarize(deployObject *m.DeploySchema) {
 	summary.DeployCollisionCount = summary.DeployCollisionCount + int64(deployObject.CollisionCount)
 	summaryNS.DeployCollisionCount = summaryNS.DeployCollisionCount + int64(deployObject.CollisionCount)
 
+	if deployObject.DrainRisk == m.DRAIN_RISK_NO_PDB_SINGLE_REPLICA {
+		summary.SingleReplicaNoPdb++
+		summaryNS.SingleReplicaNoPdb++
+	}
+
+	switch rolloutStatus {
+	case m.ROLLOUT_IN_PROGRESS:
+		summary.RolloutsInProgress++
+		summaryNS.RolloutsInProgress++
+	case m.ROLLOUT_STALLED:
+		summary.RolloutsStalled++
+		summaryNS.RolloutsStalled++
+	}
+
 	pw.SummaryMap[m.ALL] = summary
 	pw.SummaryMap[deployObject.Namespace] = summaryNS
 }
@@ -359,9 +408,74 @@ func (pw *DeployWorker) processObject(d *appsv1.Deployment, old *appsv1.Deployme
 	}
 	deployObject.ReplicasReady = d.Status.ReadyReplicas
 
+	pw.checkDrainRisk(d, &deployObject)
+
 	return deployObject, changed
 }
 
+//true if the drain risk of the deployment differs from the one last published
+func (pw *DeployWorker) drainRiskChanged(deployObject *m.DeploySchema) bool {
+	key := utils.GetKey(deployObject.Namespace, deployObject.Name)
+	lockDrainRisks.Lock()
+	defer lockDrainRisks.Unlock()
+	if pw.DrainRisks[key] == deployObject.DrainRisk {
+		return false
+	}
+	if deployObject.DrainRisk == "" {
+		delete(pw.DrainRisks, key)
+	} else {
+		pw.DrainRisks[key] = deployObject.DrainRisk
+	}
+	return true
+}
+
+//flags deployments that either block node drains or go down entirely when drained
+func (pw *DeployWorker) checkDrainRisk(d *appsv1.Deployment, deployObject *m.DeploySchema) {
+	if pw.PDBWatcher == nil {
+		return
+	}
+	pdb, ok := pw.PDBWatcher.GetMatchingPDB(d.Namespace, d.Spec.Template.Labels)
+	if ok {
+		deployObject.PdbName = pdb.Name
+		if m.PdbBlocksDrain(pdb) {
+			deployObject.DrainRisk = m.DRAIN_RISK_ZERO_DISRUPTIONS
+		}
+		return
+	}
+	var desired int32 = 1
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	if desired == 1 {
+		deployObject.DrainRisk = m.DRAIN_RISK_NO_PDB_SINGLE_REPLICA
+	}
+}
+
+//budgets that currently allow no voluntary disruptions
+func (pw *DeployWorker) summarizePDBs() {
+	if pw.PDBWatcher == nil {
+		return
+	}
+	bag := (*pw.ConfigManager).Get()
+	for _, pdb := range pw.PDBWatcher.CloneMap() {
+		if !m.PdbBlocksDrain(&pdb) {
+			continue
+		}
+		summary, okSum := pw.SummaryMap[m.ALL]
+		if !okSum {
+			summary = m.NewClusterDeployMetrics(bag, m.ALL)
+		}
+		summaryNS, okNS := pw.SummaryMap[pdb.Namespace]
+		if !okNS {
+			summaryNS = m.NewClusterDeployMetrics(bag, pdb.Namespace)
+		}
+		summary.PdbZeroDisruptions++
+		summaryNS.PdbZeroDisruptions++
+		pw.SummaryMap[m.ALL] = summary
+		pw.SummaryMap[pdb.Namespace] = summaryNS
+	}
+}
+
 func (pw DeployWorker) builAppDMetricsList() m.AppDMetricList {
 	ml := m.NewAppDMetricList()
 	var list []m.AppDMetric
@@ -400,7 +514,7 @@ func (dw *DeployWorker) updateDeployment(deployObj *appsv1.Deployment, init bool
 	dw.PendingCache = append(dw.PendingCache, utils.GetDeployKey(deployObj))
 
 	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		bth := dw.AppdController.StartBT("DeploymentUpdate")
+		bth := dw.Sinks.StartBT("DeploymentUpdate")
 		dw.Logger.WithField("Name", deployObj.Name).Info("Started deployment update for instrumentation")
 		deploymentsClient := dw.Client.AppsV1().Deployments(deployObj.Namespace)
 		result, getErr := deploymentsClient.Get(deployObj.Name, metav1.GetOptions{})
@@ -499,7 +613,7 @@ func (dw *DeployWorker) updateDeployment(deployObj *appsv1.Deployment, init bool
 		}
 
 		_, err := deploymentsClient.Update(result)
-		dw.AppdController.StopBT(bth)
+		dw.Sinks.StopBT(bth)
 		return err
 	})
 
@@ -0,0 +1,111 @@
+package workers
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+const fakeSinkName string = "fake"
+
+type fakeEventSink struct {
+	records map[string]int //schema -> records received
+}
+
+func (s *fakeEventSink) PostEvents(schemaName string, schemaDef m.AppDSchemaInterface, records interface{}) error {
+	if list, ok := records.(*[]m.DeploySchema); ok {
+		s.records[schemaName] += len(*list)
+	}
+	return nil
+}
+
+type fakeMetricSink struct {
+	metrics map[string]int64 //path -> value
+}
+
+func (s *fakeMetricSink) PostMetrics(metrics m.AppDMetricList) error {
+	for _, metric := range metrics.Items {
+		s.metrics[metric.MetricPath] = metric.MetricValue
+	}
+	return nil
+}
+
+//sinks built without a controller client, as the workers see them
+func newFakeSinks(t *testing.T, cm *config.MutexConfigManager, logger *log.Logger) (*app.SinkSet, *fakeEventSink, *fakeMetricSink) {
+	eventSink := &fakeEventSink{records: make(map[string]int)}
+	metricSink := &fakeMetricSink{metrics: make(map[string]int64)}
+	app.RegisterEventSink(fakeSinkName, func(cm *config.MutexConfigManager, logger *log.Logger) (app.EventSink, error) {
+		return eventSink, nil
+	})
+	app.RegisterMetricSink(fakeSinkName, func(cm *config.MutexConfigManager, logger *log.Logger) (app.MetricSink, error) {
+		return metricSink, nil
+	})
+	sinks, err := app.NewSinkSet(cm, logger)
+	if err != nil {
+		t.Fatalf("Unable to create the sinks. %v", err)
+	}
+	return sinks, eventSink, metricSink
+}
+
+func newTestConfigManager() (*config.MutexConfigManager, *log.Logger) {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	bag := m.GetDefaultProperties()
+	bag.EventSinks = []string{fakeSinkName}
+	bag.MetricSinks = []string{fakeSinkName}
+	return &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: logger}, logger
+}
+
+func TestDeployWorkerPublishesToSinks(t *testing.T) {
+	cm, logger := newTestConfigManager()
+	sinks, eventSink, metricSink := newFakeSinks(t, cm, logger)
+	bag := cm.Get()
+
+	dw := NewDeployWorker(&kubernetes.Clientset{}, cm, nil, sinks, nil, nil, logger)
+	var replicas int32 = 2
+	for _, name := range []string{"web", "api"} {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}}
+		if err := dw.informer.GetStore().Add(d); err != nil {
+			t.Fatalf("Unable to add deployment %s. %v", name, err)
+		}
+		record, _ := dw.processObject(d, nil)
+		dw.WQ.Add(&record)
+	}
+
+	dw.buildAppDMetrics()
+	clusterPath := m.NewClusterDeployMetrics(bag, m.ALL).GetPath()
+	if v := metricSink.metrics[clusterPath+"DeployCount"]; v != 2 {
+		t.Errorf("Expected DeployCount 2 in %s, got %d", clusterPath, v)
+	}
+	nsPath := m.NewClusterDeployMetrics(bag, "shop").GetPath()
+	if v := metricSink.metrics[nsPath+"DeployCount"]; v != 2 {
+		t.Errorf("Expected DeployCount 2 in %s, got %d", nsPath, v)
+	}
+
+	dw.flushQueue()
+	if n := eventSink.records[bag.DeploySchemaName]; n != 2 {
+		t.Errorf("Expected 2 %s records, got %d", bag.DeploySchemaName, n)
+	}
+	if dw.WQ.Len() != 0 {
+		t.Errorf("Expected the queue to be empty after the flush, %d records left", dw.WQ.Len())
+	}
+}
+
+func TestSinkSetWithoutAppDSinkDoesNotTrackTransactions(t *testing.T) {
+	cm, logger := newTestConfigManager()
+	sinks, _, _ := newFakeSinks(t, cm, logger)
+	bth := sinks.StartBT("Test")
+	if bth != 0 {
+		t.Errorf("Expected no transaction handle without the appd metric sink, got %d", bth)
+	}
+	sinks.StopBT(bth)
+}
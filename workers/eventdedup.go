@@ -0,0 +1,132 @@
+package workers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+var lockEventAggregates = sync.Mutex{}
+
+type eventAggregate struct {
+	Record      m.EventSchema
+	Counts      map[string]int32 //event name -> last reported count
+	WindowStart time.Time
+	Occurrences int32 //occurrences in the current window
+	Pending     bool  //occurrences not yet sent
+	Storm       bool
+	Continued   bool //the aggregate carries over a storm from the previous window
+}
+
+/*
+ Aggregates repeats of the same event within the dedup window.
+ The first occurrence is sent immediately, repeats are folded into one record per window
+*/
+type EventAggregator struct {
+	Aggregates map[string]*eventAggregate
+	Suppressed map[string]int64 //namespace -> occurrences folded into aggregated records
+	Storms     map[string]int64 //namespace -> detected storms
+}
+
+func NewEventAggregator() *EventAggregator {
+	return &EventAggregator{Aggregates: make(map[string]*eventAggregate), Suppressed: make(map[string]int64), Storms: make(map[string]int64)}
+}
+
+func getEventAggregateKey(record *m.EventSchema) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", record.Namespace, record.ObjectKind, record.ObjectName, record.Reason, record.Fingerprint)
+}
+
+func getStormThreshold(bag *m.AppDBag, reason string) int {
+	if t, ok := bag.EventStormReasonThresholds[reason]; ok {
+		return t
+	}
+	return bag.EventStormThreshold
+}
+
+//returns the record if it needs to be sent right away
+func (ea *EventAggregator) Add(record m.EventSchema, bag *m.AppDBag, now time.Time) (m.EventSchema, bool) {
+	key := getEventAggregateKey(&record)
+	lockEventAggregates.Lock()
+	defer lockEventAggregates.Unlock()
+
+	agg, ok := ea.Aggregates[key]
+	if !ok {
+		ea.Aggregates[key] = &eventAggregate{Record: record, Counts: map[string]int32{record.Name: record.Count}, WindowStart: now, Occurrences: record.Occurrences}
+		return record, true
+	}
+
+	delta := record.Occurrences
+	if last, seen := agg.Counts[record.Name]; seen {
+		//same event object updated by kubernetes with an incremented count
+		delta = record.Count - last
+		if delta <= 0 {
+			return record, false
+		}
+	} else if delta <= 0 {
+		delta = 1
+	}
+	agg.Counts[record.Name] = record.Count
+	agg.Occurrences += delta
+	agg.Pending = true
+
+	agg.Record.Occurrences += delta
+	agg.Record.Count = record.Count
+	agg.Record.LastSeen = record.LastSeen
+	agg.Record.LastTimestamp = record.LastTimestamp
+	agg.Record.ResourceVersion = record.ResourceVersion
+	ea.Suppressed[record.Namespace] += int64(delta)
+
+	threshold := getStormThreshold(bag, record.Reason)
+	if threshold > 0 && !agg.Storm && agg.Occurrences >= int32(threshold) {
+		agg.Storm = true
+		if !agg.Continued {
+			ea.Storms[record.Namespace]++
+		}
+	}
+	return record, false
+}
+
+//returns the aggregated records of the expired windows
+func (ea *EventAggregator) Flush(window time.Duration, now time.Time) []m.EventSchema {
+	lockEventAggregates.Lock()
+	defer lockEventAggregates.Unlock()
+
+	list := []m.EventSchema{}
+	for key, agg := range ea.Aggregates {
+		if now.Sub(agg.WindowStart) < window {
+			continue
+		}
+		if agg.Pending {
+			record := agg.Record
+			if agg.Storm {
+				record.Storm = true
+				record.Message = fmt.Sprintf("Event storm: %d occurrences since %s. %s", record.Occurrences, record.FirstSeen.Format(time.RFC3339), record.Message)
+			}
+			list = append(list, record)
+		}
+		if agg.Storm {
+			//keep collapsing the storm into one record per window until it subsides
+			agg.WindowStart = now
+			agg.Occurrences = 0
+			agg.Pending = false
+			agg.Storm = false
+			agg.Continued = true
+			continue
+		}
+		delete(ea.Aggregates, key)
+	}
+	return list
+}
+
+//returns the suppression stats since the last call
+func (ea *EventAggregator) TakeStats() (map[string]int64, map[string]int64) {
+	lockEventAggregates.Lock()
+	defer lockEventAggregates.Unlock()
+	suppressed := ea.Suppressed
+	storms := ea.Storms
+	ea.Suppressed = make(map[string]int64)
+	ea.Storms = make(map[string]int64)
+	return suppressed, storms
+}
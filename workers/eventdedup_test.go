@@ -0,0 +1,140 @@
+package workers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+func newTestEvent(name string, count int32) m.EventSchema {
+	record := m.NewEventObj()
+	record.Namespace = "shop"
+	record.ObjectKind = "Pod"
+	record.ObjectName = "web-1"
+	record.Reason = "BackOff"
+	record.Fingerprint = "back-off restarting failed container"
+	record.Message = "Back-off restarting failed container"
+	record.Name = name
+	record.Count = count
+	record.Occurrences = count
+	return record
+}
+
+func TestEventAggregatorAdd(t *testing.T) {
+	cases := []struct {
+		name        string
+		events      []m.EventSchema
+		sent        []bool
+		occurrences int32
+		suppressed  int64
+		pending     bool
+	}{
+		{name: "first event sent", events: []m.EventSchema{newTestEvent("a", 1)},
+			sent: []bool{true}, occurrences: 1},
+		{name: "first event with a count sent", events: []m.EventSchema{newTestEvent("a", 4)},
+			sent: []bool{true}, occurrences: 4},
+		{name: "repeats of the same event folded", events: []m.EventSchema{newTestEvent("a", 1), newTestEvent("a", 3), newTestEvent("a", 4)},
+			sent: []bool{true, false, false}, occurrences: 4, suppressed: 3, pending: true},
+		{name: "stale count ignored", events: []m.EventSchema{newTestEvent("a", 3), newTestEvent("a", 3), newTestEvent("a", 2)},
+			sent: []bool{true, false, false}, occurrences: 3},
+		{name: "other events of the same object folded", events: []m.EventSchema{newTestEvent("a", 1), newTestEvent("b", 1), newTestEvent("c", 2)},
+			sent: []bool{true, false, false}, occurrences: 4, suppressed: 3, pending: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bag := m.GetDefaultProperties()
+			ea := NewEventAggregator()
+			now := time.Now()
+			for i, e := range c.events {
+				if _, send := ea.Add(e, bag, now); send != c.sent[i] {
+					t.Errorf("Event %d: expected sent %v, got %v", i, c.sent[i], send)
+				}
+			}
+			if len(ea.Aggregates) != 1 {
+				t.Fatalf("Expected 1 aggregate, got %d", len(ea.Aggregates))
+			}
+			for _, agg := range ea.Aggregates {
+				if agg.Record.Occurrences != c.occurrences {
+					t.Errorf("Expected %d occurrences, got %d", c.occurrences, agg.Record.Occurrences)
+				}
+				if agg.Pending != c.pending {
+					t.Errorf("Expected pending %v, got %v", c.pending, agg.Pending)
+				}
+				if agg.Storm {
+					t.Errorf("Expected no storm")
+				}
+			}
+			suppressed, storms := ea.TakeStats()
+			if suppressed["shop"] != c.suppressed {
+				t.Errorf("Expected %d suppressed occurrences, got %d", c.suppressed, suppressed["shop"])
+			}
+			if storms["shop"] != 0 {
+				t.Errorf("Expected no storms, got %d", storms["shop"])
+			}
+		})
+	}
+}
+
+func TestEventAggregatorFlush(t *testing.T) {
+	bag := m.GetDefaultProperties()
+	bag.EventStormThreshold = 3
+	window := time.Duration(bag.EventDedupWindow) * time.Second
+	start := time.Now()
+	ea := NewEventAggregator()
+
+	//the kubelet updates the count of the same event object
+	steps := []struct {
+		name        string
+		at          time.Time
+		counts      []int32
+		records     int
+		storm       bool
+		occurrences int32
+		storms      int64
+		continued   bool
+	}{
+		{name: "below the threshold", at: start, counts: []int32{1, 2}, records: 1, occurrences: 2},
+		{name: "first occurrence of a new window sent right away", at: start.Add(window), counts: []int32{3}, records: 0},
+		{name: "storm collapsed into one record", at: start.Add(2 * window), counts: []int32{4, 5, 6, 8}, records: 1, storm: true, occurrences: 8, storms: 1, continued: true},
+		{name: "storm carried into the next window", at: start.Add(3 * window), counts: []int32{9, 10, 11}, records: 1, storm: true, occurrences: 11, storms: 0, continued: true},
+		{name: "storm subsided", at: start.Add(4 * window), records: 0},
+	}
+	for _, step := range steps {
+		for _, count := range step.counts {
+			ea.Add(newTestEvent("a", count), bag, step.at)
+		}
+		records := ea.Flush(window, step.at.Add(window))
+		if len(records) != step.records {
+			t.Fatalf("%s: expected %d records, got %d", step.name, step.records, len(records))
+		}
+		if step.records > 0 {
+			record := records[0]
+			if record.Storm != step.storm {
+				t.Errorf("%s: expected storm %v, got %v", step.name, step.storm, record.Storm)
+			}
+			if record.Occurrences != step.occurrences {
+				t.Errorf("%s: expected %d occurrences, got %d", step.name, step.occurrences, record.Occurrences)
+			}
+			if step.storm && !strings.HasPrefix(record.Message, "Event storm: ") {
+				t.Errorf("%s: expected the storm in the message, got %s", step.name, record.Message)
+			}
+		}
+		_, storms := ea.TakeStats()
+		if storms["shop"] != step.storms {
+			t.Errorf("%s: expected %d storms, got %d", step.name, step.storms, storms["shop"])
+		}
+		if !step.continued {
+			if len(ea.Aggregates) != 0 {
+				t.Errorf("%s: expected the aggregates to be dropped, got %d", step.name, len(ea.Aggregates))
+			}
+			continue
+		}
+		for _, agg := range ea.Aggregates {
+			if !agg.Continued || agg.Occurrences != 0 || agg.Pending || agg.Storm {
+				t.Errorf("%s: expected the storm to carry over with a new window, got %+v", step.name, *agg)
+			}
+		}
+	}
+}
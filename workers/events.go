@@ -26,6 +26,7 @@ import (
 
 var lockNSCache = sync.RWMutex{}
 var lockTierCache = sync.RWMutex{}
+var lockEventRules = sync.RWMutex{}
 
 type EventWorker struct {
 	informer       cache.SharedIndexInformer
@@ -34,14 +35,23 @@ type EventWorker struct {
 	SummaryMap     map[string]m.ClusterEventMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	PodsWorker     *PodWorker
+	Rules          *m.EventRuleSet
+	Aggregator     *EventAggregator
+	ImagePulls     *ImagePullTracker
+	OwnerResolver  *OwnerResolver
+	StartTime      time.Time
 	Logger         *log.Logger
 }
 
-func NewEventWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, appdController *app.ControllerClient, podsWorker *PodWorker, l *log.Logger) EventWorker {
+func NewEventWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, appdController *app.ControllerClient, sinks *app.SinkSet, podsWorker *PodWorker, resolver *OwnerResolver, l *log.Logger) EventWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	rules, _ := m.NewEventRuleSet([]m.EventRule{})
+	startTime := time.Now()
 	ew := EventWorker{Client: client, ConfigManager: cm,
-		AppdController: appdController, SummaryMap: make(map[string]m.ClusterEventMetrics), WQ: queue, PodsWorker: podsWorker, Logger: l}
+		AppdController: appdController, Sinks: sinks, SummaryMap: make(map[string]m.ClusterEventMetrics), WQ: queue, PodsWorker: podsWorker, Rules: &rules, Aggregator: NewEventAggregator(), ImagePulls: NewImagePullTracker(startTime), OwnerResolver: resolver, StartTime: startTime, Logger: l}
+	ew.loadEventRules()
 	ew.informer = ew.initInformer(client)
 	return ew
 }
@@ -62,7 +72,8 @@ func (ew *EventWorker) initInformer(client *kubernetes.Clientset) cache.SharedIn
 	)
 
 	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: ew.onNewEvent,
+		AddFunc:    ew.onNewEvent,
+		UpdateFunc: ew.onUpdateEvent,
 	})
 	return i
 }
@@ -81,9 +92,54 @@ func (ew *EventWorker) onNewEvent(obj interface{}) {
 	}
 	//	fmt.Printf("Received event: %s %s %s\n", eventObj.Namespace, eventObj.Message, eventObj.Reason)
 	eventRecord := ew.processObject(eventObj)
+	ew.notifyPodsWorker(eventObj, &eventRecord, false)
+	ew.ImagePulls.OnEvent(eventObj, &eventRecord, 1)
+	bag := (*ew.ConfigManager).Get()
+	if bag.EventDedupWindow > 0 {
+		var send bool
+		eventRecord, send = ew.Aggregator.Add(eventRecord, bag, time.Now())
+		if !send {
+			return
+		}
+	}
 	ew.WQ.Add(&eventRecord)
 }
 
+//the kubelet updates the count of a repeated event instead of creating a new one
+func (ew *EventWorker) onUpdateEvent(objOld interface{}, objNew interface{}) {
+	eventObj := objNew.(*v1.Event)
+	oldObj := objOld.(*v1.Event)
+	if !ew.qualifies(eventObj) {
+		return
+	}
+	delta := eventObj.Count - oldObj.Count
+	if delta <= 0 {
+		return
+	}
+	eventRecord := ew.processObject(eventObj)
+	ew.notifyPodsWorker(eventObj, &eventRecord, true)
+	ew.ImagePulls.OnEvent(eventObj, &eventRecord, delta)
+	bag := (*ew.ConfigManager).Get()
+	if bag.EventDedupWindow > 0 {
+		//the repeats count towards the aggregate of the event. A new record is sent if its window has expired
+		eventRecord.Occurrences = delta
+		var send bool
+		eventRecord, send = ew.Aggregator.Add(eventRecord, bag, time.Now())
+		if send {
+			ew.WQ.Add(&eventRecord)
+		}
+	}
+}
+
+func (ew *EventWorker) flushAggregates() {
+	bag := (*ew.ConfigManager).Get()
+	window := time.Duration(bag.EventDedupWindow) * time.Second
+	for _, record := range ew.Aggregator.Flush(window, time.Now()) {
+		r := record
+		ew.WQ.Add(&r)
+	}
+}
+
 func (ew *EventWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	wg.Add(1)
@@ -116,6 +172,7 @@ func (ew *EventWorker) appMetricTicker(stop <-chan struct{}, ticker *time.Ticker
 	for {
 		select {
 		case <-ticker.C:
+			ew.loadEventRules()
 			ew.buildAppDMetrics()
 		case <-stop:
 			ticker.Stop()
@@ -128,7 +185,9 @@ func (ew *EventWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticke
 	for {
 		select {
 		case <-ticker.C:
+			ew.flushAggregates()
 			ew.flushQueue()
+			ew.flushImagePulls()
 		case <-stop:
 			ticker.Stop()
 			return
@@ -143,7 +202,7 @@ func (ew *EventWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (ew *EventWorker) flushQueue() {
 	bag := (*ew.ConfigManager).Get()
-	bth := ew.AppdController.StartBT("FlushEventDataQueue")
+	bth := ew.Sinks.StartBT("FlushEventDataQueue")
 	count := ew.WQ.Len()
 	if count > 0 {
 		ew.Logger.Infof("Flushing the queue of %d event records\n", count)
@@ -151,7 +210,7 @@ func (ew *EventWorker) flushQueue() {
 		ew.Logger.Info("Event queue empty")
 	}
 	if count == 0 {
-		ew.AppdController.StopBT(bth)
+		ew.Sinks.StopBT(bth)
 		return
 	}
 
@@ -171,28 +230,41 @@ func (ew *EventWorker) flushQueue() {
 		if count == 0 || len(objList) >= bag.EventAPILimit {
 			ew.Logger.Debugf("Sending %d event records to AppD events API\n", len(objList))
 			ew.postEventRecords(&objList)
-			ew.AppdController.StopBT(bth)
+			ew.Sinks.StopBT(bth)
 			return
 		}
 	}
-	ew.AppdController.StopBT(bth)
+	ew.Sinks.StopBT(bth)
 }
 
 func (ew *EventWorker) postEventRecords(objList *[]m.EventSchema) {
 	bag := (*ew.ConfigManager).Get()
-	rc := app.NewRestClient(bag, ew.Logger)
-
 	schemaDefObj := m.NewEventSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.EventSchemaName, &schemaDefObj)
+	err := ew.Sinks.PostEvents(bag.EventSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		ew.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.EventSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
+		ew.Logger.Errorf("Issues when publishing %s records. %v\n", bag.EventSchemaName, err)
+	}
+}
+
+func (ew *EventWorker) flushImagePulls() {
+	bag := (*ew.ConfigManager).Get()
+	records := ew.ImagePulls.TakeRecords()
+	if len(records) == 0 {
+		return
+	}
+	ew.Logger.Infof("Flushing %d image pull records\n", len(records))
+	schemaDefObj := m.NewImagePullSchemaDefWrapper()
+	for len(records) > 0 {
+		batch := records
+		if bag.EventAPILimit > 0 && len(batch) > bag.EventAPILimit {
+			batch = records[:bag.EventAPILimit]
+		}
+		records = records[len(batch):]
+		err := ew.Sinks.PostEvents(bag.ImagePullSchemaName, &schemaDefObj, batch)
 		if err != nil {
-			ew.Logger.Errorf("Problems when serializing array of event schemas. %v", err)
+			ew.Logger.Errorf("Issues when publishing %s records. %v\n", bag.ImagePullSchemaName, err)
+			return
 		}
-		rc.PostAppDEvents(bag.EventSchemaName, data)
 	}
 }
 
@@ -210,7 +282,7 @@ func (ew *EventWorker) getNextQueueItem() (*m.EventSchema, bool) {
 
 func (ew *EventWorker) buildAppDMetrics() {
 	bag := (*ew.ConfigManager).Get()
-	bth := ew.AppdController.StartBT("PostEventMetrics")
+	bth := ew.Sinks.StartBT("PostEventMetrics")
 	ew.SummaryMap = make(map[string]m.ClusterEventMetrics)
 
 	var count int = 0
@@ -252,6 +324,8 @@ func (ew *EventWorker) buildAppDMetrics() {
 	}
 	lockTierCache.RUnlock()
 
+	ew.addSuppressionStats()
+
 	ml := ew.builAppDMetricsList()
 
 	//clear cache
@@ -261,8 +335,10 @@ func (ew *EventWorker) buildAppDMetrics() {
 
 	ew.Logger.Infof("Ready to push %d Event metrics\n", len(ml.Items))
 
-	ew.AppdController.PostMetrics(ml)
-	ew.AppdController.StopBT(bth)
+	if err := ew.Sinks.PostMetrics(ml); err != nil {
+		ew.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	ew.Sinks.StopBT(bth)
 }
 
 func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
@@ -286,6 +362,19 @@ func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
 
 	eventObject.LastTimestamp = e.LastTimestamp.Time
 	eventObject.Message = e.Message
+	eventObject.Fingerprint = utils.GetMessageFingerprint(e.Message)
+	eventObject.FirstSeen = e.FirstTimestamp.Time
+	if eventObject.FirstSeen.IsZero() {
+		eventObject.FirstSeen = e.CreationTimestamp.Time
+	}
+	eventObject.LastSeen = e.LastTimestamp.Time
+	if eventObject.LastSeen.IsZero() {
+		eventObject.LastSeen = eventObject.FirstSeen
+	}
+	eventObject.Occurrences = e.Count
+	if eventObject.Occurrences == 0 {
+		eventObject.Occurrences = 1
+	}
 	eventObject.Name = e.Name
 	eventObject.Namespace = e.Namespace
 	eventObject.ObjectKind = e.InvolvedObject.Kind
@@ -296,6 +385,7 @@ func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
 	for _, r := range e.GetOwnerReferences() {
 		eventObject.OwnerReferences += r.Name + ";"
 	}
+	eventObject.OwnerKind, eventObject.OwnerName = ew.OwnerResolver.ResolveObject(e.InvolvedObject.Kind, e.InvolvedObject.Namespace, e.InvolvedObject.Name)
 
 	eventObject.Reason = e.Reason
 	eventObject.Type = e.Type
@@ -305,34 +395,58 @@ func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
 	eventObject.SourceComponent = e.Source.Component
 	eventObject.SourceHost = e.Source.Host
 
-	cat, sub := ew.GetEventCategory(&eventObject)
+	cat, sub, severity := ew.GetEventCategory(&eventObject)
 	eventObject.Category = cat
 	eventObject.SubCategory = sub
+	eventObject.Severity = severity
+
+	return eventObject
+}
 
-	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" && eventObject.Category == "error" {
-		ew.PodsWorker.OnPodErrorEvent(eventObject.ObjectName, eventObject)
+//passes the pod events to the pods worker. Repeated occurrences of an event only update the probe failures and image pulls
+func (ew *EventWorker) notifyPodsWorker(e *v1.Event, eventObject *m.EventSchema, repeated bool) {
+	if ew.PodsWorker == nil || eventObject.ObjectKind != "Pod" {
+		return
+	}
+	if !repeated && eventObject.Category == "error" {
+		ew.PodsWorker.OnPodErrorEvent(eventObject.ObjectName, *eventObject)
 	}
 
-	return eventObject
+	//liveness probe failures are used to classify container restarts
+	if e.Reason == "Unhealthy" && strings.HasPrefix(e.Message, "Liveness probe failed") {
+		ew.PodsWorker.OnProbeFailure(e.InvolvedObject.Namespace, e.InvolvedObject.Name, utils.GetContainerFromFieldPath(e.InvolvedObject.FieldPath), eventObject.LastSeen)
+	}
+
+	//image pulls are a stage of the pod startup. Pulls that happened before the agent started are not replayed
+	if (e.Reason == "Pulling" || e.Reason == "Pulled") && !eventObject.LastSeen.Before(ew.StartTime) {
+		ew.PodsWorker.OnImagePullEvent(e.InvolvedObject.Namespace, e.InvolvedObject.Name, e.Reason, eventObject.LastSeen)
+	}
 }
 
 func buildTierKeyForEvent(namespace, tierName string) string {
 	return fmt.Sprintf("%s/%s", namespace, tierName)
 }
 
+//events on pods and their controllers roll up to the tier of the top-level owner
+func (ew *EventWorker) getEventTier(eventObject *m.EventSchema) string {
+	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" {
+		_, tierName, _ := ew.PodsWorker.GetCachedPod(eventObject.ObjectNamespace, eventObject.ObjectName)
+		if tierName != "" {
+			return tierName
+		}
+	}
+	if IsWorkloadKind(eventObject.OwnerKind) {
+		return eventObject.OwnerName
+	}
+	return ""
+}
+
 func (ew *EventWorker) summarize(eventObject *m.EventSchema) {
 	bag := (*ew.ConfigManager).Get()
 	//	var err error = nil
 	var tierName string = ""
 	var tierKey string = ""
-	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" {
-		_, tierName, _ = ew.PodsWorker.GetCachedPod(eventObject.ObjectNamespace, eventObject.ObjectName)
-		//		if err != nil {
-		//			fmt.Printf("Unable to lookup Pod %s for event. %v\n", eventObject.ObjectName, err)
-		//		} else {
-		//			fmt.Printf("Found Pod %s for event. %s\n", eventObject.ObjectName, eventObject.Reason)
-		//		}
-	}
+	tierName = ew.getEventTier(eventObject)
 	//global metrics
 	summary, okSum := ew.SummaryMap[m.ALL]
 	if !okSum {
@@ -371,7 +485,7 @@ func (ew *EventWorker) summarize(eventObject *m.EventSchema) {
 	cat := eventObject.Category
 	sub := eventObject.SubCategory
 
-	if cat == "error" {
+	if cat == m.EVENT_CATEGORY_ERROR {
 		summary.EventError++
 		summaryNS.EventError++
 		if summaryTier != nil {
@@ -391,43 +505,13 @@ func (ew *EventWorker) summarize(eventObject *m.EventSchema) {
 			}
 		}
 	}
-	switch sub {
-	case "pod":
-		summary.PodIssues++
-		summaryNS.PodIssues++
-		if summaryTier != nil {
-			summaryTier.PodIssues++
-		}
-		break
-	case "image":
-		summary.ImagePullErrors++
-		summaryNS.ImagePullErrors++
-		if summaryTier != nil {
-			summaryTier.ImagePullErrors++
-		}
-		break
-	case "reduction":
-		summary.ScaleDowns++
-		summaryNS.ScaleDowns++
-		if summaryTier != nil {
-			summaryTier.ScaleDowns++
-		}
-		break
-	case "storage":
-		summary.StorageIssues++
-		summaryNS.StorageIssues++
-		if summaryTier != nil {
-			summaryTier.StorageIssues++
-		}
-		break
-	case "eviction":
-		summary.EvictionThreats++
-		summaryNS.EvictionThreats++
+	if sub != "" {
+		metricName := ew.getSubCategoryMetric(sub)
+		summary.IncrementSubCategory(metricName)
+		summaryNS.IncrementSubCategory(metricName)
 		if summaryTier != nil {
-			summaryTier.EvictionThreats++
+			summaryTier.IncrementSubCategory(metricName)
 		}
-		break
-
 	}
 
 	ew.SummaryMap[m.ALL] = summary
@@ -437,12 +521,43 @@ func (ew *EventWorker) summarize(eventObject *m.EventSchema) {
 	}
 }
 
+func (ew *EventWorker) addSuppressionStats() {
+	suppressed, storms := ew.Aggregator.TakeStats()
+	summary := ew.SummaryMap[m.ALL]
+	for ns, count := range suppressed {
+		summary.SuppressedEvents += count
+		if summaryNS, ok := ew.SummaryMap[ns]; ok {
+			summaryNS.SuppressedEvents += count
+			ew.SummaryMap[ns] = summaryNS
+		}
+	}
+	for ns, count := range storms {
+		summary.EventStorms += count
+		if summaryNS, ok := ew.SummaryMap[ns]; ok {
+			summaryNS.EventStorms += count
+			ew.SummaryMap[ns] = summaryNS
+		}
+	}
+	ew.SummaryMap[m.ALL] = summary
+}
+
 func (ew *EventWorker) builAppDMetricsList() m.AppDMetricList {
 	ml := m.NewAppDMetricList()
 	var list []m.AppDMetric
+	subMetrics := ew.getSubCategoryMetricNames()
 	for _, metricEvent := range ew.SummaryMap {
 		objMap := metricEvent.Unwrap()
 		ew.addMetricToList(*objMap, metricEvent, &list)
+		//report all known subcategories, including the ones without events
+		for _, metricName := range subMetrics {
+			appdMetric := m.NewAppDMetric(metricName, metricEvent.SubCategories[metricName], metricEvent.GetPath())
+			appdMetric.MetricDelta = true
+			list = append(list, appdMetric)
+		}
+	}
+	for _, metricRegistry := range ew.ImagePulls.TakeMetrics(time.Now()) {
+		objMap := metricRegistry.Unwrap()
+		ew.addMetricToList(*objMap, metricRegistry, &list)
 	}
 
 	ml.Items = list
@@ -450,10 +565,11 @@ func (ew *EventWorker) builAppDMetricsList() m.AppDMetricList {
 }
 
 func (ew *EventWorker) addMetricToList(objMap map[string]interface{}, metric m.AppDMetricInterface, list *[]m.AppDMetric) {
-
+	deltaMetric, hasDeltas := metric.(m.AppDDeltaMetrics)
 	for fieldName, fieldValue := range objMap {
 		if !metric.ShouldExcludeField(fieldName) {
 			appdMetric := m.NewAppDMetric(fieldName, fieldValue.(int64), metric.GetPath())
+			appdMetric.MetricDelta = hasDeltas && deltaMetric.IsDeltaField(fieldName)
 			*list = append(*list, appdMetric)
 		}
 	}
@@ -496,188 +612,58 @@ func eventFromPod(podObj *v1.Pod, reason string, message string, eventType strin
 
 }
 
-func (ew *EventWorker) GetEventCategory(eventSchema *m.EventSchema) (string, string) {
-	msg := eventSchema.Message
-	cat := "info"
-	sub := ""
-	switch eventSchema.Reason {
-	case "Created":
-	case "Started":
-	case "Pulling":
-	case "Pulled":
-	case "NodeReady":
-	case "NodeSchedulable":
-	case "VolumeResizeSuccessful":
-	case "FileSystemResizeSuccessful":
-	case "AlreadyMountedVolume":
-	case "SuccessfulDetachVolume":
-	case "SuccessfulAttachVolume":
-	case "SuccessfulMountVolume":
-	case "SuccessfulUnMountVolume":
-	case "Rebooted":
-		break
-	case "NodeAllocatableEnforced":
-		cat = "error"
-		break
-	case "ScalingReplicaSet":
-		if strings.Contains(msg, "Scaled down") {
-			sub = "reduction"
-			cat = "error"
-		} else {
-			cat = "info"
-		}
-		break
-	case "Killing":
-		sub = "reduction"
-		cat = "error"
-		break
-	case "Failed":
-		if strings.Contains(msg, "ImagePullBackOff") || strings.Contains(msg, "image") {
-			sub = "image"
-		} else {
-			sub = "pod"
-		}
-		cat = "error"
-		break
-	case "Preempting":
-		cat = "error"
-		break
-	case "BackOff":
-		if strings.Contains(msg, "image") {
-			sub = "image"
-		}
-		if strings.Contains(msg, "container") {
-			sub = "pod"
-		}
-		cat = "error"
-		break
-	case "FailedCreate":
-		if strings.Contains(msg, "quota") {
-			sub = "quota"
+func (ew *EventWorker) GetEventCategory(eventSchema *m.EventSchema) (string, string, string) {
+	lockEventRules.RLock()
+	defer lockEventRules.RUnlock()
+	return ew.Rules.Categorize(eventSchema)
+}
+
+func (ew *EventWorker) getSubCategoryMetric(sub string) string {
+	lockEventRules.RLock()
+	defer lockEventRules.RUnlock()
+	return ew.Rules.GetMetric(sub)
+}
+
+func (ew *EventWorker) getSubCategoryMetricNames() []string {
+	lockEventRules.RLock()
+	defer lockEventRules.RUnlock()
+	return ew.Rules.MetricNames()
+}
+
+//rebuilds the rule set from the agent config and the optional rules configMap
+func (ew *EventWorker) loadEventRules() {
+	bag := (*ew.ConfigManager).Get()
+	configured := append([]m.EventRule{}, bag.EventRules...)
+	if bag.EventRulesConfigMap != "" {
+		cmRules, err := ew.readEventRulesConfigMap(bag.AgentNamespace, bag.EventRulesConfigMap)
+		if err != nil {
+			ew.Logger.WithFields(log.Fields{"configMap": bag.EventRulesConfigMap, "error": err}).Warn("Unable to load event rules from configMap")
 		} else {
-			sub = "pod"
+			configured = append(configured, cmRules...)
 		}
-		cat = "error"
-		break
-	case "ExceededGracePeriod":
-	case "FailedKillPod":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedCreatePodContainer":
-		sub = "pod"
-		cat = "error"
-		break
-	case "NetworkNotReady":
-	case "InspectFailed":
-		cat = "error"
-		break
-	case "ErrImageNeverPull":
-		sub = "image"
-		cat = "error"
-		break
-	case "NodeNotReady":
-	case "NodeNotSchedulable":
-	case "KubeletSetupFailed":
-		cat = "error"
-		break
-	case "FailedAttachVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedDetachVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedMount":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedBinding":
-		sub = "storage"
-		cat = "error"
-		break
-	case "VolumeResizeFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FileSystemResizeFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedUnMount":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedMapVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedUnmapDevice":
-		sub = "storage"
-		cat = "error"
-		break
-	case "HostPortConflict":
-	case "NodeSelectorMismatching":
-		cat = "error"
-		break
-	case "ContainerGCFailed":
-		sub = "pod"
-		cat = "error"
-		break
-	case "ImageGCFailed":
-		sub = "image"
-		cat = "error"
-		break
-	case "FailedNodeAllocatableEnforcement":
-		cat = "error"
-		break
-	case "UnsupportedMountOption":
-		sub = "storage"
-		cat = "error"
-		break
-	case "SandboxChanged":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedCreatePodSandBox":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedPodSandBoxStatus":
-		sub = "pod"
-		cat = "error"
-		break
-	case "InvalidDiskCapacity":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FreeDiskSpaceFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "Unhealthy":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedSync":
-	case "FailedValidation":
-	case "FailedPostStartHook":
-	case "FailedPreStopHook":
-	case "UnfinishedPreStopHook":
-		cat = "error"
-		break
-	case "Evicted":
-		sub = "eviction"
-	case "InsufficientFreeCPU":
-	case "InsufficientFreeMemory":
-	case "FailedDaemonPod":
-	case "NodeHasDiskPressure":
-	case "EvictionThresholdMet":
-	case "ErrorReconciliationRetryTimeout":
-
-		cat = "error"
-		break
-	}
-	return cat, sub
+	}
+	rules, errs := m.NewEventRuleSet(configured)
+	for _, err := range errs {
+		ew.Logger.WithField("error", err).Warn("Skipping invalid event rule")
+	}
+	lockEventRules.Lock()
+	*ew.Rules = rules
+	lockEventRules.Unlock()
+	ew.Logger.Debugf("Loaded %d event rules\n", len(rules.Rules))
+}
+
+func (ew *EventWorker) readEventRulesConfigMap(namespace, name string) ([]m.EventRule, error) {
+	cm, err := ew.Client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get configMap %s/%s. %v", namespace, name, err)
+	}
+	data, ok := cm.Data[m.EVENT_RULES_KEY]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s does not have key %s", namespace, name, m.EVENT_RULES_KEY)
+	}
+	var rules []m.EventRule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("Unable to deserialize event rules. %v", err)
+	}
+	return rules, nil
 }
@@ -0,0 +1,119 @@
+package workers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	"k8s.io/api/core/v1"
+)
+
+var lockImagePulls = sync.Mutex{}
+
+//pulls that never complete, e.g. when the pod is deleted mid-pull, are forgotten after this period
+const imagePullPendingTTL = time.Hour
+
+/*
+ Correlates the Pulling, Pulled and Failed events of the kubelet per pod, container and image
+ into image pull records and aggregates them per registry.
+ Events last seen before the agent started are ignored, so that the pulls listed at startup are not counted again
+*/
+type ImagePullTracker struct {
+	Pending    map[string]time.Time //namespace/pod/container/image -> start of the pull
+	Queue      []m.ImagePullSchema
+	Registries map[string]*m.ClusterRegistryMetrics
+	Started    time.Time
+}
+
+func NewImagePullTracker(started time.Time) *ImagePullTracker {
+	return &ImagePullTracker{Pending: make(map[string]time.Time), Queue: []m.ImagePullSchema{}, Registries: make(map[string]*m.ClusterRegistryMetrics), Started: started}
+}
+
+func getImagePullKey(eventObject *m.EventSchema, containerName string, image string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", eventObject.ObjectNamespace, eventObject.ObjectName, containerName, image)
+}
+
+//occurrences is the number of pulls the event stands for, e.g. the increase of the count of a repeated event
+func (ipt *ImagePullTracker) OnEvent(e *v1.Event, eventObject *m.EventSchema, occurrences int32) {
+	if eventObject.ObjectKind != "Pod" || occurrences <= 0 || eventObject.LastSeen.Before(ipt.Started) {
+		return
+	}
+	if e.Reason != "Pulling" && e.Reason != "Pulled" && !(e.Reason == "Failed" && strings.HasPrefix(e.Message, "Failed to pull image")) {
+		return
+	}
+	image := utils.GetImageFromPullMessage(e.Message)
+	if image == "" {
+		return
+	}
+	containerName := utils.GetContainerFromFieldPath(e.InvolvedObject.FieldPath)
+	key := getImagePullKey(eventObject, containerName, image)
+
+	lockImagePulls.Lock()
+	defer lockImagePulls.Unlock()
+
+	if e.Reason == "Pulling" {
+		ipt.Pending[key] = eventObject.LastSeen
+		return
+	}
+
+	record := m.NewImagePullSchema(eventObject, containerName, image, utils.ParseImageReference(image))
+	started, pending := ipt.Pending[key]
+	delete(ipt.Pending, key)
+
+	switch {
+	case e.Reason == "Failed":
+		record.Status = m.IMAGE_PULL_FAILED
+		record.ErrorClass = utils.ClassifyImagePullError(e.Message)
+		record.ErrorMessage = utils.TruncateString(e.Message, app.MAX_FIELD_LENGTH)
+	case strings.Contains(e.Message, "already present"):
+		record.Status = m.IMAGE_PULL_CACHED
+	default:
+		record.Status = m.IMAGE_PULL_PULLED
+		record.Duration, record.Bytes = utils.ParseImagePullMessage(e.Message)
+	}
+	//older kubelets do not report the duration of the pull
+	if record.Status != m.IMAGE_PULL_CACHED && record.Duration == 0 && pending && eventObject.LastSeen.After(started) {
+		record.Duration = eventObject.LastSeen.Sub(started).Nanoseconds() / 1000000
+	}
+
+	ipt.Queue = append(ipt.Queue, record)
+	registry, ok := ipt.Registries[record.Registry]
+	if !ok {
+		metrics := m.NewClusterRegistryMetrics(record.Registry)
+		registry = &metrics
+		ipt.Registries[record.Registry] = registry
+	}
+	for i := int32(0); i < occurrences; i++ {
+		registry.AddPull(&record)
+	}
+}
+
+func (ipt *ImagePullTracker) TakeRecords() []m.ImagePullSchema {
+	lockImagePulls.Lock()
+	defer lockImagePulls.Unlock()
+	records := ipt.Queue
+	ipt.Queue = []m.ImagePullSchema{}
+	return records
+}
+
+//returns the metrics of the interval and resets them. Known registries keep reporting zeros
+func (ipt *ImagePullTracker) TakeMetrics(now time.Time) []m.ClusterRegistryMetrics {
+	lockImagePulls.Lock()
+	defer lockImagePulls.Unlock()
+	list := []m.ClusterRegistryMetrics{}
+	for registry, metrics := range ipt.Registries {
+		list = append(list, *metrics)
+		empty := m.NewClusterRegistryMetrics(registry)
+		ipt.Registries[registry] = &empty
+	}
+	for key, started := range ipt.Pending {
+		if now.Sub(started) > imagePullPendingTTL {
+			delete(ipt.Pending, key)
+		}
+	}
+	return list
+}
@@ -1,7 +1,6 @@
 package workers
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -17,7 +16,6 @@ import (
 
 	app "github.com/appdynamics/cluster-agent/appd"
 	batchTypes "k8s.io/api/batch/v1"
-	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -35,14 +33,19 @@ type JobsWorker struct {
 	SummaryMap     map[string]m.ClusterJobMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	K8sConfig      *rest.Config
 	Logger         *log.Logger
 }
 
-func NewJobsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, config *rest.Config, l *log.Logger) JobsWorker {
+func NewJobsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, config *rest.Config, resolver *OwnerResolver, l *log.Logger) JobsWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
-	pw := JobsWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterJobMetrics), WQ: queue, AppdController: controller, K8sConfig: config, Logger: l}
-	pw.initJobInformer(client)
+	pw := JobsWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterJobMetrics), WQ: queue, AppdController: controller, Sinks: sinks, K8sConfig: config, Logger: l}
+	if i := pw.initJobInformer(client); i != nil {
+		resolver.RegisterStore("Job", i.GetStore(), i.HasSynced)
+	} else {
+		resolver.Skip("Job")
+	}
 	return pw
 }
 
@@ -62,7 +65,7 @@ func (nw *JobsWorker) initJobInformer(client *kubernetes.Clientset) cache.Shared
 				return batchClient.Jobs(metav1.NamespaceAll).Watch(options)
 			},
 		},
-		&v1.Node{},
+		&batchTypes.Job{},
 		0,
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 	)
@@ -156,7 +159,7 @@ func (pw *JobsWorker) appMetricTicker(stop <-chan struct{}, ticker *time.Ticker)
 }
 
 func (pw *JobsWorker) buildAppDMetrics() {
-	bth := pw.AppdController.StartBT("SendJobMetrics")
+	bth := pw.Sinks.StartBT("SendJobMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterJobMetrics)
 	var count int = 0
 	for _, obj := range pw.informer.GetStore().List() {
@@ -174,8 +177,10 @@ func (pw *JobsWorker) buildAppDMetrics() {
 
 	pw.Logger.Infof("Ready to push %d Job metrics\n", len(ml.Items))
 
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *JobsWorker) processObject(j *batchTypes.Job) m.JobSchema {
@@ -302,6 +307,12 @@ func (pw *JobsWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d job records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
 		case <-stop:
 			ticker.Stop()
@@ -312,7 +323,7 @@ func (pw *JobsWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker
 
 func (pw *JobsWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
-	bth := pw.AppdController.StartBT("FlushJobEventsQueue")
+	bth := pw.Sinks.StartBT("FlushJobEventsQueue")
 	count := pw.WQ.Len()
 	pw.Logger.Infof("Flushing the queue of job %d records\n", count)
 	if count == 0 {
@@ -338,26 +349,16 @@ func (pw *JobsWorker) flushQueue() {
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *JobsWorker) postJobRecords(objList *[]m.JobSchema) {
 	bag := (*pw.ConfigManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewJobSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.JobSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.JobSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.JobSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of job schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.JobSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.JobSchemaName, err)
 	}
-
 }
 
 func (pw *JobsWorker) getNextQueueItem() (*m.JobSchema, bool) {
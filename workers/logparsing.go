@@ -0,0 +1,121 @@
+package workers
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+var lockLogParsers = sync.RWMutex{}
+
+/*
+ Compiled log parsers of the agent config. The parsers are recompiled when the config changes.
+ Also tracks the custom fields of the log schema, so that the schema is re-validated when they change
+*/
+type LogParserSet struct {
+	Parsers         []*m.LogParser
+	Builtin         map[string]*m.LogParser //format -> parser selected by annotation
+	Logger          *log.Logger
+	parserSignature string
+	fieldSignature  string
+}
+
+func NewLogParserSet(l *log.Logger) *LogParserSet {
+	ps := LogParserSet{Parsers: []*m.LogParser{}, Builtin: make(map[string]*m.LogParser), Logger: l}
+	for _, format := range []string{m.LOG_FORMAT_JSON, m.LOG_FORMAT_LOGFMT} {
+		parser, _ := m.NewLogParser(m.LogParserConfig{Name: format, Format: format})
+		ps.Builtin[format] = parser
+	}
+	return &ps
+}
+
+func (ps *LogParserSet) load(bag *m.AppDBag) []*m.LogParser {
+	data, _ := json.Marshal(bag.LogParsers)
+	signature := string(data)
+
+	lockLogParsers.RLock()
+	if signature == ps.parserSignature {
+		defer lockLogParsers.RUnlock()
+		return ps.Parsers
+	}
+	lockLogParsers.RUnlock()
+
+	list := []*m.LogParser{}
+	for _, config := range bag.LogParsers {
+		parser, err := m.NewLogParser(config)
+		if err != nil {
+			ps.Logger.Errorf("Log parser skipped. %v\n", err)
+			continue
+		}
+		list = append(list, parser)
+	}
+
+	lockLogParsers.Lock()
+	defer lockLogParsers.Unlock()
+	ps.Parsers = list
+	ps.parserSignature = signature
+	return list
+}
+
+//returns the parser of the container. Nil if the logs are not parsed
+func (ps *LogParserSet) Select(p *v1.Pod, owner string, containerName string, bag *m.AppDBag) *m.LogParser {
+	parsers := ps.load(bag)
+	if p != nil && bag.LogParserAnnotation != "" {
+		if val, ok := p.Annotations[bag.LogParserAnnotation]; ok {
+			val = strings.TrimSpace(val)
+			for _, parser := range parsers {
+				if parser.Config.Name == val {
+					return parser
+				}
+			}
+			if parser, ok := ps.Builtin[strings.ToLower(val)]; ok {
+				return parser
+			}
+			ps.Logger.Warnf("Unknown log format %s requested by pod %s/%s\n", val, p.Namespace, p.Name)
+			return nil
+		}
+	}
+	namespace := ""
+	if p != nil {
+		namespace = p.Namespace
+	}
+	for _, parser := range parsers {
+		if parser.Matches(namespace, owner, containerName) {
+			return parser
+		}
+	}
+	return nil
+}
+
+//returns true if the custom fields changed since the last call
+func (ps *LogParserSet) CustomFieldsChanged(bag *m.AppDBag) bool {
+	fields := []string{}
+	for _, cf := range bag.LogCustomFields {
+		fields = append(fields, cf.GetField()+":"+cf.GetType())
+	}
+	sort.Strings(fields)
+	signature := strings.Join(fields, ",")
+
+	lockLogParsers.Lock()
+	defer lockLogParsers.Unlock()
+	changed := signature != ps.fieldSignature
+	ps.fieldSignature = signature
+	return changed
+}
+
+//fills the typed fields of the log record if the message has the format of the parser
+func parseLogRecord(logSchema *m.LogSchema, parser *m.LogParser, bag *m.AppDBag) {
+	if parser == nil {
+		return
+	}
+	parsed, ok := parser.Parse(logSchema.Message)
+	if !ok {
+		return
+	}
+	logSchema.ApplyParsed(&parsed, parser.Config.Name, bag.LogCustomFields)
+}
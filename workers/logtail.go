@@ -0,0 +1,428 @@
+package workers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	LOG_TAIL_RETRY_MIN   time.Duration = 5 * time.Second
+	LOG_TAIL_RETRY_MAX   time.Duration = time.Minute
+	LOG_TAIL_ENTRY_FLUSH time.Duration = time.Second //wait for continuation lines before sending an entry
+	LOG_TAIL_MAX_LINE    int           = 1024 * 1024
+)
+
+var lockLogTails = sync.RWMutex{}
+var lockLogOffsets = sync.RWMutex{}
+var lockLogQueue = sync.Mutex{}
+var lockLogBudgets = sync.Mutex{}
+
+type logTail struct {
+	Namespace     string
+	PodName       string
+	PodOwner      string
+	ContainerName string
+	Parser        *m.LogParser
+	Stop          chan struct{}
+}
+
+//per pod rate limits. Lines are counted per second, bytes per minute
+type logBudget struct {
+	LineWindow time.Time
+	Lines      int
+	ByteWindow time.Time
+	Bytes      int
+	Dropped    int64
+}
+
+/*
+ Continuously follows the logs of the containers of the pods opted in by annotation.
+ Each container is streamed from the timestamp of the last collected line, so that the stream
+ resumes without duplicates after container restarts and connection failures
+*/
+type LogTailer struct {
+	Client      *kubernetes.Clientset
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	Tails       map[string]*logTail   //container key -> active tail
+	Offsets     map[string]time.Time  //container key -> timestamp of the last collected line
+	Budgets     map[string]*logBudget //pod key -> rate limits
+	Queue       []m.LogSchema
+	Parsers     *LogParserSet
+	postRecords func(objList *[]m.LogSchema)
+}
+
+func NewLogTailer(client *kubernetes.Clientset, cm *config.MutexConfigManager, parsers *LogParserSet, post func(objList *[]m.LogSchema), l *log.Logger) *LogTailer {
+	return &LogTailer{Client: client, ConfManager: cm, Logger: l, Tails: make(map[string]*logTail), Offsets: make(map[string]time.Time),
+		Budgets: make(map[string]*logBudget), Queue: []m.LogSchema{}, Parsers: parsers, postRecords: post}
+}
+
+func getLogTailKey(namespace, podName, containerName string) string {
+	return fmt.Sprintf("%s_%s_%s", namespace, podName, containerName)
+}
+
+//containers of the pod requested by the annotation
+func (lt *LogTailer) getTailedContainers(p *v1.Pod, bag *m.AppDBag) []string {
+	val, ok := p.Annotations[bag.LogTailAnnotation]
+	if !ok || bag.LogTailAnnotation == "" {
+		return []string{}
+	}
+	val = strings.TrimSpace(val)
+	if all, err := strconv.ParseBool(val); err == nil {
+		if !all {
+			return []string{}
+		}
+		list := []string{}
+		for _, c := range p.Spec.Containers {
+			list = append(list, c.Name)
+		}
+		return list
+	}
+	list := []string{}
+	for _, name := range strings.Split(val, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+//starts and stops the tails of the pod according to its annotation
+func (lt *LogTailer) Sync(p *v1.Pod, podSchema *m.PodSchema) {
+	bag := (*lt.ConfManager).Get()
+	requested := []string{}
+	if p.DeletionTimestamp == nil {
+		requested = lt.getTailedContainers(p, bag)
+	}
+
+	lockLogTails.Lock()
+	defer lockLogTails.Unlock()
+	for key, tail := range lt.Tails {
+		if tail.Namespace == p.Namespace && tail.PodName == p.Name && !utils.StringInSlice(tail.ContainerName, requested) {
+			close(tail.Stop)
+			delete(lt.Tails, key)
+		}
+	}
+	for _, st := range p.Status.ContainerStatuses {
+		if !utils.StringInSlice(st.Name, requested) || st.State.Running == nil {
+			continue
+		}
+		key := getLogTailKey(p.Namespace, p.Name, st.Name)
+		if _, ok := lt.Tails[key]; ok {
+			continue
+		}
+		parser := lt.Parsers.Select(p, podSchema.Owner, st.Name, bag)
+		tail := logTail{Namespace: p.Namespace, PodName: p.Name, PodOwner: podSchema.Owner, ContainerName: st.Name, Parser: parser, Stop: make(chan struct{})}
+		lt.Tails[key] = &tail
+		lt.Logger.WithFields(log.Fields{"pod": p.Name, "container": st.Name}).Info("Starting log tail")
+		go lt.follow(&tail)
+	}
+}
+
+//stops the tails of the pod and discards its offsets
+func (lt *LogTailer) StopPod(namespace, podName string) {
+	lockLogTails.Lock()
+	for key, tail := range lt.Tails {
+		if tail.Namespace == namespace && tail.PodName == podName {
+			close(tail.Stop)
+			delete(lt.Tails, key)
+		}
+	}
+	lockLogTails.Unlock()
+
+	prefix := getLogTailKey(namespace, podName, "")
+	lockLogOffsets.Lock()
+	for key := range lt.Offsets {
+		if strings.HasPrefix(key, prefix) {
+			delete(lt.Offsets, key)
+		}
+	}
+	lockLogOffsets.Unlock()
+
+	lockLogBudgets.Lock()
+	delete(lt.Budgets, utils.GetKey(namespace, podName))
+	lockLogBudgets.Unlock()
+}
+
+func (lt *LogTailer) getOffset(key string) (time.Time, bool) {
+	lockLogOffsets.RLock()
+	defer lockLogOffsets.RUnlock()
+	t, ok := lt.Offsets[key]
+	return t, ok
+}
+
+func (lt *LogTailer) setOffset(key string, t time.Time) {
+	lockLogOffsets.Lock()
+	defer lockLogOffsets.Unlock()
+	if last, ok := lt.Offsets[key]; !ok || t.After(last) {
+		lt.Offsets[key] = t
+	}
+}
+
+//reconnects until the tail is stopped
+func (lt *LogTailer) follow(tail *logTail) {
+	retry := LOG_TAIL_RETRY_MIN
+	for {
+		received, err := lt.stream(tail)
+		if err != nil {
+			lt.Logger.Debugf("Log tail of %s/%s %s interrupted. %v\n", tail.Namespace, tail.PodName, tail.ContainerName, err)
+		}
+		if received {
+			retry = LOG_TAIL_RETRY_MIN
+		}
+		select {
+		case <-tail.Stop:
+			return
+		case <-time.After(retry):
+		}
+		if retry < LOG_TAIL_RETRY_MAX {
+			retry = retry * 2
+		}
+	}
+}
+
+//streams the container logs until the stream ends or the tail is stopped. Returns true if any lines were received
+func (lt *LogTailer) stream(tail *logTail) (bool, error) {
+	key := getLogTailKey(tail.Namespace, tail.PodName, tail.ContainerName)
+	offset, resume := lt.getOffset(key)
+	req := lt.Client.CoreV1().RESTClient().Get().
+		Namespace(tail.Namespace).
+		Name(tail.PodName).
+		Resource("pods").
+		SubResource("log").
+		Param("follow", "true").
+		Param("container", tail.ContainerName).
+		Param("timestamps", "true")
+	if resume {
+		//second precision. Lines up to the offset are skipped when read
+		req.Param("sinceTime", offset.Format(time.RFC3339))
+	} else {
+		bag := (*lt.ConfManager).Get()
+		since := int64(bag.SnapshotSyncInterval)
+		req.Param("sinceSeconds", strconv.FormatInt(since, 10))
+	}
+	readCloser, err := req.Stream()
+	if err != nil {
+		return false, err
+	}
+	defer readCloser.Close()
+
+	lines := make(chan string)
+	done := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(readCloser)
+		scanner.Buffer(make([]byte, 64*1024), LOG_TAIL_MAX_LINE)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-tail.Stop:
+				done <- nil
+				return
+			}
+		}
+		err := scanner.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		done <- err
+	}()
+
+	continuation := lt.getContinuationRegex()
+	received := false
+	var entry *m.LogSchema = nil
+	lastLine := time.Now()
+	flush := time.NewTicker(LOG_TAIL_ENTRY_FLUSH)
+	defer flush.Stop()
+	for {
+		select {
+		case l := <-lines:
+			ts, msg := parseLogLine(l)
+			if ts != nil && resume && !ts.After(offset) {
+				continue
+			}
+			received = true
+			lastLine = time.Now()
+			if entry != nil && continuation != nil && continuation.MatchString(msg) {
+				entry.Message = entry.Message + "\n" + msg
+				if ts != nil {
+					lt.setOffset(key, *ts)
+				}
+				continue
+			}
+			lt.enqueue(tail, entry)
+			entry = lt.newEntry(tail, ts, msg)
+			if ts != nil {
+				lt.setOffset(key, *ts)
+			}
+		case <-flush.C:
+			if entry != nil && time.Since(lastLine) >= LOG_TAIL_ENTRY_FLUSH {
+				lt.enqueue(tail, entry)
+				entry = nil
+			}
+		case <-tail.Stop:
+			lt.enqueue(tail, entry)
+			return received, nil
+		case err := <-done:
+			lt.enqueue(tail, entry)
+			if err == io.EOF {
+				return received, nil
+			}
+			return received, err
+		}
+	}
+}
+
+func parseLogLine(l string) (*time.Time, string) {
+	parts := strings.SplitN(l, " ", 2)
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, l
+	}
+	if len(parts) > 1 {
+		return &t, parts[1]
+	}
+	return &t, ""
+}
+
+func (lt *LogTailer) getContinuationRegex() *regexp.Regexp {
+	bag := (*lt.ConfManager).Get()
+	if bag.LogTailContinuationPattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(bag.LogTailContinuationPattern)
+	if err != nil {
+		lt.Logger.Errorf("Invalid log continuation pattern %s. %v\n", bag.LogTailContinuationPattern, err)
+		return nil
+	}
+	return re
+}
+
+func (lt *LogTailer) newEntry(tail *logTail, ts *time.Time, msg string) *m.LogSchema {
+	bag := (*lt.ConfManager).Get()
+	logSchema := m.NewLogObj()
+	logSchema.ClusterName = bag.AppName
+	logSchema.Namespace = tail.Namespace
+	logSchema.PodOwner = tail.PodOwner
+	logSchema.PodName = tail.PodName
+	logSchema.ContainerName = tail.ContainerName
+	logSchema.Timestamp = ts
+	logSchema.Message = msg
+	//continuation lines are appended to the parsed message
+	parseLogRecord(&logSchema, tail.Parser, bag)
+	return &logSchema
+}
+
+//applies the rate limits of the pod and queues the entry
+func (lt *LogTailer) enqueue(tail *logTail, entry *m.LogSchema) {
+	if entry == nil {
+		return
+	}
+	bag := (*lt.ConfManager).Get()
+	entry.Message = utils.TruncateString(entry.Message, app.MAX_FIELD_LENGTH)
+	now := time.Now()
+
+	lockLogBudgets.Lock()
+	key := utils.GetKey(tail.Namespace, tail.PodName)
+	budget, ok := lt.Budgets[key]
+	if !ok {
+		budget = &logBudget{LineWindow: now, ByteWindow: now}
+		lt.Budgets[key] = budget
+	}
+	if now.Sub(budget.LineWindow) >= time.Second {
+		budget.LineWindow = now
+		budget.Lines = 0
+	}
+	if now.Sub(budget.ByteWindow) >= time.Minute {
+		budget.ByteWindow = now
+		budget.Bytes = 0
+	}
+	size := len(entry.Message)
+	if bag.LogTailLinesPerSecond > 0 && budget.Lines >= bag.LogTailLinesPerSecond ||
+		bag.LogTailBytesPerMinute > 0 && budget.Bytes+size > bag.LogTailBytesPerMinute {
+		budget.Dropped++
+		lockLogBudgets.Unlock()
+		return
+	}
+	budget.Lines++
+	budget.Bytes += size
+	dropped := budget.Dropped
+	budget.Dropped = 0
+	lockLogBudgets.Unlock()
+
+	lockLogQueue.Lock()
+	defer lockLogQueue.Unlock()
+	if dropped > 0 {
+		//let the readers know about the gap
+		notice := *entry
+		notice.Message = fmt.Sprintf("%d log entries of pod %s dropped due to rate limits", dropped, tail.PodName)
+		lt.Queue = append(lt.Queue, notice)
+	}
+	lt.Queue = append(lt.Queue, *entry)
+}
+
+func (lt *LogTailer) Start(stopCh <-chan struct{}) {
+	bag := (*lt.ConfManager).Get()
+	ticker := time.NewTicker(time.Duration(bag.SnapshotSyncInterval) * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			lt.flushQueue()
+		case <-stopCh:
+			ticker.Stop()
+			lt.stopAll()
+			lt.flushQueue()
+			return
+		}
+	}
+}
+
+func (lt *LogTailer) stopAll() {
+	lockLogTails.Lock()
+	defer lockLogTails.Unlock()
+	for key, tail := range lt.Tails {
+		close(tail.Stop)
+		delete(lt.Tails, key)
+	}
+}
+
+func (lt *LogTailer) flushQueue() {
+	lockLogQueue.Lock()
+	queue := lt.Queue
+	lt.Queue = []m.LogSchema{}
+	lockLogQueue.Unlock()
+
+	if len(queue) == 0 {
+		return
+	}
+	bag := (*lt.ConfManager).Get()
+	batchTS := time.Now().Unix()
+	lt.Logger.Debugf("Sending %d tailed log entries\n", len(queue))
+	for start := 0; start < len(queue); start += bag.EventAPILimit {
+		end := start + bag.EventAPILimit
+		if end > len(queue) || bag.EventAPILimit <= 0 {
+			end = len(queue)
+		}
+		batch := queue[start:end]
+		for i := range batch {
+			batch[i].BatchTimestamp = batchTS
+		}
+		lt.postRecords(&batch)
+		if end == len(queue) {
+			break
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var lockMetricFilter = sync.Mutex{}
+
+//syncs for which an admitted series keeps its place in the budget while it is not reported, e.g. during a pod restart
+const metricAdmissionGraceSyncs int = 5
+
+/*
+ Keeps the number of metric series within the limits of the controller.
+ The series are filtered by the allow and deny patterns on the metric paths and cut to the budget of their family.
+ Series reported within the grace period keep their place within the budget, so that the same series are reported every time.
+ The number of suppressed series per family is reported under Cardinality
+*/
+type MetricFilter struct {
+	Logger     *log.Logger
+	allow      []*regexp.Regexp
+	deny       []*regexp.Regexp
+	signature  string
+	admitted   map[string]map[string]time.Time //family -> path -> last time the series was reported
+	suppressed map[string]int64                //family -> series suppressed in the current sync
+	reported   map[string]int64                //family -> series reported in the current sync
+	logged     map[string][2]int64             //family -> suppressed and reported series of the last log entry
+}
+
+func NewMetricFilter(l *log.Logger) *MetricFilter {
+	return &MetricFilter{Logger: l, admitted: make(map[string]map[string]time.Time), suppressed: make(map[string]int64), reported: make(map[string]int64),
+		logged: make(map[string][2]int64)}
+}
+
+func (mf *MetricFilter) load(bag *m.AppDBag) {
+	data, _ := json.Marshal([][]string{bag.MetricAllowPatterns, bag.MetricDenyPatterns})
+	signature := string(data)
+	if signature == mf.signature {
+		return
+	}
+	mf.allow = mf.compile(bag.MetricAllowPatterns)
+	mf.deny = mf.compile(bag.MetricDenyPatterns)
+	mf.signature = signature
+}
+
+func (mf *MetricFilter) compile(patterns []string) []*regexp.Regexp {
+	list := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			mf.Logger.Errorf("Metric path pattern %s skipped. %v\n", pattern, err)
+			continue
+		}
+		list = append(list, re)
+	}
+	return list
+}
+
+//starts a sync. Resets the counters of the previous one
+func (mf *MetricFilter) Reset(bag *m.AppDBag) {
+	lockMetricFilter.Lock()
+	defer lockMetricFilter.Unlock()
+	mf.load(bag)
+	mf.suppressed = make(map[string]int64)
+	mf.reported = make(map[string]int64)
+}
+
+//true if the container, instance and port metrics of the tier are rolled up to the tier level
+func (mf *MetricFilter) IsRolledUp(bag *m.AppDBag, tierName string) bool {
+	switch bag.MetricRollup {
+	case m.METRIC_ROLLUP_TIER:
+		return true
+	case m.METRIC_ROLLUP_DASHBOARD:
+		return !utils.StringInSlice(tierName, bag.DeploysToDashboard)
+	}
+	return false
+}
+
+//counts series that were not built, e.g. rolled up to the tier level
+func (mf *MetricFilter) Suppress(family string, count int) {
+	lockMetricFilter.Lock()
+	defer lockMetricFilter.Unlock()
+	mf.suppressed[family] += int64(count)
+}
+
+func (mf *MetricFilter) isAllowed(path string) bool {
+	if len(mf.allow) > 0 {
+		allowed := false
+		for _, re := range mf.allow {
+			if re.MatchString(path) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, re := range mf.deny {
+		if re.MatchString(path) {
+			return false
+		}
+	}
+	return true
+}
+
+//the series of the family that pass the patterns and fit into the budget
+func (mf *MetricFilter) Apply(bag *m.AppDBag, family string, metrics []m.AppDMetric) []m.AppDMetric {
+	return mf.applyAt(bag, family, metrics, time.Now())
+}
+
+func (mf *MetricFilter) applyAt(bag *m.AppDBag, family string, metrics []m.AppDMetric, now time.Time) []m.AppDMetric {
+	lockMetricFilter.Lock()
+	defer lockMetricFilter.Unlock()
+
+	list := []m.AppDMetric{}
+	for _, metric := range metrics {
+		if mf.isAllowed(metric.MetricPath) {
+			list = append(list, metric)
+		}
+	}
+	mf.suppressed[family] += int64(len(metrics) - len(list))
+
+	//series not reported within the grace period lose their place
+	grace := time.Duration(metricAdmissionGraceSyncs*bag.MetricsSyncInterval) * time.Second
+	admitted, ok := mf.admitted[family]
+	if !ok {
+		admitted = make(map[string]time.Time)
+		mf.admitted[family] = admitted
+	}
+	for path, reported := range admitted {
+		if now.Sub(reported) > grace {
+			delete(admitted, path)
+		}
+	}
+
+	budget := bag.MetricBudgets[family]
+	if budget > 0 && len(list) > budget {
+		sort.Slice(list, func(i, j int) bool {
+			_, previousI := admitted[list[i].MetricPath]
+			_, previousJ := admitted[list[j].MetricPath]
+			if previousI != previousJ {
+				return previousI
+			}
+			return list[i].MetricPath < list[j].MetricPath
+		})
+		mf.suppressed[family] += int64(len(list) - budget)
+		list = list[:budget]
+	}
+
+	for _, metric := range list {
+		admitted[metric.MetricPath] = now
+	}
+	mf.reported[family] += int64(len(list))
+	return list
+}
+
+//reported and suppressed series of every family in the current sync
+func (mf *MetricFilter) GetCardinalityMetrics() []m.AppDMetric {
+	lockMetricFilter.Lock()
+	defer lockMetricFilter.Unlock()
+	families := make(map[string]bool)
+	for family := range mf.reported {
+		families[family] = true
+	}
+	for family := range mf.suppressed {
+		families[family] = true
+	}
+	list := []m.AppDMetric{}
+	for family := range families {
+		path := fmt.Sprintf("%s%s%s%s%s", m.RootPath, m.METRIC_PATH_CARDINALITY, m.METRIC_SEPARATOR, family, m.METRIC_SEPARATOR)
+		list = append(list, m.NewAppDMetric("SuppressedSeries", mf.suppressed[family], path))
+		list = append(list, m.NewAppDMetric("ReportedSeries", mf.reported[family], path))
+		//logged when the counts change, not on every sync
+		counts := [2]int64{mf.suppressed[family], mf.reported[family]}
+		if last := mf.logged[family]; counts != last && (counts[0] > 0 || last[0] > 0) {
+			if counts[0] > 0 {
+				mf.Logger.Infof("%d %s metric series suppressed by the cardinality controls, %d reported\n", counts[0], family, counts[1])
+			} else {
+				mf.Logger.Infof("%s metric series are no longer suppressed by the cardinality controls, %d reported\n", family, counts[1])
+			}
+			mf.logged[family] = counts
+		}
+	}
+	return list
+}
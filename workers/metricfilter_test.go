@@ -0,0 +1,131 @@
+package workers
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	m "github.com/appdynamics/cluster-agent/models"
+)
+
+func newTestMetricFilter() *MetricFilter {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return NewMetricFilter(logger)
+}
+
+func newTestSeries(paths ...string) []m.AppDMetric {
+	list := []m.AppDMetric{}
+	for _, path := range paths {
+		list = append(list, m.NewAppDMetric(path, 1, ""))
+	}
+	return list
+}
+
+func getSeriesPaths(metrics []m.AppDMetric) []string {
+	paths := []string{}
+	for _, metric := range metrics {
+		paths = append(paths, metric.MetricPath)
+	}
+	return paths
+}
+
+func TestMetricFilterApply(t *testing.T) {
+	series := []string{"shop|web|web-2", "shop|web|web-1", "shop|api|api-1", "kube-system|dns|dns-1"}
+	cases := []struct {
+		name       string
+		allow      []string
+		deny       []string
+		budget     int
+		expected   []string
+		suppressed int64
+	}{
+		{name: "no limits", expected: series},
+		{name: "allow", allow: []string{`^shop\|`}, expected: []string{"shop|web|web-2", "shop|web|web-1", "shop|api|api-1"}, suppressed: 1},
+		{name: "deny", deny: []string{`\|web\|`}, expected: []string{"shop|api|api-1", "kube-system|dns|dns-1"}, suppressed: 2},
+		{name: "allow and deny", allow: []string{`^shop\|`}, deny: []string{`-2$`}, expected: []string{"shop|web|web-1", "shop|api|api-1"}, suppressed: 2},
+		{name: "invalid pattern skipped", allow: []string{`(`, `dns`}, expected: []string{"kube-system|dns|dns-1"}, suppressed: 3},
+		{name: "budget in path order", budget: 2, expected: []string{"kube-system|dns|dns-1", "shop|api|api-1"}, suppressed: 2},
+		{name: "budget after patterns", deny: []string{`^kube-system`}, budget: 2, expected: []string{"shop|api|api-1", "shop|web|web-1"}, suppressed: 2},
+		{name: "budget not exceeded", allow: []string{`\|web\|`}, budget: 2, expected: []string{"shop|web|web-2", "shop|web|web-1"}, suppressed: 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bag := m.GetDefaultProperties()
+			bag.MetricAllowPatterns = c.allow
+			bag.MetricDenyPatterns = c.deny
+			bag.MetricBudgets = map[string]int{m.METRIC_FAMILY_INSTANCE: c.budget}
+			mf := newTestMetricFilter()
+			mf.Reset(bag)
+			paths := getSeriesPaths(mf.Apply(bag, m.METRIC_FAMILY_INSTANCE, newTestSeries(series...)))
+			if !reflect.DeepEqual(paths, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, paths)
+			}
+			if mf.suppressed[m.METRIC_FAMILY_INSTANCE] != c.suppressed {
+				t.Errorf("Expected %d suppressed series, got %d", c.suppressed, mf.suppressed[m.METRIC_FAMILY_INSTANCE])
+			}
+			if mf.reported[m.METRIC_FAMILY_INSTANCE] != int64(len(c.expected)) {
+				t.Errorf("Expected %d reported series, got %d", len(c.expected), mf.reported[m.METRIC_FAMILY_INSTANCE])
+			}
+		})
+	}
+}
+
+func TestMetricFilterKeepsAdmittedSeries(t *testing.T) {
+	bag := m.GetDefaultProperties()
+	bag.MetricBudgets = map[string]int{m.METRIC_FAMILY_INSTANCE: 2}
+	sync := time.Duration(bag.MetricsSyncInterval) * time.Second
+	start := time.Now()
+	mf := newTestMetricFilter()
+
+	steps := []struct {
+		name     string
+		at       time.Time
+		series   []string
+		expected []string
+	}{
+		{name: "initial", at: start, series: []string{"c", "d"}, expected: []string{"c", "d"}},
+		{name: "d missing", at: start.Add(sync), series: []string{"c"}, expected: []string{"c"}},
+		{name: "d back within the grace period", at: start.Add(2 * sync), series: []string{"a", "c", "d"}, expected: []string{"c", "d"}},
+		{name: "d missing", at: start.Add(3 * sync), series: []string{"c"}, expected: []string{"c"}},
+		{name: "d back after the grace period", at: start.Add(time.Duration(3+metricAdmissionGraceSyncs) * sync).Add(time.Second), series: []string{"a", "c", "d"}, expected: []string{"a", "c"}},
+	}
+	for _, step := range steps {
+		mf.Reset(bag)
+		paths := getSeriesPaths(mf.applyAt(bag, m.METRIC_FAMILY_INSTANCE, newTestSeries(step.series...), step.at))
+		if !reflect.DeepEqual(paths, step.expected) {
+			t.Errorf("%s: expected %v, got %v", step.name, step.expected, paths)
+		}
+	}
+	if _, ok := mf.admitted[m.METRIC_FAMILY_INSTANCE]["d"]; ok {
+		t.Errorf("Expected d to lose its place after the grace period")
+	}
+}
+
+func TestMetricFilterIsRolledUp(t *testing.T) {
+	cases := []struct {
+		rollup   string
+		tierName string
+		expected bool
+	}{
+		{rollup: m.METRIC_ROLLUP_FULL, tierName: "web", expected: false},
+		{rollup: m.METRIC_ROLLUP_FULL, tierName: "api", expected: false},
+		{rollup: m.METRIC_ROLLUP_DASHBOARD, tierName: "web", expected: false},
+		{rollup: m.METRIC_ROLLUP_DASHBOARD, tierName: "api", expected: true},
+		{rollup: m.METRIC_ROLLUP_TIER, tierName: "web", expected: true},
+		{rollup: m.METRIC_ROLLUP_TIER, tierName: "api", expected: true},
+		{rollup: "", tierName: "api", expected: false},
+	}
+	mf := newTestMetricFilter()
+	for _, c := range cases {
+		bag := m.GetDefaultProperties()
+		bag.MetricRollup = c.rollup
+		bag.DeploysToDashboard = []string{"web"}
+		if rolledUp := mf.IsRolledUp(bag, c.tierName); rolledUp != c.expected {
+			t.Errorf("MetricRollup %q, tier %s: expected rolled up %v, got %v", c.rollup, c.tierName, c.expected, rolledUp)
+		}
+	}
+}
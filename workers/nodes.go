@@ -30,15 +30,16 @@ type NodesWorker struct {
 	SummaryMap     map[string]m.ClusterNodeMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	CapacityMap    map[string]m.NodeSchema
 	Logger         *log.Logger
 }
 
 var lockCapacityMap = sync.RWMutex{}
 
-func NewNodesWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) NodesWorker {
+func NewNodesWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, l *log.Logger) NodesWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
-	pw := NodesWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterNodeMetrics), WQ: queue, AppdController: controller,
+	pw := NodesWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterNodeMetrics), WQ: queue, AppdController: controller, Sinks: sinks,
 		CapacityMap: make(map[string]m.NodeSchema), Logger: l}
 	pw.initNodeInformer(client)
 	return pw
@@ -159,6 +160,12 @@ func (pw *NodesWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticke
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d node records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
 		case <-stop:
 			ticker.Stop()
@@ -174,13 +181,13 @@ func (pw *NodesWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (pw *NodesWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
-	bth := pw.AppdController.StartBT("FlushNodeDataQueue")
+	bth := pw.Sinks.StartBT("FlushNodeDataQueue")
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d node records\n", count)
 	}
 	if count == 0 {
-		pw.AppdController.StopBT(bth)
+		pw.Sinks.StopBT(bth)
 		return
 	}
 
@@ -200,28 +207,19 @@ func (pw *NodesWorker) flushQueue() {
 		if count == 0 || len(objList) >= bag.EventAPILimit {
 			pw.Logger.Debugf("Sending %d node records to AppD events API\n", len(objList))
 			pw.postNodeRecords(&objList)
-			pw.AppdController.StopBT(bth)
+			pw.Sinks.StopBT(bth)
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *NodesWorker) postNodeRecords(objList *[]m.NodeSchema) {
 	bag := (*pw.ConfigManager).Get()
-
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewNodeSchemaDefWrapper()
-	err := rc.EnsureSchema(bag.NodeSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.NodeSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.NodeSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of node schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.NodeSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.NodeSchemaName, err)
 	}
 }
 
@@ -238,7 +236,7 @@ func (pw *NodesWorker) getNextQueueItem() (*m.NodeSchema, bool) {
 }
 
 func (pw *NodesWorker) buildAppDMetrics() {
-	bth := pw.AppdController.StartBT("PostNodeMetrics")
+	bth := pw.Sinks.StartBT("PostNodeMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterNodeMetrics)
 
 	var count int = 0
@@ -253,8 +251,10 @@ func (pw *NodesWorker) buildAppDMetrics() {
 
 	pw.Logger.Infof("Ready to push %d Node metrics\n", len(ml.Items))
 
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *NodesWorker) summarize(nodeObject *m.NodeSchema) {
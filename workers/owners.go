@@ -0,0 +1,157 @@
+package workers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/appdynamics/cluster-agent/utils"
+)
+
+const MAX_OWNER_DEPTH int = 5
+
+//time to wait for the owner caches before the pods are processed
+const OWNER_SYNC_TIMEOUT time.Duration = 2 * time.Minute
+
+var lockOwnerStores = sync.RWMutex{}
+
+//kinds of the top-level owners that represent a tier
+var workloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob", "Rollout", "ReplicationController"}
+
+/*
+ Walks the controller chain of an object up to its top-level owner,
+ e.g. Pod->ReplicaSet->Deployment, Pod->Job->CronJob or Pod->ReplicaSet->Rollout.
+ Intermediate owners are looked up in the informer caches registered by the workers.
+ The chain stops at the first owner whose kind has no registered cache.
+ The resolver is synced once the caches of the expected kinds are registered and filled
+*/
+type OwnerResolver struct {
+	Stores   map[string]cache.Store          //kind -> informer store
+	Synced   map[string]cache.InformerSynced //kind -> sync status of the informer
+	Expected []string
+}
+
+func NewOwnerResolver(expected ...string) *OwnerResolver {
+	return &OwnerResolver{Stores: make(map[string]cache.Store), Synced: make(map[string]cache.InformerSynced), Expected: expected}
+}
+
+//registers the cache of the kind. The sync status is optional
+func (r *OwnerResolver) RegisterStore(kind string, store cache.Store, synced cache.InformerSynced) {
+	lockOwnerStores.Lock()
+	defer lockOwnerStores.Unlock()
+	r.Stores[kind] = store
+	if synced != nil {
+		r.Synced[kind] = synced
+	}
+}
+
+//stops waiting for the cache of the kind, e.g. when its API is not available
+func (r *OwnerResolver) Skip(kind string) {
+	lockOwnerStores.Lock()
+	defer lockOwnerStores.Unlock()
+	r.Expected = utils.RemoveFromSlice(kind, r.Expected)
+}
+
+//true when the caches of all expected kinds are registered and synced
+func (r *OwnerResolver) HasSynced() bool {
+	lockOwnerStores.RLock()
+	defer lockOwnerStores.RUnlock()
+	for _, kind := range r.Expected {
+		if _, ok := r.Stores[kind]; !ok {
+			return false
+		}
+	}
+	for _, synced := range r.Synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+//waits for the owner caches. Returns false if they did not sync within the timeout
+func (r *OwnerResolver) WaitForSync(stopCh <-chan struct{}, timeout time.Duration) bool {
+	waitCh := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-stopCh:
+		case <-timer.C:
+		case <-done:
+		}
+		close(waitCh)
+	}()
+	return cache.WaitForCacheSync(waitCh, r.HasSynced)
+}
+
+func (r *OwnerResolver) getStore(kind string) (cache.Store, bool) {
+	lockOwnerStores.RLock()
+	defer lockOwnerStores.RUnlock()
+	store, ok := r.Stores[kind]
+	return store, ok
+}
+
+func getControllerRef(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
+
+//returns the kind and name of the top-level owner. Empty if the object has no owners
+func (r *OwnerResolver) Resolve(namespace string, refs []metav1.OwnerReference) (string, string) {
+	ref := getControllerRef(refs)
+	if ref == nil {
+		return "", ""
+	}
+	kind, name := ref.Kind, ref.Name
+	for depth := 0; depth < MAX_OWNER_DEPTH; depth++ {
+		next := r.getOwnerRef(kind, namespace, name)
+		if next == nil {
+			break
+		}
+		kind, name = next.Kind, next.Name
+	}
+	return kind, name
+}
+
+//returns the top-level owner of the object. The object itself if it has no owners
+func (r *OwnerResolver) ResolveObject(kind, namespace, name string) (string, string) {
+	ref := r.getOwnerRef(kind, namespace, name)
+	if ref == nil {
+		return kind, name
+	}
+	ownerKind, ownerName := r.Resolve(namespace, []metav1.OwnerReference{*ref})
+	return ownerKind, ownerName
+}
+
+func (r *OwnerResolver) getOwnerRef(kind, namespace, name string) *metav1.OwnerReference {
+	store, ok := r.getStore(kind)
+	if !ok {
+		return nil
+	}
+	obj, exists, err := store.GetByKey(utils.GetKey(namespace, name))
+	if err != nil || !exists {
+		return nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	return getControllerRef(accessor.GetOwnerReferences())
+}
+
+func IsWorkloadKind(kind string) bool {
+	return utils.StringInSlice(kind, workloadKinds)
+}
@@ -0,0 +1,106 @@
+package workers
+
+import (
+	"sync"
+	"time"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	"k8s.io/api/core/v1"
+)
+
+var lockLifecycles = sync.RWMutex{}
+
+func (pw PodWorker) getLifecycle(key string) *m.PodLifecycle {
+	pl, ok := pw.Lifecycles[key]
+	if !ok {
+		pl = &m.PodLifecycle{}
+		pw.Lifecycles[key] = pl
+	}
+	return pl
+}
+
+//records the startup stages observed in the pod conditions and container statuses
+func (pw PodWorker) updateLifecycle(p *v1.Pod, podObject *m.PodSchema) {
+	lockLifecycles.Lock()
+	defer lockLifecycles.Unlock()
+	pl := pw.getLifecycle(utils.GetPodKey(p))
+
+	if pl.Created.IsZero() {
+		pl.Created = p.CreationTimestamp.Time
+	}
+	for _, cn := range p.Status.Conditions {
+		if cn.Status != v1.ConditionTrue {
+			continue
+		}
+		switch cn.Type {
+		case v1.PodScheduled:
+			if pl.Scheduled.IsZero() {
+				pl.Scheduled = cn.LastTransitionTime.Time
+			}
+		case v1.PodInitialized:
+			if pl.Initialized.IsZero() {
+				pl.Initialized = cn.LastTransitionTime.Time
+			}
+		case v1.ContainersReady:
+			if pl.ContainersReady.IsZero() {
+				pl.ContainersReady = cn.LastTransitionTime.Time
+			}
+		case v1.PodReady:
+			if pl.Ready.IsZero() {
+				pl.Ready = cn.LastTransitionTime.Time
+			}
+		}
+	}
+
+	//the start of the first instance of the containers is only known before any restarts
+	if pl.Started.IsZero() && len(p.Status.ContainerStatuses) > 0 {
+		var started time.Time
+		for _, st := range p.Status.ContainerStatuses {
+			if st.State.Running == nil || st.RestartCount > 0 {
+				started = time.Time{}
+				break
+			}
+			if st.State.Running.StartedAt.Time.After(started) {
+				started = st.State.Running.StartedAt.Time
+			}
+		}
+		pl.Started = started
+	}
+
+	podObject.SetLifecycle(pl)
+}
+
+/*
+ Pulling events mark the start and Pulled events the end of the image pull stage.
+ The events may arrive after the pod status reported the containers started, so they are compared by time.
+ Pulls of restarted containers are not part of the startup
+*/
+func (pw *PodWorker) OnImagePullEvent(namespace, podName, reason string, eventTime time.Time) {
+	key := utils.GetKey(namespace, podName)
+	if _, exists, err := pw.informer.GetStore().GetByKey(key); err != nil || !exists {
+		return
+	}
+	lockLifecycles.Lock()
+	defer lockLifecycles.Unlock()
+	pl := pw.getLifecycle(key)
+	if !pl.Started.IsZero() && eventTime.After(pl.Started) {
+		return
+	}
+	switch reason {
+	case "Pulling":
+		if pl.Pulling.IsZero() || eventTime.Before(pl.Pulling) {
+			pl.Pulling = eventTime
+		}
+	case "Pulled":
+		if eventTime.After(pl.Pulled) {
+			pl.Pulled = eventTime
+		}
+	}
+}
+
+func (pw *PodWorker) clearLifecycle(podObject *m.PodSchema) {
+	lockLifecycles.Lock()
+	defer lockLifecycles.Unlock()
+	delete(pw.Lifecycles, utils.GetKey(podObject.Namespace, podObject.Name))
+}
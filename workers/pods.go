@@ -49,6 +49,7 @@ type PodWorker struct {
 	InstanceSummaryMap      map[string]m.ClusterInstanceMetrics
 	WQ                      workqueue.RateLimitingInterface
 	AppdController          *app.ControllerClient
+	Sinks                   *app.SinkSet
 	K8sConfig               *rest.Config
 	PendingCache            []string
 	FailedCache             map[string]m.AttachStatus
@@ -68,12 +69,23 @@ type PodWorker struct {
 	CMWatcher               *w.ConfigWatcher
 	NSWatcher               *w.NSWatcher
 	SecretWatcher           *w.SecretWathcer
+	PDBWatcher              *w.PDBWatcher
+	Recorder                *w.ChangeRecorder
+	OwnerResolver           *OwnerResolver
 	DashboardCache          map[string]m.PodSchema
 	DelayDashboard          bool
 	PendingAssociationQueue map[string]m.AgentRetryRequest
 	EventMap                map[string][]m.EventSchema
 	NodesMonitor            *NodesWorker
 	ContainerCache          map[string]m.ContainerSchema
+	ProbeFailures           map[string]time.Time        //container key -> time of the last liveness probe failure
+	RestartCauses           map[string]map[string]int64 //tier key -> restart cause -> restarts since the last metrics update
+	HealthyRevisions        map[string]healthyRevision  //tier key -> last revision seen healthy
+	Incidents               map[string]string           //pod key -> id of the last crash incident
+	LogTailer               *LogTailer
+	LogParsers              *LogParserSet
+	MetricFilter            *MetricFilter
+	Lifecycles              map[string]*m.PodLifecycle //pod key -> startup stages
 }
 
 var lockOwnerMap = sync.RWMutex{}
@@ -87,27 +99,37 @@ var lockServices = sync.RWMutex{}
 var lockEPs = sync.RWMutex{}
 var lockNSMap = sync.RWMutex{}
 var lockContainerCache = sync.RWMutex{}
+var lockProbeFailures = sync.RWMutex{}
+var lockRestartCauses = sync.RWMutex{}
 
-func NewPodWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, config *rest.Config, l *log.Logger, nw *NodesWorker) PodWorker {
+func NewPodWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, config *rest.Config, l *log.Logger, nw *NodesWorker, recorder *w.ChangeRecorder, pdbWatcher *w.PDBWatcher, resolver *OwnerResolver) PodWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	pw := PodWorker{Client: client, ConfManager: cm, Logger: l, SummaryMap: make(map[string]m.ClusterPodMetrics), AppSummaryMap: make(map[string]m.ClusterAppMetrics),
 		ContainerSummaryMap: make(map[string]m.ClusterContainerMetrics), InstanceSummaryMap: make(map[string]m.ClusterInstanceMetrics),
-		WQ: queue, AppdController: controller, K8sConfig: config, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus),
+		WQ: queue, AppdController: controller, Sinks: sinks, K8sConfig: config, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus),
 		ServiceCache: make(map[string]m.ServiceSchema), EndpointCache: make(map[string]v1.Endpoints),
 		OwnerMap: make(map[string]string), NamespaceMap: make(map[string]string), EventMap: make(map[string][]m.EventSchema),
 		RQCache: make(map[string]v1.ResourceQuota), PVCCache: make(map[string]v1.PersistentVolumeClaim), PendingAssociationQueue: make(map[string]m.AgentRetryRequest),
 		CMCache: make(map[string]v1.ConfigMap), SecretCache: make(map[string]v1.Secret), NSCache: make(map[string]m.NsSchema), DashboardCache: make(map[string]m.PodSchema),
-		ContainerCache: make(map[string]m.ContainerSchema)}
+		ContainerCache: make(map[string]m.ContainerSchema), ProbeFailures: make(map[string]time.Time), RestartCauses: make(map[string]map[string]int64),
+		HealthyRevisions: make(map[string]healthyRevision), Incidents: make(map[string]string), Lifecycles: make(map[string]*m.PodLifecycle)}
 	pw.initPodInformer(client)
+	pw.OwnerResolver = resolver
+	pw.OwnerResolver.RegisterStore("Pod", pw.informer.GetStore(), nil)
 	pw.ServiceWatcher = w.NewServiceWatcher(client, cm, &pw.ServiceCache, pw, l)
 	pw.EndpointWatcher = w.NewEndpointWatcher(client, cm, &pw.EndpointCache, l)
 	pw.PVCWatcher = w.NewPVCWatcher(client, cm, &pw.PVCCache, l)
-	pw.RQWatcher = w.NewRQWatcher(client, cm, &pw.RQCache, pw.Logger)
-	pw.CMWatcher = w.NewConfigWatcher(client, cm, &pw.CMCache, pw, l)
-	pw.SecretWatcher = w.NewSecretWathcer(client, cm, &pw.SecretCache, pw, l)
+	pw.RQWatcher = w.NewRQWatcher(client, cm, sinks, &pw.RQCache, pw.Logger)
+	pw.CMWatcher = w.NewConfigWatcher(client, cm, &pw.CMCache, pw, recorder, l)
+	pw.SecretWatcher = w.NewSecretWathcer(client, cm, &pw.SecretCache, pw, recorder, l)
 	pw.NSWatcher = w.NewNSWatcher(client, cm, &pw.NSCache, l)
+	pw.PDBWatcher = pdbWatcher
+	pw.Recorder = recorder
 	pw.DelayDashboard = true
 	pw.NodesMonitor = nw
+	pw.LogParsers = NewLogParserSet(l)
+	pw.MetricFilter = NewMetricFilter(l)
+	pw.LogTailer = NewLogTailer(client, cm, pw.LogParsers, pw.postLogRecords, l)
 
 	return pw
 }
@@ -155,12 +177,13 @@ func (pw *PodWorker) onNewPod(obj interface{}) {
 	pw.tryDashboardCache(&podRecord)
 	pw.WQ.Add(&podRecord)
 	pw.checkForInstrumentation(podObj, &podRecord)
+	pw.LogTailer.Sync(podObj, &podRecord)
 }
 
 func (pw *PodWorker) instrument(statusChannel chan m.AttachStatus, podObj *v1.Pod, podSchema *m.PodSchema) {
 	bag := (*pw.ConfManager).Get()
 	pw.Logger.Infof("Attempting instrumentation %s...\n", podObj.Name)
-	injector := instr.NewAgentInjector(pw.Client, pw.K8sConfig, bag, pw.AppdController, pw.Logger)
+	injector := instr.NewAgentInjector(pw.Client, pw.K8sConfig, bag, pw.AppdController, pw.Sinks, pw.Logger)
 	injector.EnsureInstrumentation(statusChannel, podObj, podSchema)
 }
 
@@ -232,13 +255,13 @@ func (pw *PodWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 func (pw *PodWorker) flushQueue() {
 	pw.updateServiceCache()
 	bag := (*pw.ConfManager).Get()
-	bth := pw.AppdController.StartBT("FlushPodDataQueue")
+	bth := pw.Sinks.StartBT("FlushPodDataQueue")
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d Pod records\n", count)
 	}
 	if count == 0 {
-		pw.AppdController.StopBT(bth)
+		pw.Sinks.StopBT(bth)
 		return
 	}
 
@@ -263,11 +286,11 @@ func (pw *PodWorker) flushQueue() {
 			pw.Logger.Debugf("Sending %d pod records to AppD events API\n", len(objList))
 			pw.postPodRecords(&objList)
 			pw.postContainerRecords(containerList)
-			pw.AppdController.StopBT(bth)
+			pw.Sinks.StopBT(bth)
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *PodWorker) postContainerRecords(objList []m.ContainerSchema) {
@@ -290,77 +313,38 @@ func (pw *PodWorker) postContainerRecords(objList []m.ContainerSchema) {
 
 func (pw *PodWorker) postContainerBatchRecords(objList *[]m.ContainerSchema) {
 	bag := (*pw.ConfManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewContainerSchemaDefWrapper()
-	err := rc.EnsureSchema(bag.ContainerSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.ContainerSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.ContainerSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of container schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.ContainerSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.ContainerSchemaName, err)
 	}
-
 }
 
 func (pw *PodWorker) postPodRecords(objList *[]m.PodSchema) {
 	bag := (*pw.ConfManager).Get()
-	rc := app.NewRestClient(bag, pw.Logger)
 	schemaDefObj := m.NewPodSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.PodSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.PodSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.PodSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of pod schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.PodSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.PodSchemaName, err)
 	}
 }
 
 func (pw *PodWorker) postEPBatchRecords(objList *[]m.EpSchema) {
 	bag := (*pw.ConfManager).Get()
-
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewEpSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.EpSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.EpSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.EpSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of endpoint schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.EpSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.EpSchemaName, err)
 	}
-
 }
 
 func (pw *PodWorker) postNSBatchRecords(objList *[]m.NsSchema) {
 	bag := (*pw.ConfManager).Get()
-
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewNsSchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.NsSchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.NsSchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.NsSchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of namespace schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.NsSchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.NsSchemaName, err)
 	}
-
 }
 
 func (pw *PodWorker) getNextQueueItem() (*m.PodSchema, bool) {
@@ -387,6 +371,10 @@ func (pw *PodWorker) onDeletePod(obj interface{}) {
 	podRecord, _ := pw.processObject(podObj, nil)
 	pw.WQ.Add(&podRecord)
 	pw.clearContainerCache(&podRecord)
+	pw.clearIncidents(&podRecord)
+	pw.clearLifecycle(&podRecord)
+	pw.Recorder.UntrackPod(podObj)
+	pw.LogTailer.StopPod(podObj.Namespace, podObj.Name)
 	if podRecord.NodeID > 0 {
 		//mark node as historial
 		pw.AppdController.MarkNodeHistorical(podRecord.NodeID)
@@ -402,6 +390,11 @@ func (pw *PodWorker) clearContainerCache(podObject *m.PodSchema) {
 			delete(pw.ContainerCache, key)
 		}
 	}
+	lockProbeFailures.Lock()
+	defer lockProbeFailures.Unlock()
+	for _, c := range podObject.Containers {
+		delete(pw.ProbeFailures, utils.GetContainerKey(podObject, &c))
+	}
 }
 
 func (pw *PodWorker) onUpdatePod(objOld interface{}, objNew interface{}) {
@@ -421,6 +414,7 @@ func (pw *PodWorker) onUpdatePod(objOld interface{}, objNew interface{}) {
 	pw.WQ.Add(&podRecord)
 
 	pw.checkForInstrumentation(podObj, &podRecord)
+	pw.LogTailer.Sync(podObj, &podRecord)
 }
 
 func (pw *PodWorker) checkForInstrumentation(podObj *v1.Pod, podSchema *m.PodSchema) {
@@ -481,6 +475,13 @@ func (pw *PodWorker) checkForInstrumentation(podObj *v1.Pod, podSchema *m.PodSch
 func (pw PodWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer pw.WQ.ShutDown()
+
+	//pods are attributed to their top-level owners, which requires the replica set and job caches
+	ownersSynced := pw.OwnerResolver.WaitForSync(stopCh, OWNER_SYNC_TIMEOUT)
+	if !ownersSynced {
+		pw.Logger.Warn("Owner caches are not synchronized. Owners of the pods will be resolved again once they are")
+	}
+
 	wg.Add(1)
 	go pw.informer.Run(stopCh)
 
@@ -489,6 +490,10 @@ func (pw PodWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	}
 	pw.Logger.Infof("Pod Cache syncronized. Starting the processing...")
 
+	if !ownersSynced {
+		go pw.resolveOwnersOnSync(stopCh)
+	}
+
 	wg.Add(1)
 	go pw.startMetricsWorker(stopCh)
 
@@ -509,6 +514,8 @@ func (pw PodWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 
 	go pw.startRetryQueueWorker(stopCh)
 
+	go pw.LogTailer.Start(stopCh)
+
 	//dashbard timer
 	bag := (*pw.ConfManager).Get()
 	dashTimer := time.NewTimer(time.Minute * time.Duration(bag.DashboardDelayMin))
@@ -546,6 +553,12 @@ func (pw *PodWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker)
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d pod records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
 		case <-stop:
 			ticker.Stop()
@@ -553,6 +566,22 @@ func (pw *PodWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker)
 		}
 	}
 }
+//requeues the pods once the owner caches are synchronized, so that the records carry the top-level owners
+func (pw PodWorker) resolveOwnersOnSync(stopCh <-chan struct{}) {
+	if !cache.WaitForCacheSync(stopCh, pw.OwnerResolver.HasSynced) {
+		return
+	}
+	pw.Logger.Info("Owner caches synchronized. Resolving owners of the pods...")
+	for _, obj := range pw.informer.GetStore().List() {
+		podObject := obj.(*v1.Pod)
+		if !pw.qualifies(podObject) {
+			continue
+		}
+		podSchema, _ := pw.processObject(podObject, nil)
+		pw.WQ.Add(&podSchema)
+	}
+}
+
 func (pw PodWorker) CacheUpdated(namespace string) {
 	pw.Logger.Debugf("CacheUpdated called for namespace %s\n", namespace)
 	//queue up pod records of the namespace to update
@@ -567,7 +596,7 @@ func (pw PodWorker) CacheUpdated(namespace string) {
 
 func (pw *PodWorker) buildAppDMetrics() {
 	bag := (*pw.ConfManager).Get()
-	bth := pw.AppdController.StartBT("PostPodMetrics")
+	bth := pw.Sinks.StartBT("PostPodMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterPodMetrics)
 	pw.AppSummaryMap = make(map[string]m.ClusterAppMetrics)
 	pw.ContainerSummaryMap = make(map[string]m.ClusterContainerMetrics)
@@ -592,12 +621,16 @@ func (pw *PodWorker) buildAppDMetrics() {
 		clusterBag = m.NewDashboardBagCluster()
 	}
 	var count int = 0
+	owners := make(map[string]bool)
+	pods := make(map[string]bool)
 	for _, obj := range pw.informer.GetStore().List() {
 		podObject := obj.(*v1.Pod)
 		podSchema, changed := pw.processObject(podObject, nil)
 		if changed {
 			pw.WQ.Add(&podSchema)
 		}
+		owners[utils.GetKey(podSchema.Namespace, podSchema.Owner)] = true
+		pods[utils.GetKey(podSchema.Namespace, podSchema.Name)] = true
 		pw.summarize(&podSchema)
 		//endpoints
 		for _, ep := range epList {
@@ -607,6 +640,7 @@ func (pw *PodWorker) buildAppDMetrics() {
 		heatNode := m.NewHeatNode(podSchema)
 		if clusterBag != nil {
 			heatNode.Events = pw.GetPodEvents(&podSchema)
+			heatNode.IncidentID = pw.GetLastIncident(podSchema.Namespace, podSchema.Name)
 			heatNode.Containers = pw.GetPodUtilization(&podSchema)
 			clusterBag.AddNode(&heatNode)
 		}
@@ -632,14 +666,18 @@ func (pw *PodWorker) buildAppDMetrics() {
 	}
 
 	pw.processNamespaces()
+	pw.applyRestartCauses()
+	pw.pruneCrashForensics(owners, pods)
 
 	ml := pw.builAppDMetricsList()
 
 	pw.postEPBatchRecords(&epList)
 
 	pw.Logger.Infof("Ready to push %d Pod metrics\n", len(ml.Items))
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 
 	//delay dashboard generation
 	if !pw.DelayDashboard && clusterBag != nil {
@@ -822,6 +860,10 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		summaryApp.NoConnectivity++
 	}
 
+	if podObject.StaleConfig {
+		summaryApp.StaleConfigPods++
+	}
+
 	summary.LimitCpu += int64(podObject.CpuLimit)
 	summaryNS.LimitCpu += int64(podObject.CpuLimit)
 	summaryNode.LimitCpu += int64(podObject.CpuLimit)
@@ -852,6 +894,8 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		summaryNS.PodPending++
 		summaryNode.PodPending++
 		summaryApp.PodPending++
+		summary.AddSchedulingFailure(podObject)
+		summaryNS.AddSchedulingFailure(podObject)
 		break
 	case "Failed":
 		summary.PodFailed++
@@ -976,6 +1020,8 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		}
 	}
 
+	summaryApp.AddLatencies(podObject)
+
 	pw.AppSummaryMap[podObject.Owner] = summaryApp
 	pw.SummaryMap[m.ALL] = summary
 	pw.SummaryMap[podObject.Namespace] = summaryNS
@@ -1005,8 +1051,8 @@ func (pw *PodWorker) getPodOwner(p *v1.Pod) string {
 		}
 	}
 
-	if owner == "" && len(p.OwnerReferences) > 0 {
-		owner = p.OwnerReferences[0].Name
+	if owner == "" {
+		_, owner = pw.OwnerResolver.Resolve(p.Namespace, p.OwnerReferences)
 	}
 
 	if owner == "" {
@@ -1049,9 +1095,11 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 		}
 	}
 
-	if podObject.Owner == "" && len(p.OwnerReferences) > 0 {
-		podObject.Owner = p.OwnerReferences[0].Name
+	podObject.OwnerKind, podObject.OwnerName = pw.OwnerResolver.Resolve(p.Namespace, p.OwnerReferences)
+	if podObject.Owner == "" {
+		podObject.Owner = podObject.OwnerName
 	}
+	pw.Recorder.TrackPod(p, podObject.TierName, podObject.OwnerKind, podObject.OwnerName)
 
 	lockOwnerMap.Lock()
 	defer lockOwnerMap.Unlock()
@@ -1178,7 +1226,10 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 			podObject.LimitsDefined = limitsDefined
 			podObject.MissingDependencies = containerObj.HasMissingDependencies()
 			podObject.NoConnectivity = containerObj.NoConnectivity()
-			if podObject.MissingDependencies || podObject.NoConnectivity {
+			if containerObj.HasStaleConfig() {
+				podObject.StaleConfig = true
+			}
+			if podObject.MissingDependencies || podObject.NoConnectivity || podObject.StaleConfig {
 				changed = true
 			}
 		}
@@ -1193,6 +1244,11 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 		podObject.Priority = *p.Spec.Priority
 	}
 	podObject.ServiceAccountName = p.Spec.ServiceAccountName
+	if pw.PDBWatcher != nil {
+		if pdb, ok := pw.PDBWatcher.GetMatchingPDB(p.Namespace, p.Labels); ok {
+			podObject.PdbName = pdb.Name
+		}
+	}
 	if p.Spec.TerminationGracePeriodSeconds != nil {
 		podObject.TerminationGracePeriodSeconds = *p.Spec.TerminationGracePeriodSeconds
 	}
@@ -1279,6 +1335,7 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 	}
 
 	podObject.Phase = string(p.Status.Phase)
+	pw.updateLifecycle(p, &podObject)
 
 	if !podObject.IsEvicted {
 		var lastCondition *v1.PodCondition = nil
@@ -1293,6 +1350,10 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 				switch cn.Type {
 				case v1.PodScheduled:
 					//					fmt.Printf("Pod %s Scheduled %s. Time: %s Probe: %s\n", podObject.Name, string(cn.Status), cn.LastTransitionTime.Time, cn.LastProbeTime.Time)
+					if cn.Status == v1.ConditionFalse && cn.Reason == v1.PodReasonUnschedulable {
+						schedulingFailure := utils.ParseSchedulingFailure(cn.Message)
+						podObject.SetSchedulingFailure(&schedulingFailure)
+					}
 					break
 
 				case v1.PodReady:
@@ -1329,18 +1390,19 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 
 			for _, st := range p.Status.ContainerStatuses {
 				containerObj := findContainer(&podObject, st.Name)
-				mod := pw.updateConatainerStatus(&podObject, containerObj, st)
+				mod := pw.updateConatainerStatus(p, &podObject, containerObj, st)
 				if mod {
 					changed = true
 				}
 			}
+			pw.trackHealthyRevision(p, &podObject)
 		}
 
 		if p.Status.InitContainerStatuses != nil {
 
 			for _, st := range p.Status.InitContainerStatuses {
 				containerObj := findInitContainer(&podObject, st.Name)
-				pw.updateConatainerStatus(&podObject, containerObj, st)
+				pw.updateConatainerStatus(p, &podObject, containerObj, st)
 			}
 		}
 
@@ -1455,7 +1517,7 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 	return podObject, changed
 }
 
-func (pw PodWorker) updateConatainerStatus(podObject *m.PodSchema, containerObj *m.ContainerSchema, st v1.ContainerStatus) bool {
+func (pw PodWorker) updateConatainerStatus(p *v1.Pod, podObject *m.PodSchema, containerObj *m.ContainerSchema, st v1.ContainerStatus) bool {
 	changed := false
 
 	bag := (*pw.ConfManager).Get()
@@ -1490,6 +1552,37 @@ func (pw PodWorker) updateConatainerStatus(podObject *m.PodSchema, containerObj
 		}
 	}
 
+	key := utils.GetContainerKey(podObject, containerObj)
+	lastSnapshot, ok := pw.ContainerCache[key]
+
+	//classify the last termination of the container
+	term := st.LastTerminationState.Terminated
+	if term == nil {
+		term = st.State.Terminated
+	}
+	if term != nil {
+		probeFailure := pw.GetLastProbeFailure(podObject.Namespace, podObject.Name, containerObj.Name)
+		containerObj.RestartCause = utils.ClassifyRestart(term, p, probeFailure, lastSnapshot.MemUse, containerObj.MemLimit)
+		//normal exits, e.g. of the containers of jobs or of deleted pods, are not restarts
+		if ok && containerObj.RestartCause != m.RESTART_CAUSE_COMPLETED {
+			restarted := st.RestartCount > lastSnapshot.Restarts
+			stopped := false
+			if restarted {
+				pw.addRestartCause(podObject, containerObj.RestartCause, int64(st.RestartCount-lastSnapshot.Restarts))
+			} else if st.State.Terminated != nil && lastSnapshot.RestartCause != containerObj.RestartCause {
+				//terminated for good, e.g. evicted
+				pw.addRestartCause(podObject, containerObj.RestartCause, 1)
+				stopped = true
+			}
+			//the containers of the pods being deleted are stopped on purpose
+			if bag.CrashForensics && (restarted || stopped && p.DeletionTimestamp == nil) {
+				//logs of the failed instance. A container terminated for good has no previous instance
+				previous := st.State.Terminated == nil
+				pw.collectCrashForensics(p, podObject, containerObj, &lastSnapshot, term, previous)
+			}
+		}
+	}
+
 	podObject.Containers[containerObj.Name] = *containerObj
 
 	if bag.LogLines > 0 && (containerObj.Restarts > 2 || podObject.Phase == "Failed") {
@@ -1505,8 +1598,6 @@ func (pw PodWorker) updateConatainerStatus(podObject *m.PodSchema, containerObj
 		pw.saveLogs(podObject.ClusterName, podObject.Namespace, podObject.Owner, podObject.Name, &po)
 	}
 
-	key := utils.GetContainerKey(podObject, containerObj)
-	lastSnapshot, ok := pw.ContainerCache[key]
 	if ok {
 		if lastSnapshot.Restarts != st.RestartCount ||
 			containerObj.LastTerminationTime == nil && st.LastTerminationState.Terminated != nil {
@@ -1545,6 +1636,8 @@ func (pw PodWorker) processContainer(podObj *v1.Pod, podSchema *m.PodSchema, c v
 	containerObj.Namespace = podSchema.Namespace
 	containerObj.NodeName = podSchema.NodeName
 	containerObj.PodName = podSchema.Name
+	containerObj.OwnerKind = podSchema.OwnerKind
+	containerObj.OwnerName = podSchema.OwnerName
 	containerObj.Init = init
 	containerObj.PodInitTime = podSchema.StartTime
 
@@ -1709,6 +1802,18 @@ func (pw PodWorker) processContainer(podObj *v1.Pod, podSchema *m.PodSchema, c v
 		}
 		containerObj.Mounts = sb.String()
 	}
+
+	//check configs changed after the pod started
+	for _, name := range utils.GetContainerConfigRefs(&podObj.Spec, &c, true) {
+		if version, ok := pw.CMWatcher.GetVersion(podObj.Namespace, name); ok && version.IsStale(podSchema.StartTime) {
+			containerObj.StaleConfigs += fmt.Sprintf("%s;", name)
+		}
+	}
+	for _, name := range utils.GetContainerConfigRefs(&podObj.Spec, &c, false) {
+		if version, ok := pw.SecretWatcher.GetVersion(podObj.Namespace, name); ok && version.IsStale(podSchema.StartTime) {
+			containerObj.StaleSecrets += fmt.Sprintf("%s;", name)
+		}
+	}
 	containerObj.LimitsDefined = limitsDefined
 
 	return containerObj, limitsDefined
@@ -1746,7 +1851,7 @@ func compareInt64(val1, val2 int64, differ bool) bool {
 	return val1 != val2
 }
 
-func (pw PodWorker) saveLogs(clusterName string, namespace string, podOwner string, podName string, logOptions *v1.PodLogOptions) error {
+func (pw PodWorker) readLogs(namespace string, podName string, logOptions *v1.PodLogOptions) ([]string, error) {
 	req := pw.Client.CoreV1().RESTClient().Get().
 		Namespace(namespace).
 		Name(podName).
@@ -1772,14 +1877,23 @@ func (pw PodWorker) saveLogs(clusterName string, namespace string, podOwner stri
 	readCloser, err := req.Stream()
 	if err != nil {
 		pw.Logger.Errorf("Issues when reading logs for pod %s %s:. %v\n", namespace, podName, err)
-		return err
+		return nil, err
 	}
 
 	defer readCloser.Close()
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(readCloser)
-	s := buf.String()
-	logs := strings.Split(s, "\n")
+	return strings.Split(buf.String(), "\n"), nil
+}
+
+func (pw PodWorker) saveLogs(clusterName string, namespace string, podOwner string, podName string, logOptions *v1.PodLogOptions) error {
+	logs, err := pw.readLogs(namespace, podName, logOptions)
+	if err != nil {
+		return err
+	}
+	bag := (*pw.ConfManager).Get()
+	podObj, _, _ := pw.GetCachedPod(namespace, podName)
+	parser := pw.LogParsers.Select(podObj, podOwner, logOptions.Container, bag)
 	batchTS := time.Now().Unix()
 	objList := []m.LogSchema{}
 	for _, l := range logs {
@@ -1804,7 +1918,9 @@ func (pw PodWorker) saveLogs(clusterName string, namespace string, podOwner stri
 				}
 			}
 
-			logSchema.Message = utils.TruncateString(m, app.MAX_FIELD_LENGTH)
+			logSchema.Message = m
+			parseLogRecord(&logSchema, parser, bag)
+			logSchema.Message = utils.TruncateString(logSchema.Message, app.MAX_FIELD_LENGTH)
 			logSchema.BatchTimestamp = batchTS
 			objList = append(objList, logSchema)
 		}
@@ -1819,26 +1935,14 @@ func (pw PodWorker) saveLogs(clusterName string, namespace string, podOwner stri
 func (pw *PodWorker) postLogRecords(objList *[]m.LogSchema) {
 	bag := (*pw.ConfManager).Get()
 
-	rc := app.NewRestClient(bag, pw.Logger)
-	data, err := json.Marshal(objList)
-	schemaDefObj := m.NewLogSchemaDefWrapper()
-	schemaDef, e := json.Marshal(schemaDefObj)
-	if err == nil && e == nil {
-		if rc.SchemaExists(bag.LogSchemaName) == false {
-			pw.Logger.Debugf("Creating Log schema. %s\n", bag.LogSchemaName)
-			schemaObj, err := rc.CreateSchema(bag.LogSchemaName, schemaDef)
-			if err != nil {
-				return
-			} else if schemaObj != nil {
-				pw.Logger.Debugf("Schema %s created\n", bag.LogSchemaName)
-			} else {
-				pw.Logger.Debugf("Schema %s exists\n", bag.LogSchemaName)
-			}
-		}
-
-		rc.PostAppDEvents(bag.LogSchemaName, data)
-	} else {
-		pw.Logger.Errorf("Problems when serializing array of logs . %v", err)
+	//the schema evolves with the configured custom fields
+	if pw.LogParsers.CustomFieldsChanged(bag) {
+		app.InvalidateSchema(bag.LogSchemaName)
+	}
+	schemaDefObj := m.NewLogSchemaDefWrapper(bag.LogCustomFields)
+	err := pw.Sinks.PostEvents(bag.LogSchemaName, &schemaDefObj, objList)
+	if err != nil {
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.LogSchemaName, err)
 	}
 }
 
@@ -1904,76 +2008,109 @@ func metricsWorkerSingle(finished chan *m.PodMetricsObj, client *kubernetes.Clie
 }
 
 func (pw PodWorker) builAppDMetricsList() m.AppDMetricList {
+	bag := (*pw.ConfManager).Get()
+	pw.MetricFilter.Reset(bag)
 	ml := m.NewAppDMetricList()
-	var list []m.AppDMetric
+	//family -> series
+	families := make(map[string][]m.AppDMetric)
 	for _, metricPod := range pw.SummaryMap {
 		objMap := metricPod.Unwrap()
-		pw.addMetricToList(*objMap, metricPod, &list)
+		pw.addMetricToList(*objMap, metricPod, m.METRIC_FAMILY_CLUSTER, families)
 		//quotas
 		quotaSpecs := metricPod.GetQuotaSpecMetrics()
 		qsMap := quotaSpecs.Unwrap()
-		pw.addMetricToList(*qsMap, quotaSpecs, &list)
+		pw.addMetricToList(*qsMap, quotaSpecs, m.METRIC_FAMILY_CLUSTER, families)
 
 		quotaUsed := metricPod.GetQuotaUsedMetrics()
 		quMap := quotaUsed.Unwrap()
-		pw.addMetricToList(*quMap, quotaUsed, &list)
+		pw.addMetricToList(*quMap, quotaUsed, m.METRIC_FAMILY_CLUSTER, families)
 	}
 	for _, metricApp := range pw.AppSummaryMap {
 		objMap := metricApp.Unwrap()
-		pw.addMetricToList(*objMap, metricApp, &list)
+		pw.addMetricToList(*objMap, metricApp, m.METRIC_FAMILY_TIER, families)
 		//quotas
 		quotaSpecs := metricApp.GetQuotaSpecMetrics()
 		qsMap := quotaSpecs.Unwrap()
-		pw.addMetricToList(*qsMap, quotaSpecs, &list)
+		pw.addMetricToList(*qsMap, quotaSpecs, m.METRIC_FAMILY_TIER, families)
 
 		quotaUsed := metricApp.GetQuotaUsedMetrics()
 		quMap := quotaUsed.Unwrap()
-		pw.addMetricToList(*quMap, quotaUsed, &list)
+		pw.addMetricToList(*quMap, quotaUsed, m.METRIC_FAMILY_TIER, families)
 
 		//services
 		for _, svcMetrics := range metricApp.Services {
 			objSvcMap := svcMetrics.Unwrap()
-			pw.addMetricToList(*objSvcMap, svcMetrics, &list)
+			pw.addMetricToList(*objSvcMap, svcMetrics, m.METRIC_FAMILY_SERVICE, families)
 			//endpoints
 			for _, epMetrics := range svcMetrics.Endpoints {
 				objEPMap := epMetrics.Unwrap()
-				pw.addMetricToList(*objEPMap, epMetrics, &list)
+				pw.addMetricToList(*objEPMap, epMetrics, m.METRIC_FAMILY_ENDPOINT, families)
 			}
 		}
 	}
 
+	//the container, instance and port metrics of the rolled up tiers are not reported
 	for _, metricContainer := range pw.ContainerSummaryMap {
 		objMap := structs.Map(metricContainer)
-		pw.addMetricToList(objMap, metricContainer, &list)
+		if pw.MetricFilter.IsRolledUp(bag, metricContainer.TierName) {
+			pw.MetricFilter.Suppress(m.METRIC_FAMILY_CONTAINER, countMetricFields(objMap, metricContainer))
+			continue
+		}
+		pw.addMetricToList(objMap, metricContainer, m.METRIC_FAMILY_CONTAINER, families)
 	}
 
 	for _, metricInstance := range pw.InstanceSummaryMap {
 		objMap := structs.Map(metricInstance)
-		pw.addMetricToList(objMap, metricInstance, &list)
+		rolledUp := pw.MetricFilter.IsRolledUp(bag, metricInstance.TierName)
+		if rolledUp {
+			pw.MetricFilter.Suppress(m.METRIC_FAMILY_INSTANCE, countMetricFields(objMap, metricInstance))
+		} else {
+			pw.addMetricToList(objMap, metricInstance, m.METRIC_FAMILY_INSTANCE, families)
+		}
 		for _, portMetric := range metricInstance.PortMetrics {
 			portObjMap := structs.Map(portMetric)
-			pw.addMetricToList(portObjMap, portMetric, &list)
+			if rolledUp {
+				pw.MetricFilter.Suppress(m.METRIC_FAMILY_PORT, countMetricFields(portObjMap, portMetric))
+				continue
+			}
+			pw.addMetricToList(portObjMap, portMetric, m.METRIC_FAMILY_PORT, families)
 		}
 	}
 
+	var list []m.AppDMetric
+	for family, series := range families {
+		list = append(list, pw.MetricFilter.Apply(bag, family, series)...)
+	}
+	list = append(list, pw.MetricFilter.GetCardinalityMetrics()...)
+
 	ml.Items = list
 	return ml
 }
 
-func (pw PodWorker) addMetricToList(objMap map[string]interface{}, metric m.AppDMetricInterface, list *[]m.AppDMetric) {
+func (pw PodWorker) addMetricToList(objMap map[string]interface{}, metric m.AppDMetricInterface, family string, families map[string][]m.AppDMetric) {
 
 	for fieldName, fieldValue := range objMap {
 		if !metric.ShouldExcludeField(fieldName) {
 			appdMetric := m.NewAppDMetric(fieldName, fieldValue.(int64), metric.GetPath())
-			*list = append(*list, appdMetric)
+			families[family] = append(families[family], appdMetric)
+		}
+	}
+}
+
+func countMetricFields(objMap map[string]interface{}, metric m.AppDMetricInterface) int {
+	count := 0
+	for fieldName := range objMap {
+		if !metric.ShouldExcludeField(fieldName) {
+			count++
 		}
 	}
+	return count
 }
 
 //dashboards
 func (pw *PodWorker) buildDashboards(dashData map[string]m.DashboardBag) {
 	bag := (*pw.ConfManager).Get()
-	bth := pw.AppdController.StartBT("BuildDashboard")
+	bth := pw.Sinks.StartBT("BuildDashboard")
 	//clear cache
 	pw.DashboardCache = make(map[string]m.PodSchema)
 	dw := NewDashboardWorker(bag, pw.Logger, pw.AppdController)
@@ -1988,7 +2125,7 @@ func (pw *PodWorker) buildDashboards(dashData map[string]m.DashboardBag) {
 			}
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *PodWorker) addToAssociationQueue(retryObj *m.AgentRetryRequest) {
@@ -2018,7 +2155,7 @@ func (pw *PodWorker) flushAssociationQueue() {
 	bag := (*pw.ConfManager).Get()
 	purgeList := []m.AgentRetryRequest{}
 	lockAssociationQueue.RLock()
-	injector := instr.NewAgentInjector(pw.Client, pw.K8sConfig, bag, pw.AppdController, pw.Logger)
+	injector := instr.NewAgentInjector(pw.Client, pw.K8sConfig, bag, pw.AppdController, pw.Sinks, pw.Logger)
 	for _, p := range pw.PendingAssociationQueue {
 		//update pod from cache
 		podKey := utils.GetPodKey(p.Pod)
@@ -2101,6 +2238,57 @@ func (pw *PodWorker) OnPodErrorEvent(podName string, eventSchema m.EventSchema)
 	}
 }
 
+func (pw *PodWorker) OnProbeFailure(namespace, podName, containerName string, failureTime time.Time) {
+	lockProbeFailures.Lock()
+	defer lockProbeFailures.Unlock()
+	key := fmt.Sprintf("%s_%s_%s", namespace, podName, containerName)
+	if last, ok := pw.ProbeFailures[key]; !ok || failureTime.After(last) {
+		pw.ProbeFailures[key] = failureTime
+	}
+}
+
+func (pw *PodWorker) GetLastProbeFailure(namespace, podName, containerName string) time.Time {
+	lockProbeFailures.RLock()
+	defer lockProbeFailures.RUnlock()
+	if t, ok := pw.ProbeFailures[fmt.Sprintf("%s_%s_%s", namespace, podName, containerName)]; ok {
+		return t
+	}
+	//the event did not reference the container
+	return pw.ProbeFailures[fmt.Sprintf("%s_%s_%s", namespace, podName, "")]
+}
+
+func (pw PodWorker) addRestartCause(podObject *m.PodSchema, cause string, count int64) {
+	lockRestartCauses.Lock()
+	defer lockRestartCauses.Unlock()
+	key := utils.GetKey(podObject.Namespace, podObject.Owner)
+	causes, ok := pw.RestartCauses[key]
+	if !ok {
+		causes = make(map[string]int64)
+		pw.RestartCauses[key] = causes
+	}
+	causes[cause] += count
+	pw.Logger.WithFields(log.Fields{"pod": podObject.Name, "cause": cause, "restarts": count}).Debug("Container restarted")
+}
+
+//adds the restarts since the last metrics update to the tier metrics
+func (pw *PodWorker) applyRestartCauses() {
+	lockRestartCauses.Lock()
+	defer lockRestartCauses.Unlock()
+	for tier, summaryApp := range pw.AppSummaryMap {
+		causes, ok := pw.RestartCauses[utils.GetKey(summaryApp.Namespace, summaryApp.TierName)]
+		if !ok {
+			continue
+		}
+		for cause, count := range causes {
+			summaryApp.AddRestarts(cause, count)
+		}
+		pw.AppSummaryMap[tier] = summaryApp
+	}
+	for key := range pw.RestartCauses {
+		delete(pw.RestartCauses, key)
+	}
+}
+
 func (pw *PodWorker) GetPodEvents(podSchema *m.PodSchema) []string {
 	lockEventLock.RLock()
 	defer lockEventLock.RUnlock()
@@ -0,0 +1,263 @@
+package workers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	instr "github.com/appdynamics/cluster-agent/instrumentation"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	DEPLOY_REVISION_ANNOTATION string = "deployment.kubernetes.io/revision"
+	PROGRESS_DEADLINE_EXCEEDED string = "ProgressDeadlineExceeded"
+)
+
+var lockRollouts = sync.RWMutex{}
+
+//determines the state of the rollout from the deployment status
+func getRolloutStatus(d *appsv1.Deployment) (string, string, string) {
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == v1.ConditionFalse && c.Reason == PROGRESS_DEADLINE_EXCEEDED {
+			return m.ROLLOUT_STALLED, c.Reason, c.Message
+		}
+	}
+	var desired int32 = 1
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	if d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == desired &&
+		d.Status.Replicas == d.Status.UpdatedReplicas &&
+		d.Status.AvailableReplicas == d.Status.UpdatedReplicas {
+		return m.ROLLOUT_COMPLETE, "", ""
+	}
+	return m.ROLLOUT_IN_PROGRESS, "", ""
+}
+
+func getDeployRevision(d *appsv1.Deployment) int64 {
+	if rev, ok := d.Annotations[DEPLOY_REVISION_ANNOTATION]; ok {
+		val, err := strconv.ParseInt(rev, 10, 64)
+		if err == nil {
+			return val
+		}
+	}
+	return 0
+}
+
+func getContainerImages(spec *v1.PodSpec) map[string]string {
+	images := make(map[string]string)
+	for _, c := range spec.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+func formatImages(images map[string]string) string {
+	var list []string
+	for name, image := range images {
+		list = append(list, fmt.Sprintf("%s:%s", name, image))
+	}
+	sort.Strings(list)
+	return utils.TruncateString(strings.Join(list, ";"), app.MAX_FIELD_LENGTH)
+}
+
+func diffImages(oldImages map[string]string, newImages map[string]string) string {
+	var list []string
+	for name, image := range newImages {
+		if oldImage, ok := oldImages[name]; !ok || oldImage != image {
+			list = append(list, fmt.Sprintf("%s:%s->%s", name, oldImage, image))
+		}
+	}
+	sort.Strings(list)
+	return utils.TruncateString(strings.Join(list, ";"), app.MAX_FIELD_LENGTH)
+}
+
+func (dw *DeployWorker) trackRollout(deployObj *appsv1.Deployment, old *appsv1.Deployment) {
+	key := utils.GetDeployKey(deployObj)
+	newRevision := getDeployRevision(deployObj)
+
+	lockRollouts.Lock()
+	defer lockRollouts.Unlock()
+
+	rollout, tracked := dw.RolloutCache[key]
+
+	if old != nil && newRevision != getDeployRevision(old) && newRevision > 0 {
+		if tracked {
+			superseded := rollout
+			superseded.Finish(m.ROLLOUT_SUPERSEDED, "", fmt.Sprintf("Superseded by revision %d", newRevision))
+			dw.RolloutWQ.Add(&superseded)
+		}
+		rollout = dw.newRollout(deployObj, old)
+		tracked = true
+		dw.Logger.Infof("Rollout of deployment %s to revision %d started\n", key, newRevision)
+		dw.postRolloutChangeEvent(&rollout)
+	}
+
+	status, reason, message := getRolloutStatus(deployObj)
+	if !tracked {
+		if old != nil || status != m.ROLLOUT_IN_PROGRESS {
+			return
+		}
+		//the agent started in the middle of a rollout
+		rollout = dw.newRollout(deployObj, nil)
+	}
+
+	var desired int32 = 1
+	if deployObj.Spec.Replicas != nil {
+		desired = *deployObj.Spec.Replicas
+	}
+	if surged := deployObj.Status.Replicas - desired; surged > rollout.MaxSurged {
+		rollout.MaxSurged = surged
+	}
+	if deployObj.Status.UnavailableReplicas > rollout.MaxUnavailable {
+		rollout.MaxUnavailable = deployObj.Status.UnavailableReplicas
+	}
+	rollout.Replicas = desired
+
+	switch status {
+	case m.ROLLOUT_COMPLETE:
+		rollout.Finish(m.ROLLOUT_COMPLETE, reason, message)
+		dw.Logger.Infof("Rollout of deployment %s to revision %d completed in %d sec\n", key, rollout.NewRevision, rollout.Duration)
+		dw.RolloutWQ.Add(&rollout)
+		delete(dw.RolloutCache, key)
+		return
+	case m.ROLLOUT_STALLED:
+		if rollout.Outcome != m.ROLLOUT_STALLED {
+			//report once, keep tracking in case the rollout recovers
+			stalled := rollout
+			stalled.Finish(m.ROLLOUT_STALLED, reason, message)
+			dw.Logger.Warnf("Rollout of deployment %s to revision %d is stalled. %s\n", key, rollout.NewRevision, message)
+			dw.RolloutWQ.Add(&stalled)
+			rollout.Outcome = m.ROLLOUT_STALLED
+		}
+	}
+	dw.RolloutCache[key] = rollout
+}
+
+func (dw *DeployWorker) newRollout(deployObj *appsv1.Deployment, old *appsv1.Deployment) m.RolloutSchema {
+	bag := (*dw.ConfigManager).Get()
+	rollout := m.NewRolloutObj()
+	rollout.Name = deployObj.Name
+	rollout.Namespace = deployObj.Namespace
+	rollout.ObjectUid = string(deployObj.GetUID())
+	if deployObj.ClusterName != "" {
+		rollout.ClusterName = deployObj.ClusterName
+	} else {
+		rollout.ClusterName = bag.AppName
+	}
+	rollout.NewRevision = getDeployRevision(deployObj)
+	newImages := getContainerImages(&deployObj.Spec.Template.Spec)
+	rollout.NewImages = formatImages(newImages)
+	rollout.StartTime = time.Now()
+	if old != nil {
+		rollout.OldRevision = getDeployRevision(old)
+		oldImages := getContainerImages(&old.Spec.Template.Spec)
+		rollout.OldImages = formatImages(oldImages)
+		rollout.ChangedImages = diffImages(oldImages, newImages)
+	} else {
+		for _, c := range deployObj.Status.Conditions {
+			if c.Type == appsv1.DeploymentProgressing {
+				rollout.StartTime = c.LastTransitionTime.Time
+			}
+		}
+	}
+	rollout.AppName = dw.getAssociatedAppName(deployObj)
+	return rollout
+}
+
+func (dw *DeployWorker) onDeleteRollout(deployObj *appsv1.Deployment) {
+	key := utils.GetDeployKey(deployObj)
+	lockRollouts.Lock()
+	defer lockRollouts.Unlock()
+	if rollout, tracked := dw.RolloutCache[key]; tracked {
+		rollout.Finish(m.ROLLOUT_DELETED, "", "Deployment deleted")
+		dw.RolloutWQ.Add(&rollout)
+		delete(dw.RolloutCache, key)
+	}
+}
+
+//name of the AppDynamics application instrumented in the deployment, if any
+func (dw *DeployWorker) getAssociatedAppName(deployObj *appsv1.Deployment) string {
+	bag := (*dw.ConfigManager).Get()
+	appName, _, _ := instr.GetAttachMetadata(bag.AppDAppLabel, bag.AppDTierLabel, deployObj, bag)
+	if appName != "" {
+		return appName
+	}
+	if bag.InstrumentationMethod == m.None {
+		return ""
+	}
+	agentRequests := instr.GetAgentRequestsForDeployment(deployObj, bag, dw.Logger)
+	if agentRequests != nil && agentRequests.GetFirstRequest() != nil {
+		return agentRequests.GetFirstRequest().AppName
+	}
+	return ""
+}
+
+func (dw *DeployWorker) postRolloutChangeEvent(rollout *m.RolloutSchema) {
+	appName := rollout.AppName
+	if appName == "" {
+		return
+	}
+	bag := (*dw.ConfigManager).Get()
+	summary := fmt.Sprintf("Deployment %s/%s rolled out revision %d", rollout.Namespace, rollout.Name, rollout.NewRevision)
+	comment := rollout.ChangedImages
+	if comment != "" {
+		comment = fmt.Sprintf("Changed images: %s", comment)
+	}
+	go func() {
+		rc := app.NewRestClient(bag, dw.Logger)
+		err := rc.PostApplicationEvent(appName, "APPLICATION_DEPLOYMENT", "INFO", summary, comment)
+		if err != nil {
+			dw.Logger.Errorf("Unable to post rollout change event to application %s. %v\n", appName, err)
+		}
+	}()
+}
+
+func (dw *DeployWorker) flushRolloutQueue() {
+	bag := (*dw.ConfigManager).Get()
+	count := dw.RolloutWQ.Len()
+	if count == 0 {
+		return
+	}
+	bth := dw.Sinks.StartBT("FlushRolloutDataQueue")
+	dw.Logger.Infof("Flushing the queue of %d rollout records\n", count)
+
+	var objList []m.RolloutSchema
+	for count > 0 {
+		item, quit := dw.RolloutWQ.Get()
+		count = count - 1
+		if quit {
+			dw.Logger.Info("Rollout Queue shut down")
+			break
+		}
+		objList = append(objList, *(item.(*m.RolloutSchema)))
+		dw.RolloutWQ.Forget(item)
+		dw.RolloutWQ.Done(item)
+		if len(objList) >= bag.EventAPILimit {
+			dw.postRolloutRecords(&objList)
+			objList = objList[:0]
+		}
+	}
+	if len(objList) > 0 {
+		dw.postRolloutRecords(&objList)
+	}
+	dw.Sinks.StopBT(bth)
+}
+
+func (dw *DeployWorker) postRolloutRecords(objList *[]m.RolloutSchema) {
+	bag := (*dw.ConfigManager).Get()
+	schemaDefObj := m.NewRolloutSchemaDefWrapper()
+	err := dw.Sinks.PostEvents(bag.RolloutSchemaName, &schemaDefObj, objList)
+	if err != nil {
+		dw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.RolloutSchemaName, err)
+	}
+}
@@ -1,8 +1,6 @@
 package workers
 
 import (
-	"encoding/json"
-
 	log "github.com/sirupsen/logrus"
 
 	"sync"
@@ -31,16 +29,18 @@ type RsWorker struct {
 	SummaryMap     map[string]m.ClusterRsMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
+	Sinks          *app.SinkSet
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
 	Logger         *log.Logger
 }
 
-func NewRsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) RsWorker {
+func NewRsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, sinks *app.SinkSet, resolver *OwnerResolver, l *log.Logger) RsWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := RsWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterRsMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
+		AppdController: controller, Sinks: sinks, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
 	dw.initRsInformer(client)
+	resolver.RegisterStore("ReplicaSet", dw.informer.GetStore(), dw.informer.HasSynced)
 	return dw
 }
 
@@ -143,6 +143,12 @@ func (pw *RsWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker)
 	for {
 		select {
 		case <-ticker.C:
+			if pw.Sinks.SnapshotsPaused() {
+				if n := discardQueue(pw.WQ); n > 0 {
+					pw.Logger.Warnf("Events API is unavailable. Dropped %d replica set records\n", n)
+				}
+				continue
+			}
 			pw.flushQueue()
 		case <-stop:
 			ticker.Stop()
@@ -158,13 +164,13 @@ func (pw *RsWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (pw *RsWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
-	bth := pw.AppdController.StartBT("FlushReplicaSetDataQueue")
+	bth := pw.Sinks.StartBT("FlushReplicaSetDataQueue")
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d ReplicaSet records\n", count)
 	}
 	if count == 0 {
-		pw.AppdController.StopBT(bth)
+		pw.Sinks.StopBT(bth)
 		return
 	}
 
@@ -184,29 +190,19 @@ func (pw *RsWorker) flushQueue() {
 		if count == 0 || len(objList) >= bag.EventAPILimit {
 			pw.Logger.Debugf("Sending %d ReplicaSet records to AppD events API\n", len(objList))
 			pw.postRsRecords(&objList)
-			pw.AppdController.StopBT(bth)
+			pw.Sinks.StopBT(bth)
 			return
 		}
 	}
-	pw.AppdController.StopBT(bth)
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *RsWorker) postRsRecords(objList *[]m.DeploySchema) {
 	bag := (*pw.ConfigManager).Get()
-
-	rc := app.NewRestClient(bag, pw.Logger)
-
 	schemaDefObj := m.NewDeploySchemaDefWrapper()
-
-	err := rc.EnsureSchema(bag.DeploySchemaName, &schemaDefObj)
+	err := pw.Sinks.PostEvents(bag.DeploySchemaName, &schemaDefObj, objList)
 	if err != nil {
-		pw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.DeploySchemaName, err)
-	} else {
-		data, err := json.Marshal(objList)
-		if err != nil {
-			pw.Logger.Errorf("Problems when serializing array of rs schemas. %v", err)
-		}
-		rc.PostAppDEvents(bag.DeploySchemaName, data)
+		pw.Logger.Errorf("Issues when publishing %s records. %v\n", bag.DeploySchemaName, err)
 	}
 }
 
@@ -223,7 +219,7 @@ func (pw *RsWorker) getNextQueueItem() (*m.DeploySchema, bool) {
 }
 
 func (pw *RsWorker) buildAppDMetrics() {
-	bth := pw.AppdController.StartBT("PostReplicaSetMetrics")
+	bth := pw.Sinks.StartBT("PostReplicaSetMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterRsMetrics)
 
 	var count int = 0
@@ -243,8 +239,10 @@ func (pw *RsWorker) buildAppDMetrics() {
 
 	pw.Logger.Infof("Ready to push %d ReplicaSet metrics\n", len(ml.Items))
 
-	pw.AppdController.PostMetrics(ml)
-	pw.AppdController.StopBT(bth)
+	if err := pw.Sinks.PostMetrics(ml); err != nil {
+		pw.Logger.Errorf("Issues when publishing metrics. %v\n", err)
+	}
+	pw.Sinks.StopBT(bth)
 }
 
 func (pw *RsWorker) summarize(RsObject *m.DeploySchema) {